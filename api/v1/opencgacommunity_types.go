@@ -18,10 +18,26 @@ package v1
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/stretchr/objx"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/phamidko/opencga-operator/pkg/authentication/scram"
+	"github.com/phamidko/opencga-operator/pkg/authentication/x509"
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/kube/annotations"
+	"github.com/phamidko/opencga-operator/pkg/opencgaconfig"
 )
 
 type Type string
@@ -32,14 +48,72 @@ const (
 
 type Phase string
 
+// MigrationPhase is the state of the catalog migration to a new version.
+type MigrationPhase string
+
+const (
+	MigrationAwaitingApproval MigrationPhase = "AwaitingApproval"
+	MigrationRunning          MigrationPhase = "Running"
+	MigrationSucceeded        MigrationPhase = "Succeeded"
+	MigrationFailed           MigrationPhase = "Failed"
+)
+
+const (
+	// MigrationApprovalAnnotation approves the catalog migration to the version it is set to,
+	// if spec.upgrade.requireMigrationApproval is true.
+	MigrationApprovalAnnotation = "opencga.zetta.com/approve-migration"
+
+	// AgentAPIKeyRotationAnnotation regenerates the API key of the agents, and rolls the REST Pods, whenever
+	// its value changes.
+	AgentAPIKeyRotationAnnotation = "opencga.zetta.com/rotate-agent-api-key"
+
+	// AgentCredentialsRotationAnnotation rotates the password and keyfile of the agents whenever its value changes.
+	// The agents accept both the previous and the new credentials until every one of them has moved over.
+	AgentCredentialsRotationAnnotation = "opencga.zetta.com/rotate-agent-credentials"
+)
+
 const (
 	Running Phase = "Running"
 	Failed  Phase = "Failed"
 	Pending Phase = "Pending"
 )
 
+const (
+	// ConditionDegraded is true if a service OpenCGA depends on can't be reached.
+	ConditionDegraded = "Degraded"
+	// ConditionCatalogInstalled is true once the catalog has been installed. The install Job is never run again afterwards.
+	ConditionCatalogInstalled = "CatalogInstalled"
+	// ConditionUsersDeleted reports the Secrets of the users last removed from the resource have been deleted.
+	ConditionUsersDeleted = "UsersDeleted"
+)
+
+// AgentKeyfilePath is where the agents write the keyfile, in the volume they share with the REST container.
+const AgentKeyfilePath = "/var/lib/opencga-mms-automation/authentication/keyfile"
+
 const (
 	defaultClusterDomain = "cluster.local"
+
+	defaultCatalogUserName     = "opencga"
+	defaultPasswordKey         = "password"
+	defaultConnectionStringKey = "connectionString"
+	defaultCACertificateKey    = "ca.crt"
+	defaultClientSecretKey     = "clientSecret"
+
+	defaultIngressPath = "/opencga"
+
+	defaultTargetCPUUtilizationPercentage = 80
+	defaultRequestRateMetricName          = "http_requests_per_second"
+
+	// UserRestURLKey, UserUsernameKey, UserPasswordSecretNameKey and UserPasswordSecretKeyKey are the keys of the
+	// connection Secrets published for spec.users, next to a ready-to-use client-configuration.yml.
+	UserRestURLKey            = "restUrl"
+	UserUsernameKey           = "username"
+	UserPasswordSecretNameKey = "passwordSecretName"
+	UserPasswordSecretKeyKey  = "passwordSecretKey"
+
+	// SearchUsernameKey and SearchPasswordKey are the keys of the Secret referenced by spec.search.credentialsSecretRef.
+	SearchUsernameKey = "username"
+	SearchPasswordKey = "password"
 )
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -53,6 +127,637 @@ type OpenCGACommunitySpec struct {
 	Type Type `json:"type"`
 	// Version defines which version of OpenCGA will be used
 	Version string `json:"version"`
+
+	// Server configures the OpenCGA REST server.
+	// +optional
+	Server ServerSpec `json:"server,omitempty"`
+
+	// Catalog configures the connection to the MongoDB deployment OpenCGA stores its catalog in.
+	Catalog CatalogSpec `json:"catalog"`
+
+	// Search configures the Solr deployment used for catalog search and variant secondary indexes.
+	// +optional
+	Search *SearchSpec `json:"search,omitempty"`
+
+	// VariantStorage selects the engine OpenCGA stores variants in.
+	// +optional
+	VariantStorage VariantStorageSpec `json:"variantStorage,omitempty"`
+
+	// Admin configures the OpenCGA admin user.
+	// +optional
+	Admin AdminSpec `json:"admin,omitempty"`
+
+	// Ingress exposes the REST API outside the cluster.
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// PodDisruptionBudget configures the PodDisruptionBudget protecting the REST members from voluntary disruptions.
+	// +optional
+	PodDisruptionBudget PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+
+	// Users are the OpenCGA users REST clients connect as. A Secret with the connection details of
+	// every user is published for applications to mount.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Users []UserSpec `json:"users,omitempty"`
+
+	// Security configures TLS, the users authenticated by client certificates, against LDAP or by an OpenID
+	// provider and the custom roles the users can be granted.
+	// +optional
+	Security SecuritySpec `json:"security,omitempty"`
+
+	// Autoscaling lets a HorizontalPodAutoscaler choose the number of members. The autoscaler
+	// updates spec.members through the scale subresource.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Upgrade configures how the resource is upgraded when the version changes.
+	// +optional
+	Upgrade UpgradeSpec `json:"upgrade,omitempty"`
+
+	// Agent configures the automation agent running next to each REST member.
+	// +optional
+	Agent AgentSpec `json:"agent,omitempty"`
+
+	// AdditionalOpenCGAConfig is additional configuration that is deep-merged into the
+	// configuration.yml rendered by the operator. Values set here take precedence.
+	// +kubebuilder:validation:Type=object
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +nullable
+	AdditionalOpenCGAConfig OpenCGAConfiguration `json:"additionalOpenCGAConfig,omitempty"`
+
+	// AdditionalStorageConfig is additional configuration that is deep-merged into the
+	// storage-configuration.yml rendered by the operator.
+	// +kubebuilder:validation:Type=object
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +nullable
+	AdditionalStorageConfig OpenCGAConfiguration `json:"additionalStorageConfig,omitempty"`
+
+	// AdditionalClientConfig is additional configuration that is deep-merged into the
+	// client-configuration.yml rendered by the operator.
+	// +kubebuilder:validation:Type=object
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +nullable
+	AdditionalClientConfig OpenCGAConfiguration `json:"additionalClientConfig,omitempty"`
+}
+
+// ServerSpec holds the settings of the OpenCGA server that the operator knows about.
+type ServerSpec struct {
+	// LogLevel is the log level of the OpenCGA server.
+	// +kubebuilder:validation:Enum=debug;info;warn;error
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Workspace is the directory session and job files are stored in.
+	// +optional
+	Workspace string `json:"workspace,omitempty"`
+
+	// DatabasePrefix is prepended to the name of every catalog database.
+	// +optional
+	DatabasePrefix string `json:"databasePrefix,omitempty"`
+
+	// +optional
+	Rest RestSpec `json:"rest,omitempty"`
+
+	// +optional
+	JVM JVMSpec `json:"jvm,omitempty"`
+}
+
+// RestSpec configures the embedded Jetty server of the REST API.
+type RestSpec struct {
+	// Port is the port the REST API listens on.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// +optional
+	MinThreads int `json:"minThreads,omitempty"`
+
+	// +optional
+	MaxThreads int `json:"maxThreads,omitempty"`
+
+	// SessionAffinity of the query and admin Services, "ClientIP" sends the consecutive requests
+	// of a client to the same REST member. Defaults to "None".
+	// +kubebuilder:validation:Enum=None;ClientIP
+	// +optional
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+
+	// SessionAffinityTimeoutSeconds is how long the requests of a client stick to the same REST member
+	// when the session affinity is "ClientIP". Defaults to 10800.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	SessionAffinityTimeoutSeconds *int32 `json:"sessionAffinityTimeoutSeconds,omitempty"`
+}
+
+// JVMSpec configures the JVM the OpenCGA server runs in.
+type JVMSpec struct {
+	// InitialHeap is passed to the JVM as -Xms, e.g. "512m".
+	// +optional
+	InitialHeap string `json:"initialHeap,omitempty"`
+
+	// MaxHeap is passed to the JVM as -Xmx, e.g. "2g".
+	// +optional
+	MaxHeap string `json:"maxHeap,omitempty"`
+
+	// ExtraOptions are appended to the JVM command line.
+	// +optional
+	ExtraOptions []string `json:"extraOptions,omitempty"`
+}
+
+// CatalogSpec configures the MongoDB deployment the OpenCGA catalog is stored in.
+// Exactly one of Hosts and ConnectionStringSecretRef must be specified.
+type CatalogSpec struct {
+	// Hosts is the list of MongoDB members in the form host:port.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// ConnectionStringSecretRef references a Secret key holding a standard MongoDB connection string.
+	// The key defaults to "connectionString". Credentials in the connection string take precedence over User.
+	// +optional
+	ConnectionStringSecretRef *SecretKeyReference `json:"connectionStringSecretRef,omitempty"`
+
+	// ReplicaSetName is the name of the MongoDB replica set.
+	// +optional
+	ReplicaSetName string `json:"replicaSetName,omitempty"`
+
+	// AuthenticationDatabase is the database the catalog user is defined in. Defaults to "admin".
+	// +optional
+	AuthenticationDatabase string `json:"authenticationDatabase,omitempty"`
+
+	// DatabasePrefix is prepended to the name of every catalog database.
+	// It takes precedence over spec.server.databasePrefix.
+	// +optional
+	DatabasePrefix string `json:"databasePrefix,omitempty"`
+
+	// +optional
+	User CatalogUserSpec `json:"user,omitempty"`
+
+	// +optional
+	TLS CatalogTLSSpec `json:"tls,omitempty"`
+}
+
+// CatalogUserSpec is the MongoDB user OpenCGA connects to the catalog as.
+type CatalogUserSpec struct {
+	// Name is the name of the user. Defaults to "opencga".
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// PasswordSecretRef references the Secret key holding the password of the user. If the Secret does not
+	// exist, a password is generated and stored in it. Defaults to the "password" key of <name>-catalog-password.
+	// +optional
+	PasswordSecretRef SecretKeyReference `json:"passwordSecretRef,omitempty"`
+}
+
+// CatalogTLSSpec configures TLS for the connection to the catalog.
+type CatalogTLSSpec struct {
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// CaCertificateSecretRef references a Secret key holding the PEM encoded CA certificate of the MongoDB
+	// servers. The key defaults to "ca.crt". If not set, the default trust store of the JVM is used.
+	// +optional
+	CaCertificateSecretRef *SecretKeyReference `json:"caCertificateSecretRef,omitempty"`
+
+	// ClientCertificateSecretRef references a kubernetes.io/tls Secret holding the client certificate (tls.crt) and
+	// private key (tls.key) OpenCGA authenticates to the catalog with, using MONGODB-X509 against the $external
+	// database. No password is used: the user is the subject of the certificate, or spec.catalog.user.name if set.
+	// +optional
+	ClientCertificateSecretRef *corev1.LocalObjectReference `json:"clientCertificateSecretRef,omitempty"`
+}
+
+// SearchSpec configures the Solr deployment OpenCGA uses for catalog search and variant secondary indexes.
+// Exactly one of Hosts and ZooKeeperHosts must be specified.
+type SearchSpec struct {
+	// Hosts is the list of Solr base URLs, e.g. http://solr-0.solr:8983/solr.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// ZooKeeperHosts is the ZooKeeper ensemble of a SolrCloud deployment in the form host:port.
+	// +optional
+	ZooKeeperHosts []string `json:"zookeeperHosts,omitempty"`
+
+	// Mode is "cloud" for SolrCloud or "core" for standalone Solr servers. Defaults to "cloud".
+	// +kubebuilder:validation:Enum=cloud;core
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// CollectionPrefix is prepended to the name of every Solr collection. Defaults to "opencga".
+	// +optional
+	CollectionPrefix string `json:"collectionPrefix,omitempty"`
+
+	// TimeoutMs is the timeout of the requests to Solr in milliseconds. Defaults to 30000.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+
+	// CredentialsSecretRef references a Secret with the "username" and "password" keys used
+	// to authenticate against Solr.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// VariantStorageSpec selects the variant storage engine. Only the sub-section of the selected engine is used.
+type VariantStorageSpec struct {
+	// Engine is the variant storage engine, "mongodb" or "hadoop". Defaults to "mongodb".
+	// +kubebuilder:validation:Enum=mongodb;hadoop
+	// +optional
+	Engine string `json:"engine,omitempty"`
+
+	// MongoDB configures the MongoDB engine.
+	// +optional
+	MongoDB *MongoDBVariantStorageSpec `json:"mongodb,omitempty"`
+
+	// Hadoop configures the Hadoop engine, it is required if the engine is "hadoop".
+	// +optional
+	Hadoop *HadoopVariantStorageSpec `json:"hadoop,omitempty"`
+}
+
+// MongoDBVariantStorageSpec configures the engine storing variants in MongoDB.
+type MongoDBVariantStorageSpec struct {
+	// Hosts is the list of MongoDB members variants are stored in, in the form host:port.
+	// Defaults to the catalog deployment. The catalog user is used in both cases.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// HadoopVariantStorageSpec configures the engine storing variants in HBase.
+type HadoopVariantStorageSpec struct {
+	// HadoopConfigMapRef references a ConfigMap with the Hadoop client configuration files,
+	// e.g. core-site.xml, hdfs-site.xml, yarn-site.xml and mapred-site.xml.
+	HadoopConfigMapRef corev1.LocalObjectReference `json:"hadoopConfigMapRef"`
+
+	// HBaseConfigMapRef references a ConfigMap with the HBase client configuration files, e.g. hbase-site.xml.
+	HBaseConfigMapRef corev1.LocalObjectReference `json:"hbaseConfigMapRef"`
+
+	// HBaseNamespace is the HBase namespace the variant tables are created in.
+	// +optional
+	HBaseNamespace string `json:"hbaseNamespace,omitempty"`
+
+	// Flavour is the Hadoop distribution the OpenCGA image is built for, e.g. "hdp3.1".
+	// It is appended to the image tag.
+	// +optional
+	Flavour string `json:"flavour,omitempty"`
+}
+
+// AdminSpec configures the OpenCGA admin user, which is created when the catalog is installed.
+type AdminSpec struct {
+	// PasswordSecretRef references the Secret key holding the password of the admin user.
+	// Defaults to the "password" key of the "<name>-admin-password" Secret, which is
+	// generated if it does not exist.
+	// +optional
+	PasswordSecretRef *SecretKeyReference `json:"passwordSecretRef,omitempty"`
+}
+
+// IngressSpec configures the Ingress exposing the REST API.
+type IngressSpec struct {
+	// Host is the host name the REST API is exposed at.
+	Host string `json:"host"`
+
+	// Path is the path the REST API is exposed at. Defaults to /opencga, the context path of the
+	// REST server. Other paths have to be rewritten by the ingress controller, e.g. through annotations.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// TLSSecretName is the name of the Secret with the TLS certificate of the host.
+	// If not set, the REST API is exposed over plain HTTP.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// IngressClassName is the IngressClass of the ingress controller serving the Ingress.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations are added to the Ingress, e.g. to configure the ingress controller.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PodDisruptionBudgetSpec configures the PodDisruptionBudget of the REST members. No PodDisruptionBudget is created
+// for a single member, as it could never be evicted.
+type PodDisruptionBudgetSpec struct {
+	// MaxUnavailable is the number or percentage of REST members which can be evicted at the same time.
+	// Defaults to a minority of the members, at least one.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// UserSpec declares an OpenCGA user REST clients connect as.
+type UserSpec struct {
+	// Name is the id of the OpenCGA user.
+	Name string `json:"name"`
+
+	// PasswordSecretRef references the Secret key holding the password of the user. The key defaults to "password".
+	// Only the reference is published in the connection Secret.
+	PasswordSecretRef SecretKeyReference `json:"passwordSecretRef"`
+
+	// ConnectionSecretName is the name of the Secret the connection details of the user are published in.
+	// Defaults to <resource name>-<database prefix>-<user name>.
+	// +optional
+	ConnectionSecretName string `json:"connectionSecretName,omitempty"`
+
+	// Roles are the built-in or custom roles granted to the user.
+	// +optional
+	Roles []RoleReference `json:"roles,omitempty"`
+
+	// ScramSha1Iterations overrides spec.security.scram.sha1Iterations for the credentials of the user.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	ScramSha1Iterations int `json:"scramSha1Iterations,omitempty"`
+
+	// ScramSha256Iterations overrides spec.security.scram.sha256Iterations for the credentials of the user.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	ScramSha256Iterations int `json:"scramSha256Iterations,omitempty"`
+}
+
+// SecuritySpec configures TLS and the authentication and authorization of the users.
+type SecuritySpec struct {
+	// TLS configures TLS for the REST API served by the members.
+	// +optional
+	TLS TLS `json:"tls,omitempty"`
+
+	// X509Users are the users authenticated by the subject of their client certificate, which requires TLS.
+	// +optional
+	X509Users []X509User `json:"x509Users,omitempty"`
+
+	// LDAP configures the authentication of OpenCGA users against LDAP servers, in addition to their local accounts.
+	// +optional
+	LDAP *LDAPSpec `json:"ldap,omitempty"`
+
+	// OIDC configures the authentication of OpenCGA users with an OpenID provider, in addition to their local accounts.
+	// +optional
+	OIDC *OIDCSpec `json:"oidc,omitempty"`
+
+	// Roles are custom roles defined in the automation config, in addition to the built-in ones.
+	// +optional
+	Roles []CustomRole `json:"roles,omitempty"`
+
+	// Scram configures the SCRAM credentials generated for the users. Changing it regenerates the credentials.
+	// +optional
+	Scram ScramSpec `json:"scram,omitempty"`
+}
+
+// ScramSpec configures the mechanisms and iteration counts of the SCRAM credentials of the users.
+type ScramSpec struct {
+	// Sha1Iterations is the number of iterations of the SCRAM-SHA-1 credentials. Defaults to 10000.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	Sha1Iterations int `json:"sha1Iterations,omitempty"`
+
+	// Sha256Iterations is the number of iterations of the SCRAM-SHA-256 credentials. Defaults to 15000.
+	// +kubebuilder:validation:Minimum=5000
+	// +optional
+	Sha256Iterations int `json:"sha256Iterations,omitempty"`
+
+	// Sha256Only only generates SCRAM-SHA-256 credentials, the existing SCRAM-SHA-1 ones are removed.
+	// +optional
+	Sha256Only bool `json:"sha256Only,omitempty"`
+}
+
+// TLS configures TLS for the REST API with a certificate issued, for instance, by cert-manager. Enabling
+// TLS moves the members through the allowTLS and preferTLS modes before requiring it, and renewed
+// certificates are picked up without restarting the Pods.
+type TLS struct {
+	Enabled bool `json:"enabled"`
+
+	// Optional keeps accepting connections without TLS (preferTLS) once TLS is enabled.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+
+	// CertificateKeySecretRef references a kubernetes.io/tls Secret holding the certificate (tls.crt)
+	// and private key (tls.key) of the members.
+	// +optional
+	CertificateKeySecretRef corev1.LocalObjectReference `json:"certificateKeySecretRef,omitempty"`
+
+	// CAConfigMapRef references a ConfigMap holding the certificate of the CA (ca.crt) that
+	// issued the certificate of the members.
+	// +optional
+	CAConfigMapRef corev1.LocalObjectReference `json:"caConfigMapRef,omitempty"`
+
+	// RequireClientCertificates makes the members require a certificate issued by the CA from every client
+	// once TLS is required. Clients can authenticate with their certificate as the users of
+	// spec.security.x509Users.
+	// +optional
+	RequireClientCertificates bool `json:"requireClientCertificates,omitempty"`
+}
+
+// X509User maps the subject of a client certificate to a user and its roles.
+type X509User struct {
+	// Subject is the distinguished name of the client certificate in the RFC 4514 string format,
+	// e.g. "CN=opencga-analysis,OU=services,O=zetta". It is also the name of the user.
+	Subject string `json:"subject"`
+
+	// Roles are the built-in or custom roles granted to the user.
+	// +optional
+	Roles []RoleReference `json:"roles,omitempty"`
+}
+
+// LDAPSpec configures the LDAP servers OpenCGA authenticates users against.
+type LDAPSpec struct {
+	// Servers are the URLs of the LDAP servers, e.g. ldaps://ldap.example.org:636. They are tried in order.
+	Servers []string `json:"servers"`
+
+	// BindDN is the distinguished name OpenCGA binds as to search for users and groups. The servers are
+	// bound to anonymously if it is not set.
+	// +optional
+	BindDN string `json:"bindDN,omitempty"`
+
+	// BindPasswordSecretRef references the Secret key holding the password of BindDN. The key defaults
+	// to "password".
+	// +optional
+	BindPasswordSecretRef *SecretKeyReference `json:"bindPasswordSecretRef,omitempty"`
+
+	// UserSearchBase is the distinguished name users are searched under, e.g. ou=people,dc=example,dc=org.
+	UserSearchBase string `json:"userSearchBase"`
+
+	// GroupSearchBase is the distinguished name groups are searched under, e.g. ou=groups,dc=example,dc=org.
+	GroupSearchBase string `json:"groupSearchBase"`
+
+	// CaCertificateSecretRef references a Secret key holding the PEM encoded CA certificate of the LDAP
+	// servers. The key defaults to "ca.crt". If not set, the default trust store of the JVM is used.
+	// +optional
+	CaCertificateSecretRef *SecretKeyReference `json:"caCertificateSecretRef,omitempty"`
+}
+
+// OIDCSpec configures the OpenID provider OpenCGA authenticates users with.
+type OIDCSpec struct {
+	// Issuer is the https URL of the OpenID provider. Its discovery document is read from
+	// <issuer>/.well-known/openid-configuration.
+	Issuer string `json:"issuer"`
+
+	// ClientID is the id of the client OpenCGA is registered as with the OpenID provider.
+	ClientID string `json:"clientId"`
+
+	// ClientSecretRef references the Secret key holding the secret of the client. The key defaults to "clientSecret".
+	ClientSecretRef SecretKeyReference `json:"clientSecretRef"`
+
+	// Scopes are the scopes requested from the OpenID provider. They must include "openid".
+	// Defaults to openid, profile and email.
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Claims maps the claims of the ID token to the OpenCGA user.
+	// +optional
+	Claims OIDCClaimsSpec `json:"claims,omitempty"`
+}
+
+// OIDCClaimsSpec maps the claims of the ID token to the OpenCGA user.
+type OIDCClaimsSpec struct {
+	// UserID is the claim holding the id of the user. Defaults to "sub".
+	// +optional
+	UserID string `json:"userId,omitempty"`
+
+	// Name is the claim holding the full name of the user. Defaults to "name".
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Email is the claim holding the email address of the user. Defaults to "email".
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// Groups is the claim holding the groups of the user. Groups are not read from the ID token if it is not set.
+	// +optional
+	Groups string `json:"groups,omitempty"`
+}
+
+// CustomRole is a role defined by its privileges and the roles it inherits from.
+type CustomRole struct {
+	// Name is the name of the role.
+	Name string `json:"role"`
+
+	// DB is the database the role is defined in.
+	DB string `json:"db"`
+
+	// Privileges are the actions the role allows on resources.
+	// +optional
+	Privileges []Privilege `json:"privileges,omitempty"`
+
+	// Roles are the built-in or custom roles this role inherits the privileges of.
+	// +optional
+	Roles []RoleReference `json:"roles,omitempty"`
+
+	// AuthenticationRestrictions restrict the addresses users granted the role can connect from and to.
+	// +optional
+	AuthenticationRestrictions []AuthenticationRestriction `json:"authenticationRestrictions,omitempty"`
+}
+
+// Privilege allows actions on a resource.
+type Privilege struct {
+	// Resource is the resource the actions are allowed on.
+	Resource Resource `json:"resource"`
+
+	// Actions are the allowed actions.
+	Actions []string `json:"actions"`
+}
+
+// Resource is a database, a collection, the cluster or any resource. A database and a collection
+// can be combined, and an empty name matches all the databases or collections.
+type Resource struct {
+	// +optional
+	DB *string `json:"db,omitempty"`
+
+	// +optional
+	Collection *string `json:"collection,omitempty"`
+
+	// +optional
+	AnyResource bool `json:"anyResource,omitempty"`
+
+	// +optional
+	Cluster bool `json:"cluster,omitempty"`
+}
+
+// RoleReference references a built-in or custom role.
+type RoleReference struct {
+	// Name is the name of the role.
+	Name string `json:"name"`
+
+	// DB is the database the role is defined in.
+	DB string `json:"db"`
+}
+
+// AuthenticationRestriction lists the addresses a user can connect from and to. Addresses are IP
+// addresses or CIDR ranges.
+type AuthenticationRestriction struct {
+	// ClientSource are the addresses the user can connect from.
+	// +optional
+	ClientSource []string `json:"clientSource,omitempty"`
+
+	// ServerAddress are the addresses the user can connect to.
+	// +optional
+	ServerAddress []string `json:"serverAddress,omitempty"`
+}
+
+// AutoscalingSpec configures the HorizontalPodAutoscaler of the REST members. The average CPU utilization
+// is the target when no metric is set.
+type AutoscalingSpec struct {
+	// MinMembers is the lowest number of members the autoscaler can scale down to. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinMembers *int32 `json:"minMembers,omitempty"`
+	// MaxMembers is the highest number of members the autoscaler can scale up to.
+	// +kubebuilder:validation:Minimum=1
+	MaxMembers int32 `json:"maxMembers"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the members, as a percentage of
+	// the requested CPU, the autoscaler aims for.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// RequestRate scales on the number of requests served by each member.
+	// +optional
+	RequestRate *RequestRateMetricSpec `json:"requestRate,omitempty"`
+}
+
+// RequestRateMetricSpec targets a request rate exposed for every REST Pod through the custom metrics API.
+type RequestRateMetricSpec struct {
+	// MetricName is the name of the Pod metric. Defaults to "http_requests_per_second".
+	// +optional
+	MetricName string `json:"metricName,omitempty"`
+	// TargetAverageValue is the number of requests per second per member the autoscaler aims for.
+	TargetAverageValue resource.Quantity `json:"targetAverageValue"`
+}
+
+// UpgradeSpec configures version upgrades. The catalog is migrated by a Job running the new version
+// before the REST and master Pods are replaced.
+type UpgradeSpec struct {
+	// RequireMigrationApproval holds the catalog migration back until the resource is annotated
+	// with opencga.zetta.com/approve-migration set to the new version.
+	// +optional
+	RequireMigrationApproval bool `json:"requireMigrationApproval,omitempty"`
+}
+
+// AgentSpec configures the automation agent of the REST members.
+type AgentSpec struct {
+	// LocalReadinessProbe runs the readiness probe of the agent without access to the Kubernetes API. The
+	// automation config version reached by the agent is then served by a sidecar and scraped by the operator,
+	// instead of being written to the Pod annotations.
+	// +optional
+	LocalReadinessProbe bool `json:"localReadinessProbe,omitempty"`
+
+	// ReadinessMetricsInterval makes the agent version sidecar evaluate the readiness of the agent with this
+	// interval and serve it as Prometheus metrics, e.g. "30s". It requires localReadinessProbe.
+	// +optional
+	ReadinessMetricsInterval *metav1.Duration `json:"readinessMetricsInterval,omitempty"`
+
+	// CredentialsRotationInterval rotates the password and keyfile of the agents once they are older than it,
+	// e.g. "720h". The credentials are only rotated through the opencga.zetta.com/rotate-agent-credentials
+	// annotation if it is not set.
+	// +optional
+	CredentialsRotationInterval *metav1.Duration `json:"credentialsRotationInterval,omitempty"`
+}
+
+// SecretKeyReference is a reference to a key of a Secret in the same namespace.
+type SecretKeyReference struct {
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // OpenCGAConfiguration holds the optional openCGA REST configuration
@@ -105,11 +810,54 @@ type OpenCGACommunityStatus struct {
 	CurrentStatefulSetReplicas int `json:"currentStatefulSetReplicas"`
 	CurrentRestMembers         int `json:"currentOpenCGARESTMembers"`
 
+	// Selector is the label selector of the REST Pods, read by autoscalers through the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
 	Message string `json:"message,omitempty"`
+
+	// Conditions describe the current state of the resource.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Migration is the state of the last catalog migration.
+	// +optional
+	Migration *MigrationStatus `json:"migration,omitempty"`
+
+	// Users are the users whose Secrets have been published. The Secrets of the users removed from the
+	// resource are deleted.
+	// +optional
+	Users []UserStatus `json:"users,omitempty"`
+}
+
+// UserStatus records the Secrets published for a user.
+type UserStatus struct {
+	Name                       string `json:"name"`
+	ConnectionSecretName       string `json:"connectionSecretName"`
+	ScramCredentialsSecretName string `json:"scramCredentialsSecretName"`
+}
+
+// MigrationStatus records the catalog migration run when the version changes.
+type MigrationStatus struct {
+	FromVersion string         `json:"fromVersion"`
+	ToVersion   string         `json:"toVersion"`
+	Phase       MigrationPhase `json:"phase"`
+
+	// JobName is the name of the Job running the migration.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Logs is the Pod of the last attempt of the migration Job, in the form namespace/name,
+	// whose logs hold the output of the migration.
+	// +optional
+	Logs string `json:"logs,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.members,statuspath=.status.currentOpenCGARESTMembers,selectorpath=.status.selector
 
 // OpenCGACommunity is the Schema for the opencgacommunities API
 // +kubebuilder:resource:path=opencgacommunity,scope=Namespaced,shortName=ocbc,singular=opencgacommunity
@@ -136,3 +884,627 @@ type OpenCGACommunityList struct {
 func init() {
 	SchemeBuilder.Register(&OpenCGACommunity{}, &OpenCGACommunityList{})
 }
+
+// ServiceName returns the name of the headless Service which governs the REST StatefulSet.
+func (m OpenCGACommunity) ServiceName() string {
+	return m.Name + "-svc"
+}
+
+// PodDisruptionBudgetName returns the name of the PodDisruptionBudget of the REST members.
+func (m OpenCGACommunity) PodDisruptionBudgetName() string {
+	return m.Name + "-pdb"
+}
+
+// GetMaxUnavailable returns the number of REST members which can be evicted at the same time,
+// or nil if no PodDisruptionBudget is needed.
+func (m OpenCGACommunity) GetMaxUnavailable() *intstr.IntOrString {
+	members := m.DesiredReplicas()
+	if members < 2 {
+		return nil
+	}
+	if m.Spec.PodDisruptionBudget.MaxUnavailable != nil {
+		maxUnavailable := *m.Spec.PodDisruptionBudget.MaxUnavailable
+		return &maxUnavailable
+	}
+	maxUnavailable := intstr.FromInt((members - 1) / 2)
+	if maxUnavailable.IntVal < 1 {
+		maxUnavailable = intstr.FromInt(1)
+	}
+	return &maxUnavailable
+}
+
+// RestPodSelector returns the label selector of the REST Pods, in its serialized form.
+func (m OpenCGACommunity) RestPodSelector() string {
+	return "app=" + m.ServiceName()
+}
+
+// HorizontalPodAutoscalerName returns the name of the HorizontalPodAutoscaler scaling the resource.
+func (m OpenCGACommunity) HorizontalPodAutoscalerName() string {
+	return m.Name + "-hpa"
+}
+
+// GetMinMembers returns the lowest number of members the autoscaler can scale down to.
+func (m OpenCGACommunity) GetMinMembers() int32 {
+	if m.Spec.Autoscaling == nil || m.Spec.Autoscaling.MinMembers == nil {
+		return 1
+	}
+	return *m.Spec.Autoscaling.MinMembers
+}
+
+// GetTargetCPUUtilizationPercentage returns the CPU utilization the autoscaler aims for, or nil if
+// the autoscaler only follows the request rate.
+func (m OpenCGACommunity) GetTargetCPUUtilizationPercentage() *int32 {
+	if m.Spec.Autoscaling == nil {
+		return nil
+	}
+	if m.Spec.Autoscaling.TargetCPUUtilizationPercentage != nil {
+		target := *m.Spec.Autoscaling.TargetCPUUtilizationPercentage
+		return &target
+	}
+	if m.Spec.Autoscaling.RequestRate != nil {
+		return nil
+	}
+	target := int32(defaultTargetCPUUtilizationPercentage)
+	return &target
+}
+
+// GetRequestRateMetricName returns the name of the Pod metric the autoscaler reads the request rate from.
+func (m OpenCGACommunity) GetRequestRateMetricName() string {
+	if m.Spec.Autoscaling == nil || m.Spec.Autoscaling.RequestRate == nil || m.Spec.Autoscaling.RequestRate.MetricName == "" {
+		return defaultRequestRateMetricName
+	}
+	return m.Spec.Autoscaling.RequestRate.MetricName
+}
+
+// QueryServiceName returns the name of the Service load-balancing the requests across all the REST members.
+func (m OpenCGACommunity) QueryServiceName() string {
+	return m.Name + "-query"
+}
+
+// AdminServiceName returns the name of the Service sending every request to the first REST member, so that
+// administrative operations are not spread across members.
+func (m OpenCGACommunity) AdminServiceName() string {
+	return m.Name + "-admin"
+}
+
+// GetSessionAffinity returns the session affinity of the query and admin Services along with its timeout,
+// which is nil unless the affinity is ClientIP.
+func (m OpenCGACommunity) GetSessionAffinity() (corev1.ServiceAffinity, *int32) {
+	rest := m.Spec.Server.Rest
+	if rest.SessionAffinity != corev1.ServiceAffinityClientIP {
+		return corev1.ServiceAffinityNone, nil
+	}
+	timeout := int32(corev1.DefaultClientIPServiceAffinitySeconds)
+	if rest.SessionAffinityTimeoutSeconds != nil {
+		timeout = *rest.SessionAffinityTimeoutSeconds
+	}
+	return corev1.ServiceAffinityClientIP, &timeout
+}
+
+// MasterName returns the name of the StatefulSet running the OpenCGA master.
+func (m OpenCGACommunity) MasterName() string {
+	return m.Name + "-master"
+}
+
+func (m OpenCGACommunity) AutomationConfigSecretName() string {
+	return m.Name + "-config"
+}
+
+// OpenCGAConfigSecretName returns the name of the Secret which stores the rendered OpenCGA configuration files.
+func (m OpenCGACommunity) OpenCGAConfigSecretName() string {
+	return m.Name + "-opencga-config"
+}
+
+// CatalogInstallJobName returns the name of the Job installing the catalog.
+func (m OpenCGACommunity) CatalogInstallJobName() string {
+	return m.Name + "-catalog-install"
+}
+
+// MigrationJobName returns the name of the Job migrating the catalog to the version of the resource.
+func (m OpenCGACommunity) MigrationJobName() string {
+	return m.Name + "-migration-" + strings.ReplaceAll(strings.ToLower(m.Spec.Version), ".", "-")
+}
+
+// GetAgentPasswordSecretNamespacedName returns the NamespacedName of the secret which stores the password of the agents.
+func (m OpenCGACommunity) GetAgentPasswordSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-agent-password", Namespace: m.Namespace}
+}
+
+func (m OpenCGACommunity) GetAgentKeyfileSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-keyfile", Namespace: m.Namespace}
+}
+
+// GetScramOptions returns the SCRAM options the agents and the users are configured with.
+func (m OpenCGACommunity) GetScramOptions() scram.Options {
+	rotation := scram.Rotation{Trigger: annotations.GetAnnotation(&m, AgentCredentialsRotationAnnotation)}
+	if interval := m.Spec.Agent.CredentialsRotationInterval; interval != nil {
+		rotation.Interval = interval.Duration
+	}
+	return scram.Options{
+		KeyFile:            AgentKeyfilePath,
+		AutoAuthMechanisms: []string{scram.Sha256},
+		AgentName:          scram.AgentName,
+		AutoAuthMechanism:  scram.Sha256,
+		Rotation:           rotation,
+
+		ScramSha1Iterations:   m.Spec.Security.Scram.Sha1Iterations,
+		ScramSha256Iterations: m.Spec.Security.Scram.Sha256Iterations,
+		Sha256Only:            m.Spec.Security.Scram.Sha256Only,
+	}
+}
+
+// GetScramUsers returns the users of the resource which authenticate with SCRAM.
+func (m OpenCGACommunity) GetScramUsers() []scram.User {
+	return m.GetUsers()
+}
+
+// GetAgentAPIKeySecretNamespacedName returns the NamespacedName of the secret which stores the API key of the agents.
+func (m OpenCGACommunity) GetAgentAPIKeySecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name + "-agent-api-key", Namespace: m.Namespace}
+}
+
+func (m OpenCGACommunity) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Name, Namespace: m.Namespace}
+}
+
+func (m OpenCGACommunity) GetOwnerReferences() []metav1.OwnerReference {
+	ownerReference := *metav1.NewControllerRef(&m, schema.GroupVersionKind{
+		Group:   GroupVersion.Group,
+		Version: GroupVersion.Version,
+		Kind:    "OpenCGACommunity",
+	})
+	return []metav1.OwnerReference{ownerReference}
+}
+
+func (m OpenCGACommunity) GetOpenCGAVersion() string {
+	return m.Spec.Version
+}
+
+// GetOpenCGAVersionForAnnotation returns the OpenCGA version to record in the last applied version annotation.
+func (m OpenCGACommunity) GetOpenCGAVersionForAnnotation() string {
+	return m.Spec.Version
+}
+
+// IsChangingVersion returns true if the version in the spec differs from the last version that was applied.
+func (m OpenCGACommunity) IsChangingVersion() bool {
+	lastVersion := annotations.GetAnnotation(&m, annotations.LastAppliedOpenCGAVersion)
+	return lastVersion != "" && lastVersion != m.Spec.Version
+}
+
+// GetLastAppliedOpenCGAVersion returns the version the resource was running before the current version change.
+func (m OpenCGACommunity) GetLastAppliedOpenCGAVersion() string {
+	return annotations.GetAnnotation(&m, annotations.LastAppliedOpenCGAVersion)
+}
+
+// IsMigrationApproved returns true if the catalog can be migrated to the version of the resource.
+func (m OpenCGACommunity) IsMigrationApproved() bool {
+	if !m.Spec.Upgrade.RequireMigrationApproval {
+		return true
+	}
+	return annotations.GetAnnotation(&m, MigrationApprovalAnnotation) == m.Spec.Version
+}
+
+// HasMigratedCatalog returns true if the catalog migration to the version of the resource has succeeded.
+func (m OpenCGACommunity) HasMigratedCatalog() bool {
+	migration := m.Status.Migration
+	return migration != nil && migration.ToVersion == m.Spec.Version && migration.Phase == MigrationSucceeded
+}
+
+// GetAutomationConfigOpenCGAVersion returns the version the agents are asked to run. While the version is
+// changing, the members keep the last applied version until the catalog has been migrated.
+func (m OpenCGACommunity) GetAutomationConfigOpenCGAVersion() string {
+	if m.IsChangingVersion() && !m.HasMigratedCatalog() {
+		return m.GetLastAppliedOpenCGAVersion()
+	}
+	return m.Spec.Version
+}
+
+// GetUpdateStrategyType returns the UpdateStrategyType of the StatefulSets. While the version is changing,
+// the Pods are held back until the catalog has been migrated, the StatefulSet controller then rolls them
+// one at a time.
+func (m OpenCGACommunity) GetUpdateStrategyType() appsv1.StatefulSetUpdateStrategyType {
+	if m.IsChangingVersion() && !m.HasMigratedCatalog() {
+		return appsv1.OnDeleteStatefulSetStrategyType
+	}
+	return appsv1.RollingUpdateStatefulSetStrategyType
+}
+
+func (m OpenCGACommunity) HasSeparateDataAndLogsVolumes() bool {
+	return true
+}
+
+func (m OpenCGACommunity) DataVolumeName() string {
+	return "data-volume"
+}
+
+func (m OpenCGACommunity) LogsVolumeName() string {
+	return "logs-volume"
+}
+
+func (m OpenCGACommunity) GetOpenCGAConfiguration() OpenCGAConfiguration {
+	return m.Spec.AdditionalOpenCGAConfig
+}
+
+func (m OpenCGACommunity) NeedsAutomationConfigVolume() bool {
+	return true
+}
+
+// IsReadinessProbeLocalMode returns true if the readiness probe of the agents runs without access to the Kubernetes API.
+func (m OpenCGACommunity) IsReadinessProbeLocalMode() bool {
+	return m.Spec.Agent.LocalReadinessProbe
+}
+
+// GetReadinessMetricsInterval returns the interval the agent version sidecar evaluates the readiness of the agent
+// with, or 0 if the readiness metrics are not served.
+func (m OpenCGACommunity) GetReadinessMetricsInterval() time.Duration {
+	if interval := m.Spec.Agent.ReadinessMetricsInterval; interval != nil && m.IsReadinessProbeLocalMode() {
+		return interval.Duration
+	}
+	return 0
+}
+
+// DesiredReplicas returns the number of members, kept within the autoscaling bounds when the
+// resource is autoscaled.
+func (m OpenCGACommunity) DesiredReplicas() int {
+	if m.Spec.Autoscaling == nil {
+		return m.Spec.Members
+	}
+	if minMembers := int(m.GetMinMembers()); m.Spec.Members < minMembers {
+		return minMembers
+	}
+	if maxMembers := int(m.Spec.Autoscaling.MaxMembers); m.Spec.Members > maxMembers {
+		return maxMembers
+	}
+	return m.Spec.Members
+}
+
+func (m OpenCGACommunity) CurrentReplicas() int {
+	return m.Status.CurrentStatefulSetReplicas
+}
+
+func (m OpenCGACommunity) ForcedIndividualScaling() bool {
+	return false
+}
+
+// GetLogLevel returns the log level of the OpenCGA server, falling back to the OpenCGA default.
+func (m OpenCGACommunity) GetLogLevel() string {
+	if m.Spec.Server.LogLevel == "" {
+		return automationconfig.DefaultLogLevel
+	}
+	return m.Spec.Server.LogLevel
+}
+
+// GetWorkspace returns the workspace directory, falling back to the OpenCGA default.
+func (m OpenCGACommunity) GetWorkspace() string {
+	if m.Spec.Server.Workspace == "" {
+		return automationconfig.DefaultWorkspace
+	}
+	return m.Spec.Server.Workspace
+}
+
+// GetDatabasePrefix returns the catalog database prefix, falling back to the OpenCGA default.
+func (m OpenCGACommunity) GetDatabasePrefix() string {
+	if m.Spec.Catalog.DatabasePrefix != "" {
+		return m.Spec.Catalog.DatabasePrefix
+	}
+	if m.Spec.Server.DatabasePrefix == "" {
+		return automationconfig.DefaultCatalogDatabasePrefix
+	}
+	return m.Spec.Server.DatabasePrefix
+}
+
+// GetRestPort returns the port of the REST API, falling back to the OpenCGA default.
+func (m OpenCGACommunity) GetRestPort() int {
+	if m.Spec.Server.Rest.Port == 0 {
+		return automationconfig.DefaultRestPort
+	}
+	return m.Spec.Server.Rest.Port
+}
+
+// GetThreadPool returns the Jetty thread pool settings, falling back to the OpenCGA defaults for unset values.
+func (m OpenCGACommunity) GetThreadPool() automationconfig.ThreadPool {
+	threadPool := automationconfig.ThreadPool{
+		MinThreads:    automationconfig.DefaultMinThreads,
+		MaxThreads:    automationconfig.DefaultMaxThreads,
+		IdleTimeoutMs: automationconfig.DefaultIdleTimeoutMs,
+	}
+	if m.Spec.Server.Rest.MinThreads != 0 {
+		threadPool.MinThreads = m.Spec.Server.Rest.MinThreads
+	}
+	if m.Spec.Server.Rest.MaxThreads != 0 {
+		threadPool.MaxThreads = m.Spec.Server.Rest.MaxThreads
+	}
+	return threadPool
+}
+
+// GetJVMOptions returns the JVM options, falling back to the OpenCGA defaults for unset values.
+func (m OpenCGACommunity) GetJVMOptions() automationconfig.JVMOptions {
+	jvm := automationconfig.JVMOptions{
+		InitialHeap:  automationconfig.DefaultInitialHeap,
+		MaxHeap:      automationconfig.DefaultMaxHeap,
+		ExtraOptions: m.Spec.Server.JVM.ExtraOptions,
+	}
+	if m.Spec.Server.JVM.InitialHeap != "" {
+		jvm.InitialHeap = m.Spec.Server.JVM.InitialHeap
+	}
+	if m.Spec.Server.JVM.MaxHeap != "" {
+		jvm.MaxHeap = m.Spec.Server.JVM.MaxHeap
+	}
+	return jvm
+}
+
+// RestURI returns the in-cluster URL of the OpenCGA REST API, served by the query Service so that clients
+// are load-balanced across the ready members and keep their session affinity.
+func (m OpenCGACommunity) RestURI(clusterDomain string) string {
+	if clusterDomain == "" {
+		clusterDomain = defaultClusterDomain
+	}
+	scheme := "http"
+	if m.IsTLSEnabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc.%s:%d/opencga", scheme, m.QueryServiceName(), m.Namespace, clusterDomain, m.GetRestPort())
+}
+
+// IsTLSEnabled returns true if the REST API is served over TLS.
+func (m OpenCGACommunity) IsTLSEnabled() bool {
+	return m.Spec.Security.TLS.Enabled
+}
+
+// GetTLSMode returns the TLS mode the members end up in: requireTLS, preferTLS if TLS is optional,
+// or disabled.
+func (m OpenCGACommunity) GetTLSMode() automationconfig.TLSMode {
+	if !m.IsTLSEnabled() {
+		return automationconfig.TLSModeDisabled
+	}
+	if m.Spec.Security.TLS.Optional {
+		return automationconfig.TLSModePreferred
+	}
+	return automationconfig.TLSModeRequired
+}
+
+// GetClientCertificateMode returns whether the members require client certificates in the given TLS mode.
+// Client certificates can only be required once TLS is.
+func (m OpenCGACommunity) GetClientCertificateMode(tlsMode automationconfig.TLSMode) automationconfig.ClientCertificateMode {
+	if m.Spec.Security.TLS.RequireClientCertificates && tlsMode == automationconfig.TLSModeRequired {
+		return automationconfig.ClientCertificateModeRequired
+	}
+	return automationconfig.ClientCertificateModeOptional
+}
+
+// GetX509Users returns the users authenticated by the subject of their client certificate.
+func (m OpenCGACommunity) GetX509Users() []x509.User {
+	users := make([]x509.User, 0, len(m.Spec.Security.X509Users))
+	for _, u := range m.Spec.Security.X509Users {
+		user := x509.User{Subject: u.Subject}
+		for _, role := range u.Roles {
+			user.Roles = append(user.Roles, automationconfig.Role{Role: role.Name, Database: role.DB})
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// TLSSecretNamespacedName returns the NamespacedName of the kubernetes.io/tls Secret with the certificate of the members.
+func (m OpenCGACommunity) TLSSecretNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Spec.Security.TLS.CertificateKeySecretRef.Name, Namespace: m.Namespace}
+}
+
+// TLSCAConfigMapNamespacedName returns the NamespacedName of the ConfigMap with the CA certificate.
+func (m OpenCGACommunity) TLSCAConfigMapNamespacedName() types.NamespacedName {
+	return types.NamespacedName{Name: m.Spec.Security.TLS.CAConfigMapRef.Name, Namespace: m.Namespace}
+}
+
+// TLSOperatorSecretName returns the name of the Secret the operator stores the combined certificate and
+// private key of the members, and the CA certificate, in.
+func (m OpenCGACommunity) TLSOperatorSecretName() string {
+	return m.Name + "-server-certificate-key"
+}
+
+// IngressName returns the name of the Ingress exposing the REST API.
+func (m OpenCGACommunity) IngressName() string {
+	return m.Name + "-rest"
+}
+
+// GetIngressPath returns the path the REST API is exposed at by the Ingress.
+func (m OpenCGACommunity) GetIngressPath() string {
+	if m.Spec.Ingress == nil || m.Spec.Ingress.Path == "" {
+		return defaultIngressPath
+	}
+	return m.Spec.Ingress.Path
+}
+
+// ExternalRestURI returns the URL the REST API is exposed at by the Ingress, or an empty string if it is not exposed.
+func (m OpenCGACommunity) ExternalRestURI() string {
+	if m.Spec.Ingress == nil {
+		return ""
+	}
+	scheme := "http"
+	if m.Spec.Ingress.TLSSecretName != "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, m.Spec.Ingress.Host, strings.TrimSuffix(m.GetIngressPath(), "/"))
+}
+
+// GetCatalogUser returns the user OpenCGA connects to the catalog as, along with the Secret its password is stored in.
+func (m OpenCGACommunity) GetCatalogUser() scram.User {
+	user := scram.User{
+		Username:           defaultCatalogUserName,
+		Database:           m.GetCatalogAuthenticationDatabase(),
+		PasswordSecretName: m.Name + "-catalog-password",
+		PasswordSecretKey:  defaultPasswordKey,
+	}
+	if name := m.Spec.Catalog.User.Name; name != "" {
+		user.Username = name
+	}
+	if ref := m.Spec.Catalog.User.PasswordSecretRef; ref.Name != "" {
+		user.PasswordSecretName = ref.Name
+	}
+	if key := m.Spec.Catalog.User.PasswordSecretRef.Key; key != "" {
+		user.PasswordSecretKey = key
+	}
+	return user
+}
+
+// GetUsers returns the users declared in the resource. Their database is the database prefix of the catalog.
+func (m OpenCGACommunity) GetUsers() []scram.User {
+	users := make([]scram.User, 0, len(m.Spec.Users))
+	for _, u := range m.Spec.Users {
+		user := scram.User{
+			Username:                   u.Name,
+			Database:                   m.GetDatabasePrefix(),
+			PasswordSecretName:         u.PasswordSecretRef.Name,
+			PasswordSecretKey:          u.PasswordSecretRef.Key,
+			ConnectionStringSecretName: u.ConnectionSecretName,
+			ScramCredentialsSecretName: fmt.Sprintf("%s-%s-scram-credentials", m.Name, u.Name),
+			ScramSha1Iterations:        u.ScramSha1Iterations,
+			ScramSha256Iterations:      u.ScramSha256Iterations,
+		}
+		if user.PasswordSecretKey == "" {
+			user.PasswordSecretKey = defaultPasswordKey
+		}
+		for _, role := range u.Roles {
+			user.Roles = append(user.Roles, scram.Role{Name: role.Name, Database: role.DB})
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// GetCustomRoles returns the custom roles of the resource as automation config roles.
+func (m OpenCGACommunity) GetCustomRoles() []automationconfig.CustomRole {
+	roles := make([]automationconfig.CustomRole, 0, len(m.Spec.Security.Roles))
+	for _, r := range m.Spec.Security.Roles {
+		role := automationconfig.CustomRole{
+			Role: r.Name,
+			DB:   r.DB,
+		}
+		for _, p := range r.Privileges {
+			role.Privileges = append(role.Privileges, automationconfig.Privilege{
+				Resource: automationconfig.Resource{
+					DB:          p.Resource.DB,
+					Collection:  p.Resource.Collection,
+					AnyResource: p.Resource.AnyResource,
+					Cluster:     p.Resource.Cluster,
+				},
+				Actions: p.Actions,
+			})
+		}
+		for _, inherited := range r.Roles {
+			role.Roles = append(role.Roles, automationconfig.Role{Role: inherited.Name, Database: inherited.DB})
+		}
+		for _, restriction := range r.AuthenticationRestrictions {
+			role.AuthenticationRestrictions = append(role.AuthenticationRestrictions, automationconfig.AuthenticationRestriction{
+				ClientSource:  restriction.ClientSource,
+				ServerAddress: restriction.ServerAddress,
+			})
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// GetCatalogAuthenticationDatabase returns the database the catalog user is defined in.
+func (m OpenCGACommunity) GetCatalogAuthenticationDatabase() string {
+	if m.Spec.Catalog.AuthenticationDatabase == "" {
+		return opencgaconfig.DefaultCatalogAuthenticationDatabase
+	}
+	return m.Spec.Catalog.AuthenticationDatabase
+}
+
+// GetCatalogConnectionStringSecretKeyRef returns the Secret key holding the catalog connection string,
+// or nil if the hosts are listed in the resource.
+func (m OpenCGACommunity) GetCatalogConnectionStringSecretKeyRef() *corev1.SecretKeySelector {
+	return secretKeySelector(m.Spec.Catalog.ConnectionStringSecretRef, defaultConnectionStringKey)
+}
+
+// GetCatalogCASecretKeyRef returns the Secret key holding the CA certificate of the catalog,
+// or nil if TLS is disabled or the default trust store is used.
+func (m OpenCGACommunity) GetCatalogCASecretKeyRef() *corev1.SecretKeySelector {
+	if !m.Spec.Catalog.TLS.Enabled {
+		return nil
+	}
+	return secretKeySelector(m.Spec.Catalog.TLS.CaCertificateSecretRef, defaultCACertificateKey)
+}
+
+// GetCatalogClientCertificateSecretName returns the name of the kubernetes.io/tls Secret holding the client certificate
+// OpenCGA authenticates to the catalog with, or an empty string if it authenticates with a password.
+func (m OpenCGACommunity) GetCatalogClientCertificateSecretName() string {
+	if !m.Spec.Catalog.TLS.Enabled || m.Spec.Catalog.TLS.ClientCertificateSecretRef == nil {
+		return ""
+	}
+	return m.Spec.Catalog.TLS.ClientCertificateSecretRef.Name
+}
+
+// GetLDAPBindPasswordSecretKeyRef returns the Secret key holding the password OpenCGA binds to LDAP with,
+// or nil if LDAP is not configured or bound to anonymously.
+func (m OpenCGACommunity) GetLDAPBindPasswordSecretKeyRef() *corev1.SecretKeySelector {
+	if m.Spec.Security.LDAP == nil {
+		return nil
+	}
+	return secretKeySelector(m.Spec.Security.LDAP.BindPasswordSecretRef, defaultPasswordKey)
+}
+
+// GetLDAPCASecretKeyRef returns the Secret key holding the CA certificate of the LDAP servers,
+// or nil if LDAP is not configured or the default trust store is used.
+func (m OpenCGACommunity) GetLDAPCASecretKeyRef() *corev1.SecretKeySelector {
+	if m.Spec.Security.LDAP == nil {
+		return nil
+	}
+	return secretKeySelector(m.Spec.Security.LDAP.CaCertificateSecretRef, defaultCACertificateKey)
+}
+
+// GetOIDCClientSecretSecretKeyRef returns the Secret key holding the secret of the OpenID client,
+// or nil if OIDC is not configured.
+func (m OpenCGACommunity) GetOIDCClientSecretSecretKeyRef() *corev1.SecretKeySelector {
+	if m.Spec.Security.OIDC == nil {
+		return nil
+	}
+	return secretKeySelector(&m.Spec.Security.OIDC.ClientSecretRef, defaultClientSecretKey)
+}
+
+// GetVariantStorageEngine returns the variant storage engine, "mongodb" unless configured otherwise.
+func (m OpenCGACommunity) GetVariantStorageEngine() string {
+	if m.Spec.VariantStorage.Engine == "" {
+		return opencgaconfig.VariantStorageEngineMongoDB
+	}
+	return m.Spec.VariantStorage.Engine
+}
+
+// GetHadoopVariantStorage returns the settings of the Hadoop engine, or nil if another engine is used.
+func (m OpenCGACommunity) GetHadoopVariantStorage() *HadoopVariantStorageSpec {
+	if m.GetVariantStorageEngine() != opencgaconfig.VariantStorageEngineHadoop {
+		return nil
+	}
+	return m.Spec.VariantStorage.Hadoop
+}
+
+// GetAdminPasswordSecretKeyRef returns the Secret key holding the password of the admin user.
+func (m OpenCGACommunity) GetAdminPasswordSecretKeyRef() corev1.SecretKeySelector {
+	if ref := secretKeySelector(m.Spec.Admin.PasswordSecretRef, defaultPasswordKey); ref != nil {
+		return *ref
+	}
+	return corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: m.Name + "-admin-password"},
+		Key:                  defaultPasswordKey,
+	}
+}
+
+// IsCatalogInstalled returns true if the catalog install Job has completed.
+func (m OpenCGACommunity) IsCatalogInstalled() bool {
+	return meta.IsStatusConditionTrue(m.Status.Conditions, ConditionCatalogInstalled)
+}
+
+func secretKeySelector(ref *SecretKeyReference, defaultKey string) *corev1.SecretKeySelector {
+	if ref == nil {
+		return nil
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultKey
+	}
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+		Key:                  key,
+	}
+}
@@ -22,16 +22,387 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminSpec) DeepCopyInto(out *AdminSpec) {
+	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminSpec.
+func (in *AdminSpec) DeepCopy() *AdminSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentSpec) DeepCopyInto(out *AgentSpec) {
+	*out = *in
+	if in.ReadinessMetricsInterval != nil {
+		in, out := &in.ReadinessMetricsInterval, &out.ReadinessMetricsInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CredentialsRotationInterval != nil {
+		in, out := &in.CredentialsRotationInterval, &out.CredentialsRotationInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentSpec.
+func (in *AgentSpec) DeepCopy() *AgentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthenticationRestriction) DeepCopyInto(out *AuthenticationRestriction) {
+	*out = *in
+	if in.ClientSource != nil {
+		in, out := &in.ClientSource, &out.ClientSource
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServerAddress != nil {
+		in, out := &in.ServerAddress, &out.ServerAddress
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationRestriction.
+func (in *AuthenticationRestriction) DeepCopy() *AuthenticationRestriction {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthenticationRestriction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingSpec) DeepCopyInto(out *AutoscalingSpec) {
+	*out = *in
+	if in.MinMembers != nil {
+		in, out := &in.MinMembers, &out.MinMembers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RequestRate != nil {
+		in, out := &in.RequestRate, &out.RequestRate
+		*out = new(RequestRateMetricSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingSpec.
+func (in *AutoscalingSpec) DeepCopy() *AutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CatalogSpec) DeepCopyInto(out *CatalogSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConnectionStringSecretRef != nil {
+		in, out := &in.ConnectionStringSecretRef, &out.ConnectionStringSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	out.User = in.User
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogSpec.
+func (in *CatalogSpec) DeepCopy() *CatalogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CatalogTLSSpec) DeepCopyInto(out *CatalogTLSSpec) {
+	*out = *in
+	if in.CaCertificateSecretRef != nil {
+		in, out := &in.CaCertificateSecretRef, &out.CaCertificateSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.ClientCertificateSecretRef != nil {
+		in, out := &in.ClientCertificateSecretRef, &out.ClientCertificateSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogTLSSpec.
+func (in *CatalogTLSSpec) DeepCopy() *CatalogTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CatalogTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CatalogUserSpec) DeepCopyInto(out *CatalogUserSpec) {
+	*out = *in
+	out.PasswordSecretRef = in.PasswordSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogUserSpec.
+func (in *CatalogUserSpec) DeepCopy() *CatalogUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CatalogUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRole) DeepCopyInto(out *CustomRole) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]Privilege, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]RoleReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.AuthenticationRestrictions != nil {
+		in, out := &in.AuthenticationRestrictions, &out.AuthenticationRestrictions
+		*out = make([]AuthenticationRestriction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRole.
+func (in *CustomRole) DeepCopy() *CustomRole {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HadoopVariantStorageSpec) DeepCopyInto(out *HadoopVariantStorageSpec) {
+	*out = *in
+	out.HadoopConfigMapRef = in.HadoopConfigMapRef
+	out.HBaseConfigMapRef = in.HBaseConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HadoopVariantStorageSpec.
+func (in *HadoopVariantStorageSpec) DeepCopy() *HadoopVariantStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HadoopVariantStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JVMSpec) DeepCopyInto(out *JVMSpec) {
+	*out = *in
+	if in.ExtraOptions != nil {
+		in, out := &in.ExtraOptions, &out.ExtraOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JVMSpec.
+func (in *JVMSpec) DeepCopy() *JVMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JVMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPSpec) DeepCopyInto(out *LDAPSpec) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BindPasswordSecretRef != nil {
+		in, out := &in.BindPasswordSecretRef, &out.BindPasswordSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.CaCertificateSecretRef != nil {
+		in, out := &in.CaCertificateSecretRef, &out.CaCertificateSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPSpec.
+func (in *LDAPSpec) DeepCopy() *LDAPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
+func (in *MigrationStatus) DeepCopy() *MigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBVariantStorageSpec) DeepCopyInto(out *MongoDBVariantStorageSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBVariantStorageSpec.
+func (in *MongoDBVariantStorageSpec) DeepCopy() *MongoDBVariantStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBVariantStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClaimsSpec) DeepCopyInto(out *OIDCClaimsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClaimsSpec.
+func (in *OIDCClaimsSpec) DeepCopy() *OIDCClaimsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClaimsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCSpec) DeepCopyInto(out *OIDCSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Claims = in.Claims
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCSpec.
+func (in *OIDCSpec) DeepCopy() *OIDCSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenCGACommunity) DeepCopyInto(out *OpenCGACommunity) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenCGACommunity.
@@ -87,6 +458,39 @@ func (in *OpenCGACommunityList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenCGACommunitySpec) DeepCopyInto(out *OpenCGACommunitySpec) {
 	*out = *in
+	in.Server.DeepCopyInto(&out.Server)
+	in.Catalog.DeepCopyInto(&out.Catalog)
+	if in.Search != nil {
+		in, out := &in.Search, &out.Search
+		*out = new(SearchSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.VariantStorage.DeepCopyInto(&out.VariantStorage)
+	in.Admin.DeepCopyInto(&out.Admin)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]UserSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Security.DeepCopyInto(&out.Security)
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	out.Upgrade = in.Upgrade
+	in.Agent.DeepCopyInto(&out.Agent)
+	in.AdditionalOpenCGAConfig.DeepCopyInto(&out.AdditionalOpenCGAConfig)
+	in.AdditionalStorageConfig.DeepCopyInto(&out.AdditionalStorageConfig)
+	in.AdditionalClientConfig.DeepCopyInto(&out.AdditionalClientConfig)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenCGACommunitySpec.
@@ -102,6 +506,23 @@ func (in *OpenCGACommunitySpec) DeepCopy() *OpenCGACommunitySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenCGACommunityStatus) DeepCopyInto(out *OpenCGACommunityStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(MigrationStatus)
+		**out = **in
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]UserStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenCGACommunityStatus.
@@ -119,3 +540,351 @@ func (in *OpenCGAConfiguration) DeepCopyInto(out *OpenCGAConfiguration) {
 	clone := in.DeepCopy()
 	*out = *clone
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Privilege) DeepCopyInto(out *Privilege) {
+	*out = *in
+	in.Resource.DeepCopyInto(&out.Resource)
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Privilege.
+func (in *Privilege) DeepCopy() *Privilege {
+	if in == nil {
+		return nil
+	}
+	out := new(Privilege)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequestRateMetricSpec) DeepCopyInto(out *RequestRateMetricSpec) {
+	*out = *in
+	out.TargetAverageValue = in.TargetAverageValue.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestRateMetricSpec.
+func (in *RequestRateMetricSpec) DeepCopy() *RequestRateMetricSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RequestRateMetricSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Resource) DeepCopyInto(out *Resource) {
+	*out = *in
+	if in.DB != nil {
+		in, out := &in.DB, &out.DB
+		*out = new(string)
+		**out = **in
+	}
+	if in.Collection != nil {
+		in, out := &in.Collection, &out.Collection
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Resource.
+func (in *Resource) DeepCopy() *Resource {
+	if in == nil {
+		return nil
+	}
+	out := new(Resource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestSpec) DeepCopyInto(out *RestSpec) {
+	*out = *in
+	if in.SessionAffinityTimeoutSeconds != nil {
+		in, out := &in.SessionAffinityTimeoutSeconds, &out.SessionAffinityTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestSpec.
+func (in *RestSpec) DeepCopy() *RestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleReference) DeepCopyInto(out *RoleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleReference.
+func (in *RoleReference) DeepCopy() *RoleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScramSpec) DeepCopyInto(out *ScramSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScramSpec.
+func (in *ScramSpec) DeepCopy() *ScramSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScramSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SearchSpec) DeepCopyInto(out *SearchSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ZooKeeperHosts != nil {
+		in, out := &in.ZooKeeperHosts, &out.ZooKeeperHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SearchSpec.
+func (in *SearchSpec) DeepCopy() *SearchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SearchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecuritySpec) DeepCopyInto(out *SecuritySpec) {
+	*out = *in
+	out.TLS = in.TLS
+	if in.X509Users != nil {
+		in, out := &in.X509Users, &out.X509Users
+		*out = make([]X509User, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LDAP != nil {
+		in, out := &in.LDAP, &out.LDAP
+		*out = new(LDAPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(OIDCSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]CustomRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.Scram = in.Scram
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecuritySpec.
+func (in *SecuritySpec) DeepCopy() *SecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
+	*out = *in
+	in.Rest.DeepCopyInto(&out.Rest)
+	in.JVM.DeepCopyInto(&out.JVM)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSpec.
+func (in *ServerSpec) DeepCopy() *ServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLS) DeepCopyInto(out *TLS) {
+	*out = *in
+	out.CertificateKeySecretRef = in.CertificateKeySecretRef
+	out.CAConfigMapRef = in.CAConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLS.
+func (in *TLS) DeepCopy() *TLS {
+	if in == nil {
+		return nil
+	}
+	out := new(TLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeSpec) DeepCopyInto(out *UpgradeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSpec.
+func (in *UpgradeSpec) DeepCopy() *UpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSpec) DeepCopyInto(out *UserSpec) {
+	*out = *in
+	out.PasswordSecretRef = in.PasswordSecretRef
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]RoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSpec.
+func (in *UserSpec) DeepCopy() *UserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserStatus) DeepCopyInto(out *UserStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserStatus.
+func (in *UserStatus) DeepCopy() *UserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VariantStorageSpec) DeepCopyInto(out *VariantStorageSpec) {
+	*out = *in
+	if in.MongoDB != nil {
+		in, out := &in.MongoDB, &out.MongoDB
+		*out = new(MongoDBVariantStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hadoop != nil {
+		in, out := &in.Hadoop, &out.Hadoop
+		*out = new(HadoopVariantStorageSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VariantStorageSpec.
+func (in *VariantStorageSpec) DeepCopy() *VariantStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VariantStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *X509User) DeepCopyInto(out *X509User) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]RoleReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new X509User.
+func (in *X509User) DeepCopy() *X509User {
+	if in == nil {
+		return nil
+	}
+	out := new(X509User)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -4,12 +4,22 @@ import (
 	"reflect"
 
 	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
+// triggerAnnotations are set by users to request an action from the operator, changing them
+// triggers a reconciliation.
+var triggerAnnotations = []string{
+	opencgav1.MigrationApprovalAnnotation,
+	opencgav1.AgentAPIKeyRotationAnnotation,
+	opencgav1.AgentCredentialsRotationAnnotation,
+}
+
 // OnlyOnSpecChange returns a set of predicates indicating
-// that reconciliations should only happen on changes to the Spec of the resource.
+// that reconciliations should only happen on changes to the Spec of the resource, or to the annotations
+// requesting an action from the operator.
 // any other changes won't trigger a reconciliation. This allows us to freely update the annotations
 // of the resource without triggering unintentional reconciliations.
 func OnlyOnSpecChange() predicate.Funcs {
@@ -18,7 +28,59 @@ func OnlyOnSpecChange() predicate.Funcs {
 			oldResource := e.ObjectOld.(*opencgav1.OpenCGACommunity)
 			newResource := e.ObjectNew.(*opencgav1.OpenCGACommunity)
 			specChanged := !reflect.DeepEqual(oldResource.Spec, newResource.Spec)
-			return specChanged
+			return specChanged || triggerAnnotationsChanged(oldResource, newResource)
 		},
 	}
 }
+
+// OnlyOnSecretDataChange returns a set of predicates for watched Secrets indicating that reconciliations
+// should only happen when a Secret is created or its data changes. Metadata changes, such as the
+// resourceVersion bumped on every resync by external secret stores, are ignored.
+func OnlyOnSecretDataChange() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret, okOld := e.ObjectOld.(*corev1.Secret)
+			newSecret, okNew := e.ObjectNew.(*corev1.Secret)
+			if !okOld || !okNew {
+				return false
+			}
+			return !reflect.DeepEqual(oldSecret.Data, newSecret.Data) || !reflect.DeepEqual(oldSecret.StringData, newSecret.StringData)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// OnlyOnConfigMapDataChange returns a set of predicates for watched ConfigMaps indicating that reconciliations
+// should only happen when a ConfigMap is created or its data changes.
+func OnlyOnConfigMapDataChange() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldConfigMap, okOld := e.ObjectOld.(*corev1.ConfigMap)
+			newConfigMap, okNew := e.ObjectNew.(*corev1.ConfigMap)
+			if !okOld || !okNew {
+				return false
+			}
+			return !reflect.DeepEqual(oldConfigMap.Data, newConfigMap.Data) || !reflect.DeepEqual(oldConfigMap.BinaryData, newConfigMap.BinaryData)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func triggerAnnotationsChanged(oldResource, newResource *opencgav1.OpenCGACommunity) bool {
+	for _, annotation := range triggerAnnotations {
+		if oldResource.Annotations[annotation] != newResource.Annotations[annotation] {
+			return true
+		}
+	}
+	return false
+}
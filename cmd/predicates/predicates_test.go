@@ -0,0 +1,74 @@
+package predicates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/pkg/kube/annotations"
+)
+
+func TestOnlyOnSpecChange(t *testing.T) {
+	updated := func(modify func(*opencgav1.OpenCGACommunity)) bool {
+		oldResource := &opencgav1.OpenCGACommunity{Spec: opencgav1.OpenCGACommunitySpec{Members: 3}}
+		newResource := oldResource.DeepCopy()
+		modify(newResource)
+		return OnlyOnSpecChange().Update(event.UpdateEvent{ObjectOld: oldResource, ObjectNew: newResource})
+	}
+
+	assert.True(t, updated(func(ocb *opencgav1.OpenCGACommunity) { ocb.Spec.Members = 5 }))
+	assert.False(t, updated(func(ocb *opencgav1.OpenCGACommunity) { ocb.Status.Phase = opencgav1.Running }))
+	assert.False(t, updated(func(ocb *opencgav1.OpenCGACommunity) {
+		ocb.Annotations = map[string]string{annotations.LastAppliedOpenCGAVersion: "2.2.0"}
+	}), "annotations set by the operator should not trigger a reconciliation")
+	assert.True(t, updated(func(ocb *opencgav1.OpenCGACommunity) {
+		ocb.Annotations = map[string]string{opencgav1.AgentAPIKeyRotationAnnotation: "2022-06-01"}
+	}))
+	assert.True(t, updated(func(ocb *opencgav1.OpenCGACommunity) {
+		ocb.Annotations = map[string]string{opencgav1.MigrationApprovalAnnotation: "2.3.0"}
+	}))
+	assert.True(t, updated(func(ocb *opencgav1.OpenCGACommunity) {
+		ocb.Annotations = map[string]string{opencgav1.AgentCredentialsRotationAnnotation: "2022-06-01"}
+	}))
+}
+
+func TestOnlyOnSecretDataChange(t *testing.T) {
+	updated := func(modify func(*corev1.Secret)) bool {
+		oldSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice-password", Namespace: "my-ns", ResourceVersion: "1"},
+			Data:       map[string][]byte{"password": []byte("old")},
+		}
+		newSecret := oldSecret.DeepCopy()
+		modify(newSecret)
+		return OnlyOnSecretDataChange().Update(event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: newSecret})
+	}
+
+	assert.True(t, updated(func(s *corev1.Secret) { s.Data["password"] = []byte("new") }))
+	assert.True(t, updated(func(s *corev1.Secret) { s.Data["other"] = []byte("value") }))
+	assert.False(t, updated(func(s *corev1.Secret) { s.ResourceVersion = "2" }))
+	assert.False(t, updated(func(s *corev1.Secret) { s.Labels = map[string]string{"synced": "true"} }))
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "alice-password", Namespace: "my-ns"}}
+	assert.True(t, OnlyOnSecretDataChange().Create(event.CreateEvent{Object: secret}))
+	assert.False(t, OnlyOnSecretDataChange().Delete(event.DeleteEvent{Object: secret}))
+}
+
+func TestOnlyOnConfigMapDataChange(t *testing.T) {
+	updated := func(modify func(*corev1.ConfigMap)) bool {
+		oldConfigMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-rs-ca", Namespace: "my-ns", ResourceVersion: "1"},
+			Data:       map[string]string{"ca.crt": "old"},
+		}
+		newConfigMap := oldConfigMap.DeepCopy()
+		modify(newConfigMap)
+		return OnlyOnConfigMapDataChange().Update(event.UpdateEvent{ObjectOld: oldConfigMap, ObjectNew: newConfigMap})
+	}
+
+	assert.True(t, updated(func(c *corev1.ConfigMap) { c.Data["ca.crt"] = "new" }))
+	assert.True(t, updated(func(c *corev1.ConfigMap) { c.BinaryData = map[string][]byte{"ca.der": []byte("new")} }))
+	assert.False(t, updated(func(c *corev1.ConfigMap) { c.ResourceVersion = "2" }))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,6 +25,7 @@ import (
 
 const (
 	headlessAgent                 = "HEADLESS_AGENT"
+	localMode                     = "READINESS_PROBE_LOCAL_MODE"
 	mongodNotReadyIntervalMinutes = time.Minute * 1
 )
 
@@ -175,6 +177,25 @@ func isHeadlessMode() bool {
 	return os.Getenv(headlessAgent) == "true"
 }
 
+// isLocalMode returns true if the probe should not talk to the Kubernetes API. The target automation config
+// version is then read from the mounted file and the achieved version is written to a local file.
+func isLocalMode() bool {
+	return os.Getenv(localMode) == "true"
+}
+
+// probeClientset returns the clientset the health status is evaluated with, or nil if the probe runs without
+// access to the Kubernetes API.
+func probeClientset() kubernetes.Interface {
+	if isLocalMode() {
+		return nil
+	}
+	clientSet, err := kubernetesClientset()
+	if err != nil {
+		panic(err)
+	}
+	return clientSet
+}
+
 func kubernetesClientset() (kubernetes.Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -209,12 +230,23 @@ func initLogger(l *lumberjack.Logger) {
 }
 
 func main() {
-	clientSet, err := kubernetesClientset()
-	if err != nil {
-		panic(err)
+	listenAddress := flag.String("listen-address", "", "If set, serve the achieved automation config version on this address instead of running the readiness check")
+	metricsInterval := flag.Duration("metrics-interval", 0, "If positive, evaluate the health status with this interval and serve Prometheus metrics. Requires -listen-address")
+	flag.Parse()
+
+	if *listenAddress != "" {
+		// serving the agent version only reads a local file, the API is only needed to evaluate the health status
+		var clientSet kubernetes.Interface
+		if *metricsInterval > 0 {
+			clientSet = probeClientset()
+		}
+		if err := serve(*listenAddress, *metricsInterval, clientSet); err != nil {
+			panic(err)
+		}
+		return
 	}
 
-	cfg, err := config.BuildFromEnvVariables(clientSet, isHeadlessMode())
+	cfg, err := config.BuildFromEnvVariables(probeClientset(), isHeadlessMode())
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/phamidko/opencga-operator/pkg/readiness/config"
+	"github.com/phamidko/opencga-operator/pkg/readiness/headless"
+	"github.com/phamidko/opencga-operator/pkg/readiness/health"
+	"github.com/phamidko/opencga-operator/pkg/readiness/metrics"
+)
+
+const metricsPath = "/metrics"
+
+// serve runs the readiness binary as a long-running sidecar. It always serves the version recorded by the
+// probe in local mode so that the Operator can scrape it instead of reading Pod annotations. If metricsInterval
+// is positive, the health status is also evaluated periodically and exposed as Prometheus metrics.
+func serve(listenAddress string, metricsInterval time.Duration, clientSet kubernetes.Interface) error {
+	mux := http.NewServeMux()
+	mux.Handle(headless.AgentVersionPath, headless.AgentVersionHandler(config.AgentVersionFilePath()))
+
+	if metricsInterval > 0 {
+		cfg, err := config.BuildForMonitoring(clientSet, isHeadlessMode())
+		if err != nil {
+			return err
+		}
+
+		registry := prometheus.NewRegistry()
+		recorder, err := metrics.NewRecorder(registry)
+		if err != nil {
+			return err
+		}
+		go monitor(cfg, recorder, metricsInterval)
+
+		mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		logger.Infof("Serving readiness metrics on %s%s", listenAddress, metricsPath)
+	}
+
+	logger.Infof("Serving the agent version on %s%s", listenAddress, headless.AgentVersionPath)
+	return http.ListenAndServe(listenAddress, mux)
+}
+
+// monitor records an observation of the health status every interval.
+func monitor(cfg config.Config, recorder *metrics.Recorder, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		healthStatus, err := readHealthStatusFile(cfg.HealthStatusFilePath)
+		if err != nil {
+			logger.Warnf("Could not read the health status file: %s", err)
+		} else {
+			recorder.Record(observe(healthStatus, cfg))
+		}
+		<-ticker.C
+	}
+}
+
+func readHealthStatusFile(path string) (health.Status, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return health.Status{}, err
+	}
+	defer file.Close()
+	return parseHealthStatus(file)
+}
+
+// observe evaluates the health status with the same predicates as isPodReady and returns the readiness decision
+// along with the values it is based on. Contrary to isPodReady, the achieved version is not reported anywhere.
+func observe(healthStatus health.Status, conf config.Config) metrics.Observation {
+	observation := metrics.Observation{
+		AchievedVersion: metrics.UnknownVersion,
+		TargetVersion:   metrics.UnknownVersion,
+	}
+
+	if currentStep := findCurrentStep(healthStatus.ProcessPlans); currentStep != nil {
+		observation.CurrentStep = currentStep.Step
+		observation.CurrentStepStarted = *currentStep.Started
+	}
+
+	// The 'statuses' file can be empty only for OM Agents
+	if len(healthStatus.Healthiness) == 0 && !isHeadlessMode() {
+		observation.Ready = true
+		return observation
+	}
+
+	if isHeadlessMode() {
+		targetVersion, err := headless.ReadTargetVersion(conf)
+		if err != nil {
+			logger.Warnf("Could not read the target automation config version: %s", err)
+		} else {
+			observation.TargetVersion = targetVersion
+			observation.AchievedVersion = headless.ReadCurrentAgentVersion(healthStatus, targetVersion)
+			observation.InGoalState = observation.AchievedVersion == targetVersion
+		}
+	} else {
+		observation.InGoalState = performCheckOMMode(healthStatus)
+		for _, v := range healthStatus.ProcessPlans {
+			observation.AchievedVersion = v.LastGoalStateClusterConfigVersion
+		}
+	}
+
+	observation.DeadlockEscaped = !observation.InGoalState && hasDeadlockedSteps(healthStatus)
+	observation.Ready = (observation.InGoalState && isInReadyState(healthStatus)) || observation.DeadlockEscaped
+	return observation
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/phamidko/opencga-operator/cmd/readiness/testdata"
 	"github.com/phamidko/opencga-operator/pkg/readiness/config"
 	"github.com/phamidko/opencga-operator/pkg/readiness/health"
+	"github.com/phamidko/opencga-operator/pkg/readiness/metrics"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
@@ -225,3 +226,70 @@ func NewTestHealthStatusReader(status health.Status) io.Reader {
 	}
 	return bytes.NewReader(data)
 }
+
+// TestObserveDeadlockedAgent verifies that the observation used for the metrics reports the step the agent is stuck on
+// and that the pod is only ready because of the deadlock detection
+func TestObserveDeadlockedAgent(t *testing.T) {
+	c := testConfig("testdata/health-status-deadlocked.json")
+	status, err := parseHealthStatus(c.HealthStatusReader)
+	assert.NoError(t, err)
+
+	observation := observe(status, c)
+
+	assert.True(t, observation.Ready)
+	assert.False(t, observation.InGoalState)
+	assert.True(t, observation.DeadlockEscaped)
+	assert.Equal(t, "WaitAllRsMembersUp", observation.CurrentStep)
+	assert.Equal(t, metrics.UnknownVersion, observation.TargetVersion)
+}
+
+// TestObserveHeadlessAgent verifies that the observation contains both the target and the achieved version and that
+// the pod annotation is not changed
+func TestObserveHeadlessAgent(t *testing.T) {
+	_ = os.Setenv(headlessAgent, "true")
+	defer os.Unsetenv(headlessAgent)
+
+	c := testConfig("testdata/health-status-ok.json")
+	c.ClientSet = fake.NewSimpleClientset(testdata.TestPod(c.Namespace, c.Hostname), testdata.TestSecret(c.Namespace, c.AutomationConfigSecretName, 6))
+	status, err := parseHealthStatus(c.HealthStatusReader)
+	assert.NoError(t, err)
+
+	observation := observe(status, c)
+
+	assert.False(t, observation.Ready)
+	assert.False(t, observation.InGoalState)
+	assert.Equal(t, int64(5), observation.AchievedVersion)
+	assert.Equal(t, int64(6), observation.TargetVersion)
+	assert.False(t, observation.DeadlockEscaped)
+
+	thePod, _ := c.ClientSet.CoreV1().Pods(c.Namespace).Get(context.TODO(), c.Hostname, metav1.GetOptions{})
+	assert.Equal(t, map[string]string{"agent.mongodb.com/version": ""}, thePod.Annotations)
+}
+
+// TestObserveMatchesPodReadiness verifies that the observation used for the metrics reports the same decision as the
+// readiness probe
+func TestObserveMatchesPodReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() config.Config
+	}{
+		{name: "Goal state reached", config: func() config.Config { return testConfig("testdata/health-status-ok.json") }},
+		{name: "No processes", config: func() config.Config { return testConfig("testdata/health-status-no-processes.json") }},
+		{name: "Deadlocked agent", config: func() config.Config { return testConfig("testdata/health-status-deadlocked.json") }},
+		{name: "Replication state not readable", config: func() config.Config { return testConfig("testdata/health-status-not-readable-state.json") }},
+		{name: "Mongod is down", config: func() config.Config {
+			return testConfigWithMongoUp("testdata/health-status-ok.json", time.Hour)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, err := isPodReady(tt.config())
+			assert.NoError(t, err)
+
+			c := tt.config()
+			status, err := parseHealthStatus(c.HealthStatusReader)
+			assert.NoError(t, err)
+			assert.Equal(t, ready, observe(status, c).Ready)
+		})
+	}
+}
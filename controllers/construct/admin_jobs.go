@@ -0,0 +1,97 @@
+package construct
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/phamidko/opencga-operator/pkg/kube/container"
+	"github.com/phamidko/opencga-operator/pkg/kube/podtemplatespec"
+	"github.com/phamidko/opencga-operator/pkg/kube/resourcerequirements"
+)
+
+const (
+	// CatalogInstallContainerName is the name of the container installing the catalog.
+	CatalogInstallContainerName = "catalog-install"
+	// MigrationContainerName is the name of the container migrating the catalog.
+	MigrationContainerName = "migration"
+
+	adminPasswordEnvName = "OPENCGA_ADMIN_PASSWORD"
+
+	// adminJobBackoffLimit is the number of times a Job is retried before it is marked as failed.
+	adminJobBackoffLimit = 3
+)
+
+// AdminJobOwner is implemented by the resources whose catalog is installed and migrated by Jobs
+// running opencga-admin.sh.
+type AdminJobOwner interface {
+	OpenCGADeploymentOwner
+	// CatalogInstallJobName returns the name of the Job installing the catalog.
+	CatalogInstallJobName() string
+	// MigrationJobName returns the name of the Job migrating the catalog to the version of the resource.
+	MigrationJobName() string
+	// GetAdminPasswordSecretKeyRef returns the Secret key holding the password of the admin user.
+	GetAdminPasswordSecretKeyRef() corev1.SecretKeySelector
+}
+
+// BuildCatalogInstallJob returns the Job running "opencga-admin.sh catalog install", which creates the catalog
+// databases and the admin user.
+func BuildCatalogInstallJob(ocb AdminJobOwner) batchv1.Job {
+	return buildAdminJob(ocb, ocb.CatalogInstallJobName(), CatalogInstallContainerName, "catalog install")
+}
+
+// BuildMigrationJob returns the Job running "opencga-admin.sh migration run" with the image of the version of
+// the resource, which applies the catalog migrations that version requires.
+func BuildMigrationJob(ocb AdminJobOwner) batchv1.Job {
+	return buildAdminJob(ocb, ocb.MigrationJobName(), MigrationContainerName, "migration run")
+}
+
+// buildAdminJob returns a Job running opencga-admin.sh with the given arguments as the admin user. The admin
+// password is read from its Secret and passed on the standard input.
+func buildAdminJob(ocb AdminJobOwner, name, containerName, args string) batchv1.Job {
+	labels := map[string]string{
+		"app": name,
+	}
+	passwordRef := ocb.GetAdminPasswordSecretKeyRef()
+
+	modifications := []podtemplatespec.Modification{
+		podtemplatespec.WithPodLabels(labels),
+		podtemplatespec.WithServiceAccount(opencgaDatabaseServiceAccountName),
+		podtemplatespec.WithRestartPolicy(corev1.RestartPolicyNever),
+		podtemplatespec.WithContainer(containerName, container.Apply(
+			container.WithName(containerName),
+			container.WithImage(getOpenCGAImage(ocb)),
+			container.WithCommand(opencgaAdminCommand(ocb, args+` --password <<< "${`+adminPasswordEnvName+`}"`)),
+			container.WithResourceRequirements(resourcerequirements.Defaults()),
+			container.WithEnvs(append(opencgaEnvs(ocb), corev1.EnvVar{
+				Name:      adminPasswordEnvName,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &passwordRef},
+			})...),
+			container.WithSecurityContext(container.DefaultSecurityContext()),
+		)),
+	}
+	for _, volumeMountData := range opencgaVolumes(ocb) {
+		modifications = append(modifications,
+			podtemplatespec.WithVolume(volumeMountData.Volume),
+			podtemplatespec.WithVolumeMounts(containerName, corev1.VolumeMount{
+				Name:      volumeMountData.Name,
+				MountPath: volumeMountData.MountPath,
+				ReadOnly:  volumeMountData.ReadOnly,
+			}),
+		)
+	}
+
+	backoffLimit := int32(adminJobBackoffLimit)
+	return batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       ocb.GetNamespace(),
+			Labels:          labels,
+			OwnerReferences: ocb.GetOwnerReferences(),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     podtemplatespec.New(modifications...),
+		},
+	}
+}
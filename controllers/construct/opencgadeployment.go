@@ -1,21 +1,26 @@
 package construct
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
-	// "github.com/phamidko/opencga-operator/pkg/automationconfig"
-	// "github.com/phamidko/opencga-operator/pkg/kube/container"
-	// "github.com/phamidko/opencga-operator/pkg/kube/persistentvolumeclaim"
-	// "github.com/phamidko/opencga-operator/pkg/kube/podtemplatespec"
-	// "github.com/phamidko/opencga-operator/pkg/kube/probes"
-	// "github.com/phamidko/opencga-operator/pkg/kube/resourcerequirements"
-	// "github.com/phamidko/opencga-operator/pkg/kube/statefulset"
-	// "github.com/phamidko/opencga-operator/pkg/util/envvar"
-	// "github.com/phamidko/opencga-operator/pkg/util/scale"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	ocbv1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/kube/container"
+	"github.com/phamidko/opencga-operator/pkg/kube/podtemplatespec"
+	"github.com/phamidko/opencga-operator/pkg/kube/probes"
+	"github.com/phamidko/opencga-operator/pkg/kube/resourcerequirements"
 	"github.com/phamidko/opencga-operator/pkg/kube/statefulset"
+	"github.com/phamidko/opencga-operator/pkg/opencgaconfig"
+	"github.com/phamidko/opencga-operator/pkg/util/envvar"
 	"github.com/phamidko/opencga-operator/pkg/util/scale"
 )
 
@@ -33,6 +38,48 @@ const (
 
 	opencgaRepoUrl = "opencga_REPO_URL"
 
+	// MasterContainerName is the name of the container running the OpenCGA master.
+	MasterContainerName = "opencga-master"
+	// RestPortName is the name of the container port the REST API is served on.
+	RestPortName        = "rest"
+	restPingPath        = "/opencga/webservices/rest/v2/meta/ping"
+	opencgaBinPath      = "/opt/opencga/bin"
+	opencgaLogsPath     = "/opt/opencga/logs"
+	defaultOpenCGAImage = "opencb/opencga-base"
+	javaOptsEnvName     = "JAVA_OPTS"
+
+	catalogCAVolumeName  = "catalog-ca"
+	catalogCAMountPath   = "/opt/opencga/catalog-ca"
+	ldapCAVolumeName     = "ldap-ca"
+	ldapCAMountPath      = "/opt/opencga/ldap-ca"
+	trustStoreVolumeName = "truststore"
+	trustStoreMountPath  = "/opt/opencga/truststore"
+
+	catalogClientCertificateVolumeName = "catalog-client-certificate"
+	catalogClientCertificateMountPath  = "/opt/opencga/catalog-client-certificate"
+	catalogKeyStorePath                = trustStoreMountPath + "/catalog-client.p12"
+
+	hadoopConfVolumeName   = "hadoop-conf"
+	hadoopConfMountPath    = "/opt/opencga/hadoop/conf"
+	hbaseConfVolumeName    = "hbase-conf"
+	hbaseConfMountPath     = "/opt/opencga/hbase/conf"
+	hadoopConfDirEnvName   = "HADOOP_CONF_DIR"
+	hbaseConfDirEnvName    = "HBASE_CONF_DIR"
+	classpathPrefixEnvName = "CLASSPATH_PREFIX"
+
+	// AgentVersionContainerName is the name of the sidecar serving the automation config version reached by the
+	// agent when the readiness probe runs without access to the Kubernetes API.
+	AgentVersionContainerName = "opencga-agent-version"
+	// AgentVersionPort is the port the sidecar serves the version reached by the agent on.
+	AgentVersionPort = 8081
+
+	agentVersionPortName        = "agent-version"
+	healthStatusMountPath       = "/var/log/opencga-mms-automation/healthstatus"
+	agentVersionFilePathValue   = healthStatusMountPath + "/agent-version"
+	readinessProbeLocalModeEnv  = "READINESS_PROBE_LOCAL_MODE"
+	agentVersionFilePathEnv     = "AGENT_VERSION_FILEPATH"
+	automationConfigFilePathEnv = "AUTOMATION_CONFIG_FILEPATH"
+
 	headlessAgentEnv           = "HEADLESS_AGENT"
 	podNamespaceEnv            = "POD_NAMESPACE"
 	automationConfigEnv        = "AUTOMATION_CONFIG_MAP"
@@ -43,12 +90,38 @@ const (
 	ManagedSecurityContextEnv  = "MANAGED_SECURITY_CONTEXT"
 
 	automationMongodConfFileName = "automation-opencga.conf"
-	keyfileFilePath              = "/var/lib/opencga-mms-automation/authentication/keyfile"
 
 	automationAgentOptions = " -skipMongoStart -noDaemonize -useLocalOpencgaTools"
 
+	// AgentAPIKeySecretKey is the key of the agent API key secret holding the key.
+	AgentAPIKeySecretKey = "agentApiKey"
+	// AgentAPIKeyHashAnnotationKey is set on the pod template so that the REST Pods are rolled whenever
+	// the agent API key is rotated.
+	AgentAPIKeyHashAnnotationKey = "opencga.zetta.com/agentApiKeyHash"
+	agentAPIKeyVolumeName        = "agent-api-key"
+	agentAPIKeyMountPath         = "/opencga-automation/agent-api-key"
+
+	// TLSOperatorSecretMountPath is where the Secret with the certificates of the members and of the CA is
+	// mounted. The Secret is not mounted with a subPath, so that the kubelet updates the files when the
+	// certificates are renewed.
+	TLSOperatorSecretMountPath = "/opencga-automation/tls"
+	tlsOperatorSecretVolume    = "tls"
+
+	// copyTrustStoreCommand copies the default trust store of the JVM, CA certificates are imported into the copy.
+	copyTrustStoreCommand = `JAVA_HOME="${JAVA_HOME:-$(dirname "$(dirname "$(readlink -f "$(command -v java)")")")}"
+cp "${JAVA_HOME}/lib/security/cacerts" ` + trustStoreMountPath + `/cacerts
+`
+	// useTrustStoreCommand makes the JVM use the copied trust store.
+	useTrustStoreCommand = `export ` + javaOptsEnvName + `="${` + javaOptsEnvName + `} -Djavax.net.ssl.trustStore=` + trustStoreMountPath + `/cacerts -Djavax.net.ssl.trustStorePassword=changeit"
+`
+	// catalogKeyStoreCommand bundles the client certificate of the catalog and its key into a PKCS12 key store and makes
+	// the JVM use it.
+	catalogKeyStoreCommand = `openssl pkcs12 -export -in ` + catalogClientCertificateMountPath + `/tls.crt -inkey ` + catalogClientCertificateMountPath + `/tls.key -out ` + catalogKeyStorePath + ` -passout pass:changeit
+export ` + javaOptsEnvName + `="${` + javaOptsEnvName + `} -Djavax.net.ssl.keyStore=` + catalogKeyStorePath + ` -Djavax.net.ssl.keyStorePassword=changeit -Djavax.net.ssl.keyStoreType=PKCS12"
+`
+
 	OpencgaUserCommand = `current_uid=$(id -u)
-AGENT_API_KEY="$(cat /opencga-automation/agent-api-key/agentApiKey)"
+AGENT_API_KEY="$(cat ` + agentAPIKeyMountPath + `/` + AgentAPIKeySecretKey + `)"
 declare -r current_uid
 if ! grep -q "${current_uid}" /etc/passwd ; then
 sed -e "s/^opencga:/builder:/" /etc/passwd > /tmp/passwd
@@ -64,6 +137,8 @@ fi
 type OpenCGADeploymentOwner interface {
 	// ServiceName returns the name of the K8S service the operator will create.
 	ServiceName() string
+	// MasterName returns the name of the StatefulSet running the OpenCGA master.
+	MasterName() string
 	// GetName returns the name of the resource.
 	GetName() string
 	// GetNamespace returns the namespace the resource is defined in.
@@ -72,32 +147,420 @@ type OpenCGADeploymentOwner interface {
 	GetOpenCGAVersion() string
 	// AutomationConfigSecretName returns the name of the secret which will contain the automation config.
 	AutomationConfigSecretName() string
-	// GetUpdateStrategyType returns the UpdateStrategyType of the statefulset.
+	// OpenCGAConfigSecretName returns the name of the secret which contains the rendered OpenCGA configuration files.
+	OpenCGAConfigSecretName() string
+	// GetUpdateStrategyType returns the UpdateStrategyType of the statefulsets.
 	GetUpdateStrategyType() appsv1.StatefulSetUpdateStrategyType
 	// HasSeparateDataAndLogsVolumes returns whether or not the volumes for data and logs would need to be different.
 	HasSeparateDataAndLogsVolumes() bool
 	// GetAgentScramKeyfileSecretNamespacedName returns the NamespacedName of the secret which stores the keyfile for the agent.
 	GetAgentKeyfileSecretNamespacedName() types.NamespacedName
+	// GetAgentAPIKeySecretNamespacedName returns the NamespacedName of the secret which stores the API key of the agents.
+	GetAgentAPIKeySecretNamespacedName() types.NamespacedName
 	// DataVolumeName returns the name that the data volume should have
 	DataVolumeName() string
 	// LogsVolumeName returns the name that the data volume should have
 	LogsVolumeName() string
+	// GetOwnerReferences returns the OwnerReferences pointing to the resource.
+	GetOwnerReferences() []metav1.OwnerReference
 
 	// GetOpenCGAConfiguration returns the OpenCGA configuration for each member.
 	GetOpenCGAConfiguration() ocbv1.OpenCGAConfiguration
+	// GetWorkspace returns the directory OpenCGA stores session and job files in.
+	GetWorkspace() string
+	// GetRestPort returns the port the REST API listens on.
+	GetRestPort() int
+	// GetJVMOptions returns the options the OpenCGA JVM is started with.
+	GetJVMOptions() automationconfig.JVMOptions
+	// GetCatalogCASecretKeyRef returns the Secret key holding the CA certificate of the catalog, if any.
+	GetCatalogCASecretKeyRef() *corev1.SecretKeySelector
+	// GetCatalogClientCertificateSecretName returns the name of the Secret holding the client certificate of the
+	// catalog, if OpenCGA authenticates with one.
+	GetCatalogClientCertificateSecretName() string
+	// GetLDAPCASecretKeyRef returns the Secret key holding the CA certificate of the LDAP servers, if any.
+	GetLDAPCASecretKeyRef() *corev1.SecretKeySelector
+	// GetHadoopVariantStorage returns the settings of the Hadoop variant storage engine, or nil if it is not used.
+	GetHadoopVariantStorage() *ocbv1.HadoopVariantStorageSpec
+
+	// IsTLSEnabled returns true if the REST API is served over TLS.
+	IsTLSEnabled() bool
+	// TLSOperatorSecretName returns the name of the Secret with the certificates of the members and of the CA.
+	TLSOperatorSecretName() string
 
 	// NeedsAutomationConfigVolume returns whether the statefuslet needs to have a volume for the automationconfig.
 	NeedsAutomationConfigVolume() bool
+	// IsReadinessProbeLocalMode returns true if the readiness probe of the agents runs without access to the Kubernetes API.
+	IsReadinessProbeLocalMode() bool
+	// GetReadinessMetricsInterval returns the interval the agent version sidecar evaluates the readiness of the
+	// agent with, or 0 if the readiness metrics are not served.
+	GetReadinessMetricsInterval() time.Duration
 }
 
-// BuildMongoDBReplicaSetStatefulSetModificationFunction builds the parts of the replica set that are common between every resource that implements
-// MongoDBStatefulSetOwner.
-// It doesn't configure TLS or additional containers/env vars that the statefulset might need.
-func BuildOpenCGABReplicaSetDeploymentModificationFunction(ocb OpenCGADeploymentOwner, scaler scale.ReplicaSetScaler) statefulset.Modification {
+// BuildOpenCGAReplicaSetStatefulSet returns a Builder for the StatefulSet running the OpenCGA REST members.
+// configHash and agentAPIKeyHash are the hashes of the rendered OpenCGA configuration and of the agent API key,
+// the Pods are rolled whenever either changes.
+// Callers can add further volumes to the returned Builder before building the StatefulSet.
+func BuildOpenCGAReplicaSetStatefulSet(ocb OpenCGADeploymentOwner, scaler scale.ReplicaSetScaler, configHash, agentAPIKeyHash string) *statefulset.Builder {
 	labels := map[string]string{
 		"app": ocb.ServiceName(),
 	}
-	// healthStatusVolume :=
 
-	return labels
+	scriptsVolume := statefulset.CreateVolumeFromEmptyDir("agent-scripts")
+	healthStatusVolume := statefulset.CreateVolumeFromEmptyDir("healthstatus")
+	keyFileVolume := statefulset.CreateVolumeFromEmptyDir("opencga-keyfile")
+	agentAPIKeyVolume := statefulset.CreateVolumeFromSecret(agentAPIKeyVolumeName, ocb.GetAgentAPIKeySecretNamespacedName().Name)
+
+	builder := statefulset.NewBuilder().
+		SetName(ocb.GetName()).
+		SetNamespace(ocb.GetNamespace()).
+		SetServiceName(ocb.ServiceName()).
+		SetLabels(labels).
+		SetMatchLabels(labels).
+		SetOwnerReference(ocb.GetOwnerReferences()).
+		SetReplicas(scale.ReplicasThisReconciliation(scaler)).
+		SetUpdateStrategy(ocb.GetUpdateStrategyType()).
+		SetPodTemplateSpec(podtemplatespec.New(
+			podtemplatespec.WithPodLabels(labels),
+			podtemplatespec.WithAnnotations(map[string]string{
+				opencgaconfig.HashAnnotationKey: configHash,
+				AgentAPIKeyHashAnnotationKey:    agentAPIKeyHash,
+			}),
+			podtemplatespec.WithServiceAccount(opencgaDatabaseServiceAccountName),
+			serviceAccountToken(ocb),
+			podtemplatespec.WithTerminationGracePeriodSeconds(30),
+			podtemplatespec.WithInitContainer(ReadinessProbeContainerName, readinessProbeInit(scriptsVolume.Name)),
+			podtemplatespec.WithContainer(AgentName, agentContainer(ocb)),
+			podtemplatespec.WithContainer(opencgaName, restContainer(ocb)),
+			agentVersionSidecar(ocb),
+		)).
+		AddVolumeAndMount(statefulset.VolumeMountData{Name: scriptsVolume.Name, MountPath: "/opt/scripts", Volume: scriptsVolume}, AgentName).
+		AddVolumeAndMount(statefulset.VolumeMountData{Name: healthStatusVolume.Name, MountPath: healthStatusMountPath, Volume: healthStatusVolume}, AgentName).
+		AddVolumeAndMount(statefulset.VolumeMountData{Name: keyFileVolume.Name, MountPath: "/var/lib/opencga-mms-automation/authentication", Volume: keyFileVolume}, AgentName, opencgaName).
+		AddVolumeAndMount(statefulset.VolumeMountData{Name: agentAPIKeyVolume.Name, MountPath: agentAPIKeyMountPath, Volume: agentAPIKeyVolume, ReadOnly: true}, AgentName).
+		AddVolumeClaimTemplates(persistentVolumeClaims(ocb)).
+		AddVolumeMount(AgentName, statefulset.CreateVolumeMount(ocb.DataVolumeName(), ocb.GetWorkspace())).
+		AddVolumeMount(opencgaName, statefulset.CreateVolumeMount(ocb.DataVolumeName(), ocb.GetWorkspace())).
+		AddVolumeMount(AgentName, statefulset.CreateVolumeMount(ocb.LogsVolumeName(), automationconfig.DefaultAgentLogPath)).
+		AddVolumeMount(opencgaName, statefulset.CreateVolumeMount(ocb.LogsVolumeName(), opencgaLogsPath))
+
+	addOpenCGAVolumes(builder, ocb, opencgaName)
+
+	if ocb.IsReadinessProbeLocalMode() {
+		builder.AddVolumeMount(AgentVersionContainerName, statefulset.CreateVolumeMount(healthStatusVolume.Name, healthStatusMountPath, statefulset.WithReadOnly(true)))
+	}
+
+	if ocb.IsTLSEnabled() {
+		tlsVolume := statefulset.CreateVolumeFromSecret(tlsOperatorSecretVolume, ocb.TLSOperatorSecretName())
+		builder.AddVolumeAndMount(statefulset.VolumeMountData{Name: tlsVolume.Name, MountPath: TLSOperatorSecretMountPath, Volume: tlsVolume, ReadOnly: true}, AgentName, opencgaName)
+	}
+
+	if ocb.NeedsAutomationConfigVolume() {
+		automationConfigVolume := statefulset.CreateVolumeFromSecret("automation-config", ocb.AutomationConfigSecretName())
+		containerNames := []string{AgentName}
+		if ocb.GetReadinessMetricsInterval() > 0 {
+			// the sidecar evaluates the readiness against the mounted automation config
+			containerNames = append(containerNames, AgentVersionContainerName)
+		}
+		builder.AddVolumeAndMount(statefulset.VolumeMountData{Name: automationConfigVolume.Name, MountPath: "/var/lib/automation/config", Volume: automationConfigVolume, ReadOnly: true}, containerNames...)
+	}
+	return builder
+}
+
+// BuildOpenCGAMasterStatefulSet returns a Builder for the single member StatefulSet running the OpenCGA master,
+// which schedules and monitors the jobs submitted through the REST API.
+func BuildOpenCGAMasterStatefulSet(ocb OpenCGADeploymentOwner, configHash string) *statefulset.Builder {
+	labels := map[string]string{
+		"app": ocb.MasterName(),
+	}
+
+	builder := statefulset.NewBuilder().
+		SetName(ocb.MasterName()).
+		SetNamespace(ocb.GetNamespace()).
+		SetServiceName(ocb.ServiceName()).
+		SetLabels(labels).
+		SetMatchLabels(labels).
+		SetOwnerReference(ocb.GetOwnerReferences()).
+		SetReplicas(1).
+		SetUpdateStrategy(ocb.GetUpdateStrategyType()).
+		SetPodTemplateSpec(podtemplatespec.New(
+			podtemplatespec.WithPodLabels(labels),
+			podtemplatespec.WithAnnotations(map[string]string{opencgaconfig.HashAnnotationKey: configHash}),
+			podtemplatespec.WithServiceAccount(opencgaDatabaseServiceAccountName),
+			podtemplatespec.WithContainer(MasterContainerName, masterContainer(ocb)),
+		)).
+		AddVolumeClaimTemplates([]corev1.PersistentVolumeClaim{persistentVolumeClaim(ocb.DataVolumeName(), "10G")}).
+		AddVolumeMount(MasterContainerName, statefulset.CreateVolumeMount(ocb.DataVolumeName(), ocb.GetWorkspace()))
+
+	addOpenCGAVolumes(builder, ocb, MasterContainerName)
+	return builder
+}
+
+// addOpenCGAVolumes adds the volumes returned by opencgaVolumes to the given containers.
+func addOpenCGAVolumes(builder *statefulset.Builder, ocb OpenCGADeploymentOwner, containerNames ...string) {
+	for _, volumeMountData := range opencgaVolumes(ocb) {
+		builder.AddVolumeAndMount(volumeMountData, containerNames...)
+	}
+}
+
+// trustedCA is a CA certificate, mounted from a Secret key, which is imported into the trust store of the JVM.
+type trustedCA struct {
+	volumeName string
+	mountPath  string
+	ref        *corev1.SecretKeySelector
+}
+
+// trustedCAs returns the CA certificates of the catalog and of the LDAP servers, if configured.
+func trustedCAs(ocb OpenCGADeploymentOwner) []trustedCA {
+	var cas []trustedCA
+	if ref := ocb.GetCatalogCASecretKeyRef(); ref != nil {
+		cas = append(cas, trustedCA{volumeName: catalogCAVolumeName, mountPath: catalogCAMountPath, ref: ref})
+	}
+	if ref := ocb.GetLDAPCASecretKeyRef(); ref != nil {
+		cas = append(cas, trustedCA{volumeName: ldapCAVolumeName, mountPath: ldapCAMountPath, ref: ref})
+	}
+	return cas
+}
+
+// opencgaVolumes returns the volumes every container running OpenCGA needs: the configuration files and, if
+// configured, the CA certificates of the catalog and of the LDAP servers and the client certificate of the catalog
+// with an empty directory for the trust and key stores they are imported into, and the Hadoop and HBase client
+// configuration.
+func opencgaVolumes(ocb OpenCGADeploymentOwner) []statefulset.VolumeMountData {
+	configVolume := statefulset.CreateVolumeFromSecret("opencga-config", ocb.OpenCGAConfigSecretName())
+	volumes := []statefulset.VolumeMountData{
+		{Name: configVolume.Name, MountPath: opencgaconfig.MountPath, Volume: configVolume, ReadOnly: true},
+	}
+
+	cas := trustedCAs(ocb)
+	for _, ca := range cas {
+		ref := ca.ref
+		caVolume := statefulset.CreateVolumeFromSecret(ca.volumeName, ref.Name, func(v *corev1.Volume) {
+			v.Secret.Items = []corev1.KeyToPath{{Key: ref.Key, Path: "ca.crt"}}
+		})
+		volumes = append(volumes, statefulset.VolumeMountData{Name: caVolume.Name, MountPath: ca.mountPath, Volume: caVolume, ReadOnly: true})
+	}
+	clientCertificateSecretName := ocb.GetCatalogClientCertificateSecretName()
+	if clientCertificateSecretName != "" {
+		clientCertificateVolume := statefulset.CreateVolumeFromSecret(catalogClientCertificateVolumeName, clientCertificateSecretName, func(v *corev1.Volume) {
+			v.Secret.Items = []corev1.KeyToPath{{Key: corev1.TLSCertKey, Path: corev1.TLSCertKey}, {Key: corev1.TLSPrivateKeyKey, Path: corev1.TLSPrivateKeyKey}}
+		})
+		volumes = append(volumes, statefulset.VolumeMountData{Name: clientCertificateVolume.Name, MountPath: catalogClientCertificateMountPath, Volume: clientCertificateVolume, ReadOnly: true})
+	}
+	if len(cas) > 0 || clientCertificateSecretName != "" {
+		trustStoreVolume := statefulset.CreateVolumeFromEmptyDir(trustStoreVolumeName)
+		volumes = append(volumes, statefulset.VolumeMountData{Name: trustStoreVolume.Name, MountPath: trustStoreMountPath, Volume: trustStoreVolume})
+	}
+
+	if hadoop := ocb.GetHadoopVariantStorage(); hadoop != nil {
+		hadoopConfVolume := statefulset.CreateVolumeFromConfigMap(hadoopConfVolumeName, hadoop.HadoopConfigMapRef.Name)
+		hbaseConfVolume := statefulset.CreateVolumeFromConfigMap(hbaseConfVolumeName, hadoop.HBaseConfigMapRef.Name)
+		volumes = append(volumes,
+			statefulset.VolumeMountData{Name: hadoopConfVolume.Name, MountPath: hadoopConfMountPath, Volume: hadoopConfVolume, ReadOnly: true},
+			statefulset.VolumeMountData{Name: hbaseConfVolume.Name, MountPath: hbaseConfMountPath, Volume: hbaseConfVolume, ReadOnly: true},
+		)
+	}
+	return volumes
+}
+
+func persistentVolumeClaims(ocb OpenCGADeploymentOwner) []corev1.PersistentVolumeClaim {
+	if !ocb.HasSeparateDataAndLogsVolumes() {
+		return []corev1.PersistentVolumeClaim{persistentVolumeClaim(ocb.DataVolumeName(), "10G")}
+	}
+	return []corev1.PersistentVolumeClaim{
+		persistentVolumeClaim(ocb.DataVolumeName(), "10G"),
+		persistentVolumeClaim(ocb.LogsVolumeName(), "2G"),
+	}
+}
+
+func persistentVolumeClaim(name, storage string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: resourcerequirements.BuildStorageRequirements(storage),
+			},
+		},
+	}
+}
+
+// readinessProbeInit copies the readiness probe binary into the scripts volume shared with the agent container.
+func readinessProbeInit(scriptsVolumeName string) container.Modification {
+	return container.Apply(
+		container.WithName(ReadinessProbeContainerName),
+		container.WithImage(os.Getenv(ReadinessProbeImageEnv)),
+		container.WithCommand([]string{"cp", "/probes/readinessprobe", readinessProbePath}),
+		container.WithVolumeMounts([]corev1.VolumeMount{statefulset.CreateVolumeMount(scriptsVolumeName, "/opt/scripts")}),
+		container.WithSecurityContext(container.DefaultSecurityContext()),
+	)
+}
+
+func agentContainer(ocb OpenCGADeploymentOwner) container.Modification {
+	agentCommand := strings.Join([]string{
+		"agent/opencga-agent",
+		"-cluster=" + clusterFilePath,
+		"-healthCheckFilePath=" + agentHealthStatusFilePathValue,
+		"-serveStatusPort=5000",
+	}, " ") + automationAgentOptions
+
+	return container.Apply(
+		container.WithName(AgentName),
+		container.WithImage(os.Getenv(AgentImageEnv)),
+		container.WithImagePullPolicy(corev1.PullAlways),
+		container.WithReadinessProbe(probes.Apply(
+			probes.WithExecCommand([]string{readinessProbePath}),
+			probes.WithFailureThreshold(40),
+			probes.WithInitialDelaySeconds(5),
+		)),
+		container.WithResourceRequirements(resourcerequirements.Defaults()),
+		container.WithCommand([]string{"/bin/bash", "-c", OpencgaUserCommand + agentCommand}),
+		container.WithEnvs(
+			corev1.EnvVar{Name: headlessAgentEnv, Value: "true"},
+			corev1.EnvVar{Name: podNamespaceEnv, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.namespace"}}},
+			corev1.EnvVar{Name: automationConfigEnv, Value: ocb.AutomationConfigSecretName()},
+			corev1.EnvVar{Name: agentHealthStatusFilePathEnv, Value: agentHealthStatusFilePathValue},
+		),
+		readinessProbeLocalModeEnvs(ocb),
+		container.WithSecurityContext(container.DefaultSecurityContext()),
+	)
+}
+
+// readinessProbeLocalModeEnvs makes the readiness probe read the target version from the mounted automation config
+// and write the version reached by the agent to a file served by the agent version sidecar, if it runs without
+// access to the Kubernetes API.
+func readinessProbeLocalModeEnvs(ocb OpenCGADeploymentOwner) container.Modification {
+	if !ocb.IsReadinessProbeLocalMode() {
+		return container.NOOP()
+	}
+	return container.WithEnvs(
+		corev1.EnvVar{Name: readinessProbeLocalModeEnv, Value: "true"},
+		corev1.EnvVar{Name: automationConfigFilePathEnv, Value: clusterFilePath},
+		corev1.EnvVar{Name: agentVersionFilePathEnv, Value: agentVersionFilePathValue},
+	)
+}
+
+// serviceAccountToken keeps the token of the ServiceAccount out of the members when the readiness probe runs
+// without access to the Kubernetes API, no other container of the members talks to it.
+func serviceAccountToken(ocb OpenCGADeploymentOwner) podtemplatespec.Modification {
+	if !ocb.IsReadinessProbeLocalMode() {
+		return podtemplatespec.NOOP()
+	}
+	return podtemplatespec.WithAutomountServiceAccountToken(false)
+}
+
+// agentVersionSidecar adds the container serving the automation config version reached by the agent, which the
+// operator scrapes when the readiness probe runs without access to the Kubernetes API. The sidecar also serves
+// the readiness metrics of the agent if they are enabled on the resource.
+func agentVersionSidecar(ocb OpenCGADeploymentOwner) podtemplatespec.Modification {
+	if !ocb.IsReadinessProbeLocalMode() {
+		return podtemplatespec.NOOP()
+	}
+	command := []string{"/probes/readinessprobe", fmt.Sprintf("-listen-address=:%d", AgentVersionPort)}
+	envs := []corev1.EnvVar{
+		{Name: readinessProbeLocalModeEnv, Value: "true"},
+		{Name: agentVersionFilePathEnv, Value: agentVersionFilePathValue},
+	}
+	if interval := ocb.GetReadinessMetricsInterval(); interval > 0 {
+		command = append(command, "-metrics-interval="+interval.String())
+		envs = append(envs,
+			corev1.EnvVar{Name: headlessAgentEnv, Value: "true"},
+			corev1.EnvVar{Name: automationConfigFilePathEnv, Value: clusterFilePath},
+			corev1.EnvVar{Name: agentHealthStatusFilePathEnv, Value: agentHealthStatusFilePathValue},
+		)
+	}
+	return podtemplatespec.WithContainer(AgentVersionContainerName, container.Apply(
+		container.WithName(AgentVersionContainerName),
+		container.WithImage(os.Getenv(ReadinessProbeImageEnv)),
+		container.WithCommand(command),
+		container.WithPorts([]corev1.ContainerPort{{Name: agentVersionPortName, ContainerPort: AgentVersionPort}}),
+		container.WithEnvs(envs...),
+		container.WithSecurityContext(container.DefaultSecurityContext()),
+	))
+}
+
+func restContainer(ocb OpenCGADeploymentOwner) container.Modification {
+	// the kubelet doesn't verify the certificate, HTTPS probes work in every TLS mode but disabled
+	scheme := corev1.URISchemeHTTP
+	if ocb.IsTLSEnabled() {
+		scheme = corev1.URISchemeHTTPS
+	}
+	return container.Apply(
+		container.WithName(opencgaName),
+		container.WithImage(getOpenCGAImage(ocb)),
+		container.WithCommand(opencgaAdminCommand(ocb, "server rest --start")),
+		container.WithPorts([]corev1.ContainerPort{{Name: RestPortName, ContainerPort: int32(ocb.GetRestPort())}}),
+		container.WithReadinessProbe(probes.Apply(
+			probes.WithHandler(corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: restPingPath, Port: intstr.FromString(RestPortName), Scheme: scheme}}),
+			probes.WithInitialDelaySeconds(10),
+			probes.WithPeriodSeconds(10),
+		)),
+		container.WithResourceRequirements(resourcerequirements.Defaults()),
+		container.WithEnvs(opencgaEnvs(ocb)...),
+		container.WithSecurityContext(container.DefaultSecurityContext()),
+	)
+}
+
+func masterContainer(ocb OpenCGADeploymentOwner) container.Modification {
+	return container.Apply(
+		container.WithName(MasterContainerName),
+		container.WithImage(getOpenCGAImage(ocb)),
+		container.WithCommand(opencgaAdminCommand(ocb, "catalog daemon --start")),
+		container.WithResourceRequirements(resourcerequirements.Defaults()),
+		container.WithEnvs(opencgaEnvs(ocb)...),
+		container.WithSecurityContext(container.DefaultSecurityContext()),
+	)
+}
+
+// opencgaAdminCommand returns the command running opencga-admin.sh with the given arguments. If the catalog or the
+// LDAP servers use a custom CA, it is added to the trust store of the JVM first, and the client certificate of the
+// catalog is added to its key store.
+func opencgaAdminCommand(ocb OpenCGADeploymentOwner, args string) []string {
+	command := opencgaBinPath + "/opencga-admin.sh " + args
+	if ocb.GetCatalogClientCertificateSecretName() != "" {
+		command = catalogKeyStoreCommand + command
+	}
+	if cas := trustedCAs(ocb); len(cas) > 0 {
+		command = trustStoreCommand(cas) + command
+	}
+	return []string{"/bin/bash", "-c", command}
+}
+
+// trustStoreCommand returns the commands adding the given CA certificates to a copy of the default trust store of
+// the JVM and making the JVM use it.
+func trustStoreCommand(cas []trustedCA) string {
+	command := copyTrustStoreCommand
+	for _, ca := range cas {
+		command += "keytool -importcert -noprompt -alias opencga-" + ca.volumeName + " -file " + ca.mountPath + "/ca.crt -keystore " + trustStoreMountPath + "/cacerts -storepass changeit\n"
+	}
+	return command + useTrustStoreCommand
+}
+
+// opencgaEnvs returns the environment variables of the containers running OpenCGA. The Hadoop and HBase client
+// configuration is added to the classpath if variants are stored in HBase.
+func opencgaEnvs(ocb OpenCGADeploymentOwner) []corev1.EnvVar {
+	envs := []corev1.EnvVar{javaOptsEnv(ocb.GetJVMOptions())}
+	if ocb.GetHadoopVariantStorage() != nil {
+		envs = append(envs,
+			corev1.EnvVar{Name: hadoopConfDirEnvName, Value: hadoopConfMountPath},
+			corev1.EnvVar{Name: hbaseConfDirEnvName, Value: hbaseConfMountPath},
+			corev1.EnvVar{Name: classpathPrefixEnvName, Value: hadoopConfMountPath + ":" + hbaseConfMountPath},
+		)
+	}
+	return envs
+}
+
+// javaOptsEnv returns the environment variable the OpenCGA scripts pass to the JVM.
+func javaOptsEnv(jvm automationconfig.JVMOptions) corev1.EnvVar {
+	opts := []string{fmt.Sprintf("-Xms%s", jvm.InitialHeap), fmt.Sprintf("-Xmx%s", jvm.MaxHeap)}
+	return corev1.EnvVar{Name: javaOptsEnvName, Value: strings.Join(append(opts, jvm.ExtraOptions...), " ")}
+}
+
+// getOpenCGAImage returns the OpenCGA image of the version of the resource. Images for the Hadoop engine are
+// tagged with the Hadoop distribution they are built for, e.g. opencb/opencga-base:2.2.0-hdp3.1.
+func getOpenCGAImage(ocb OpenCGADeploymentOwner) string {
+	tag := ocb.GetOpenCGAVersion()
+	if hadoop := ocb.GetHadoopVariantStorage(); hadoop != nil && hadoop.Flavour != "" {
+		tag += "-" + hadoop.Flavour
+	}
+	return fmt.Sprintf("%s:%s", envvar.GetEnvOrDefault(OpencgaImageEnv, defaultOpenCGAImage), tag)
 }
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"github.com/phamidko/opencga-operator/pkg/util/result"
+	"go.uber.org/zap"
+
+	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/pkg/util/status"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// severity indicates the severity level
+// at which the message should be logged
+type severity string
+
+const (
+	Info  severity = "INFO"
+	Debug severity = "DEBUG"
+	Warn  severity = "WARN"
+	Error severity = "ERROR"
+	None  severity = "NONE"
+)
+
+// optionBuilder is in charge of constructing a slice of options that
+// will be applied on top of the OpenCGACommunity resource that has been provided
+type optionBuilder struct {
+	options []status.Option
+}
+
+// GetOptions implements the OptionBuilder interface
+func (o *optionBuilder) GetOptions() []status.Option {
+	return o.options
+}
+
+// statusOptions returns an initialized optionBuilder
+func statusOptions() *optionBuilder {
+	return &optionBuilder{
+		options: []status.Option{},
+	}
+}
+
+func (o *optionBuilder) withRestURI(uri string) *optionBuilder {
+	o.options = append(o.options,
+		restURIOption{
+			restURI: uri,
+		})
+	return o
+}
+
+type restURIOption struct {
+	restURI string
+}
+
+func (r restURIOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.RestURI = r.restURI
+}
+
+func (r restURIOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withVersion(version string) *optionBuilder {
+	o.options = append(o.options,
+		versionOption{
+			version: version,
+		})
+	return o
+}
+
+type versionOption struct {
+	version string
+}
+
+func (v versionOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.Version = v.version
+}
+
+func (v versionOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withPhase(phase opencgav1.Phase, retryAfter int) *optionBuilder {
+	o.options = append(o.options,
+		phaseOption{
+			phase:      phase,
+			retryAfter: retryAfter,
+		})
+	return o
+}
+
+type message struct {
+	messageString string
+	severityLevel severity
+}
+
+type messageOption struct {
+	message message
+}
+
+func (m messageOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.Message = m.message.messageString
+	if m.message.severityLevel == Error {
+		zap.S().Error(m.message.messageString)
+	}
+	if m.message.severityLevel == Warn {
+		zap.S().Warn(m.message.messageString)
+	}
+	if m.message.severityLevel == Info {
+		zap.S().Info(m.message.messageString)
+	}
+	if m.message.severityLevel == Debug {
+		zap.S().Debug(m.message.messageString)
+	}
+}
+
+func (m messageOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+func (o *optionBuilder) withRestMembers(members int) *optionBuilder {
+	o.options = append(o.options,
+		restMembersOption{
+			members: members,
+		})
+	return o
+}
+
+func (o *optionBuilder) withStatefulSetReplicas(members int) *optionBuilder {
+	o.options = append(o.options,
+		statefulSetReplicasOption{
+			replicas: members,
+		})
+	return o
+}
+
+func (o *optionBuilder) withMessage(severityLevel severity, msg string) *optionBuilder {
+	o.options = append(o.options, messageOption{
+		message: message{
+			messageString: msg,
+			severityLevel: severityLevel,
+		},
+	})
+	return o
+}
+
+func (o *optionBuilder) withCondition(condition metav1.Condition) *optionBuilder {
+	o.options = append(o.options, conditionOption{
+		condition: condition,
+	})
+	return o
+}
+
+func (o *optionBuilder) withMigration(migration opencgav1.MigrationStatus) *optionBuilder {
+	o.options = append(o.options, migrationOption{
+		migration: migration,
+	})
+	return o
+}
+
+func (o *optionBuilder) withFailedPhase() *optionBuilder {
+	return o.withPhase(opencgav1.Failed, 0)
+}
+
+func (o *optionBuilder) withPendingPhase(retryAfter int) *optionBuilder {
+	return o.withPhase(opencgav1.Pending, retryAfter)
+}
+
+func (o *optionBuilder) withRunningPhase() *optionBuilder {
+	return o.withPhase(opencgav1.Running, -1)
+}
+
+type restMembersOption struct {
+	members int
+}
+
+func (r restMembersOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.CurrentRestMembers = r.members
+}
+
+func (r restMembersOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+type statefulSetReplicasOption struct {
+	replicas int
+}
+
+func (s statefulSetReplicasOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.CurrentStatefulSetReplicas = s.replicas
+}
+
+func (s statefulSetReplicasOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+type conditionOption struct {
+	condition metav1.Condition
+}
+
+func (c conditionOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	meta.SetStatusCondition(&ocb.Status.Conditions, c.condition)
+}
+
+func (c conditionOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+type migrationOption struct {
+	migration opencgav1.MigrationStatus
+}
+
+func (m migrationOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	migration := m.migration
+	ocb.Status.Migration = &migration
+}
+
+func (m migrationOption) GetResult() (reconcile.Result, error) {
+	return result.OK()
+}
+
+type phaseOption struct {
+	phase      opencgav1.Phase
+	retryAfter int
+}
+
+func (p phaseOption) ApplyOption(ocb *opencgav1.OpenCGACommunity) {
+	ocb.Status.Phase = p.phase
+}
+
+func (p phaseOption) GetResult() (reconcile.Result, error) {
+	if p.phase == opencgav1.Running {
+		return result.OK()
+	}
+	if p.phase == opencgav1.Pending {
+		return result.Retry(p.retryAfter)
+	}
+	if p.phase == opencgav1.Failed {
+		return result.Failed()
+	}
+	return result.OK()
+}
@@ -18,45 +18,1416 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"k8s.io/apimachinery/pkg/runtime"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/cmd/predicates"
+	"github.com/phamidko/opencga-operator/controllers/construct"
+	"github.com/phamidko/opencga-operator/controllers/validation"
+	"github.com/phamidko/opencga-operator/pkg/authentication/scram"
+	"github.com/phamidko/opencga-operator/pkg/authentication/x509"
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/kube/annotations"
+	kubernetesClient "github.com/phamidko/opencga-operator/pkg/kube/client"
+	"github.com/phamidko/opencga-operator/pkg/kube/horizontalpodautoscaler"
+	"github.com/phamidko/opencga-operator/pkg/kube/ingress"
+	"github.com/phamidko/opencga-operator/pkg/kube/job"
+	"github.com/phamidko/opencga-operator/pkg/kube/poddisruptionbudget"
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+	"github.com/phamidko/opencga-operator/pkg/kube/service"
+	"github.com/phamidko/opencga-operator/pkg/kube/statefulset"
+	"github.com/phamidko/opencga-operator/pkg/opencgaconfig"
+	"github.com/phamidko/opencga-operator/pkg/preflight"
+	"github.com/phamidko/opencga-operator/pkg/readiness/headless"
+	"github.com/phamidko/opencga-operator/pkg/readiness/pod"
+	"github.com/phamidko/opencga-operator/pkg/util/envvar"
+	"github.com/phamidko/opencga-operator/pkg/util/generate"
+	"github.com/phamidko/opencga-operator/pkg/util/merge"
+	"github.com/phamidko/opencga-operator/pkg/util/result"
+	"github.com/phamidko/opencga-operator/pkg/util/scale"
+	"github.com/phamidko/opencga-operator/pkg/util/status"
 )
 
+const (
+	clusterDomain = "CLUSTER_DOMAIN"
+
+	// preflightTimeout is the time the pre-flight checks of the services OpenCGA depends on can take.
+	preflightTimeout = 5 * time.Second
+
+	automationDownloadBase = "/var/lib/opencga-mms-automation"
+)
+
+func NewReconciler(mgr manager.Manager) *OpenCGACommunityReconciler {
+	return &OpenCGACommunityReconciler{
+		client:     kubernetesClient.NewClient(mgr.GetClient()),
+		log:        zap.S(),
+		httpClient: &http.Client{Timeout: preflightTimeout},
+	}
+}
+
 // OpenCGACommunityReconciler reconciles a OpenCGACommunity object
 type OpenCGACommunityReconciler struct {
-	client.Client
-	Scheme *runtime.Scheme
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client     kubernetesClient.Client
+	log        *zap.SugaredLogger
+	httpClient *http.Client
 }
 
 //+kubebuilder:rbac:groups=opencga.zetta.com,resources=opencgacommunities,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=opencga.zetta.com,resources=opencgacommunities/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=opencga.zetta.com,resources=opencgacommunities/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets;services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile reads that state of the cluster for a OpenCGACommunity object and makes changes based on the state read
+// and what is in the OpenCGACommunity.Spec
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *OpenCGACommunityReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// TODO: generalize preparation for resource
+	// Fetch the OpenCGACommunity instance
+	ocb := opencgav1.OpenCGACommunity{}
+	err := r.client.Get(ctx, request.NamespacedName, &ocb)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return result.OK()
+		}
+		r.log.Errorf("Error reconciling OpenCGACommunity resource: %s", err)
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	r.log = zap.S().With("OpenCGACommunity", request.NamespacedName)
+	r.log.Infow("Reconciling OpenCGACommunity", "OpenCGACommunity.Spec", ocb.Spec, "OpenCGACommunity.Status", ocb.Status)
+
+	r.log.Debug("Validating the resource")
+	if err := validation.ValidateSpec(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Invalid OpenCGACommunity resource: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Ensuring the services exist")
+	if err := r.ensureServices(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the services exist: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Ensuring the ingress")
+	if err := r.ensureIngress(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the ingress: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Publishing the user connection secrets")
+	if err := r.ensureUserConnectionSecrets(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error publishing the user connection secrets: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Deleting the secrets of the removed users")
+	if err := r.deleteRemovedUserSecrets(&ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error deleting the secrets of the removed users: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Ensuring the pod disruption budget")
+	if err := r.ensurePodDisruptionBudget(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the pod disruption budget: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Ensuring the horizontal pod autoscaler")
+	if err := r.ensureHorizontalPodAutoscaler(ocb); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the horizontal pod autoscaler: %s", err)).
+				withFailedPhase(),
+		)
+	}
+	ocb.Status.Selector = ocb.RestPodSelector()
+
+	r.log.Debug("Ensuring the TLS certificates")
+	tls, err := r.ensureTLSResources(ocb)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the TLS certificates: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Deploying the automation config")
+	tlsMode, err := r.deployAutomationConfig(ocb, tls)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error deploying the automation config: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Ensuring the agent API key")
+	agentAPIKeyHash, err := r.ensureAgentAPIKey(ocb)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error ensuring the agent API key: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Reading the search configuration")
+	search, err := r.readSearchConnection(ocb)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error reading the search configuration: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Rendering the OpenCGA configuration")
+	configHash, err := r.ensureOpenCGAConfig(ocb, search)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error rendering the OpenCGA configuration: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	r.log.Debug("Checking the services OpenCGA depends on")
+	if err := r.checkDependencies(ctx, search); err != nil {
+		// the REST pods would only crash-loop, so the StatefulSets are left untouched until the services are reachable
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withCondition(metav1.Condition{
+					Type:    opencgav1.ConditionDegraded,
+					Status:  metav1.ConditionTrue,
+					Reason:  "SearchUnreachable",
+					Message: err.Error(),
+				}).
+				withMessage(Warn, fmt.Sprintf("Solr is not reachable, retrying in 30 seconds: %s", err)).
+				withPendingPhase(30),
+		)
+	}
+
+	r.log.Debug("Checking the OIDC issuer")
+	if err := r.checkOIDCIssuer(ctx, ocb); err != nil {
+		// users could not log in with the OpenID provider, so the StatefulSets are left untouched until it is reachable
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withCondition(metav1.Condition{
+					Type:    opencgav1.ConditionDegraded,
+					Status:  metav1.ConditionTrue,
+					Reason:  "OIDCIssuerUnreachable",
+					Message: err.Error(),
+				}).
+				withMessage(Warn, fmt.Sprintf("The discovery document of the OIDC issuer can't be fetched, retrying in 30 seconds: %s", err)).
+				withPendingPhase(30),
+		)
+	}
+	meta.SetStatusCondition(&ocb.Status.Conditions, metav1.Condition{
+		Type:    opencgav1.ConditionDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  "DependenciesReachable",
+		Message: "All the services OpenCGA depends on are reachable",
+	})
+
+	r.log.Debug("Installing the catalog")
+	installed, err := r.ensureCatalogInstalled(ocb)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error installing the catalog: %s", err)).
+				withFailedPhase(),
+		)
+	}
+	if !installed {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Info, fmt.Sprintf("Job %s/%s is installing the catalog, retrying in 10 seconds", ocb.Namespace, ocb.CatalogInstallJobName())).
+				withPendingPhase(10),
+		)
+	}
+	meta.SetStatusCondition(&ocb.Status.Conditions, metav1.Condition{
+		Type:    opencgav1.ConditionCatalogInstalled,
+		Status:  metav1.ConditionTrue,
+		Reason:  "InstallJobSucceeded",
+		Message: "The catalog has been installed",
+	})
+
+	if ocb.IsChangingVersion() {
+		r.log.Debug("Migrating the catalog")
+		migration, err := r.ensureMigration(ctx, ocb)
+		if err != nil {
+			return status.Update(r.client.Status(), &ocb,
+				statusOptions().
+					withMigration(migration).
+					withMessage(Error, fmt.Sprintf("Error migrating the catalog to version %s: %s", ocb.GetOpenCGAVersion(), err)).
+					withFailedPhase(),
+			)
+		}
+		switch migration.Phase {
+		case opencgav1.MigrationAwaitingApproval:
+			return status.Update(r.client.Status(), &ocb,
+				statusOptions().
+					withMigration(migration).
+					withMessage(Warn, fmt.Sprintf("The catalog migration to version %s requires approval, annotate the resource with %s=%s",
+						ocb.GetOpenCGAVersion(), opencgav1.MigrationApprovalAnnotation, ocb.GetOpenCGAVersion())).
+					withPendingPhase(30),
+			)
+		case opencgav1.MigrationRunning:
+			return status.Update(r.client.Status(), &ocb,
+				statusOptions().
+					withMigration(migration).
+					withMessage(Info, fmt.Sprintf("Job %s/%s is migrating the catalog, retrying in 10 seconds", ocb.Namespace, migration.JobName)).
+					withPendingPhase(10),
+			)
+		}
+		ocb.Status.Migration = &migration
+	}
+
+	r.log.Debug("Creating/Updating the StatefulSets")
+	if err := r.createOrUpdateStatefulSets(ocb, configHash, agentAPIKeyHash); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error creating/updating the StatefulSets: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if ready, err := r.statefulSetsAreReady(ocb); err != nil || !ready {
+		msg := fmt.Sprintf("StatefulSet %s/%s is not yet ready, retrying in 10 seconds", ocb.Namespace, ocb.Name)
+		if err != nil {
+			msg = fmt.Sprintf("Error checking the StatefulSets: %s", err)
+		}
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Info, msg).
+				withPendingPhase(10),
+		)
+	}
+
+	if scale.IsStillScaling(ocb) {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withRestMembers(scale.ReplicasThisReconciliation(ocb)).
+				withStatefulSetReplicas(scale.ReplicasThisReconciliation(ocb)).
+				withMessage(Info, fmt.Sprintf("Performing scaling operation, currentMembers=%d, desiredMembers=%d",
+					ocb.CurrentReplicas(), ocb.DesiredReplicas())).
+				withPendingPhase(10),
+		)
+	}
+
+	if tlsMode != ocb.GetTLSMode() {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Info, fmt.Sprintf("Moving the TLS mode from %s to %s, retrying in 10 seconds", tlsMode, ocb.GetTLSMode())).
+				withPendingPhase(10),
+		)
+	}
+
+	rotating, err := scram.IsRotatingAgentCredentials(r.client, ocb)
+	if err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error reading the agent credentials: %s", err)).
+				withFailedPhase(),
+		)
+	}
+	if rotating {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Info, "Rotating the agent credentials, retrying in 10 seconds").
+				withPendingPhase(10),
+		)
+	}
+
+	res, err := status.Update(r.client.Status(), &ocb,
+		statusOptions().
+			withRestURI(clientRestURI(ocb)).
+			withRestMembers(ocb.DesiredReplicas()).
+			withStatefulSetReplicas(ocb.DesiredReplicas()).
+			withVersion(ocb.GetOpenCGAVersion()).
+			withMessage(None, "").
+			withRunningPhase(),
+	)
+	if err != nil {
+		r.log.Errorf("Error updating the status of the OpenCGACommunity resource: %s", err)
+		return res, err
+	}
+
+	if err := statefulset.ResetUpdateStrategy(&ocb, r.client); err != nil {
+		return status.Update(r.client.Status(), &ocb,
+			statusOptions().
+				withMessage(Error, fmt.Sprintf("Error resetting StatefulSet UpdateStrategyType: %s", err)).
+				withFailedPhase(),
+		)
+	}
+
+	if err := annotations.UpdateLastAppliedOpenCGAVersion(&ocb, r.client); err != nil {
+		r.log.Errorf("Could not save current version as an annotation: %s", err)
+	}
+
+	// resyncs are filtered out, the next scheduled rotation of the agent credentials is requeued explicitly.
+	if untilRotation, err := scram.TimeUntilScheduledRotation(r.client, ocb); err != nil {
+		r.log.Errorf("Could not read when the agent credentials are next rotated: %s", err)
+	} else if untilRotation > 0 && (res.RequeueAfter == 0 || untilRotation < res.RequeueAfter) {
+		res.RequeueAfter = untilRotation
+	}
+
+	if res.RequeueAfter > 0 || res.Requeue {
+		r.log.Info("Requeuing reconciliation")
+		return res, nil
+	}
+
+	r.log.Infow("Successfully finished reconciliation", "OpenCGACommunity.Spec", ocb.Spec, "OpenCGACommunity.Status", ocb.Status)
+	return res, err
+}
+
+// ensureServices creates the headless Service which governs the REST StatefulSet, and the query and admin Services
+// clients connect through, or updates them if they exist.
+func (r *OpenCGACommunityReconciler) ensureServices(ocb opencgav1.OpenCGACommunity) error {
+	for _, svc := range []corev1.Service{buildService(ocb), buildQueryService(ocb), buildAdminService(ocb)} {
+		if err := service.CreateOrUpdate(r.client, svc); err != nil {
+			return errors.Errorf("could not create/update service %s: %s", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func buildService(ocb opencgav1.OpenCGACommunity) corev1.Service {
+	label := map[string]string{"app": ocb.ServiceName()}
+	return service.Builder().
+		SetName(ocb.ServiceName()).
+		SetNamespace(ocb.Namespace).
+		SetSelector(label).
+		SetLabels(label).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetClusterIP("None").
+		SetPublishNotReadyAddresses(true).
+		SetOwnerReferences(ocb.GetOwnerReferences()).
+		AddPort(&corev1.ServicePort{Port: int32(ocb.GetRestPort()), Name: construct.RestPortName}).
+		Build()
+}
+
+// buildQueryService returns the Service load-balancing the requests across the ready REST members.
+func buildQueryService(ocb opencgav1.OpenCGACommunity) corev1.Service {
+	return buildLoadBalancingService(ocb, ocb.QueryServiceName(), map[string]string{"app": ocb.ServiceName()})
+}
+
+// buildAdminService returns the Service sending the requests to the first REST member only.
+func buildAdminService(ocb opencgav1.OpenCGACommunity) corev1.Service {
+	return buildLoadBalancingService(ocb, ocb.AdminServiceName(), map[string]string{
+		"app":                          ocb.ServiceName(),
+		appsv1.StatefulSetPodNameLabel: fmt.Sprintf("%s-0", ocb.Name),
+	})
+}
+
+func buildLoadBalancingService(ocb opencgav1.OpenCGACommunity, name string, selector map[string]string) corev1.Service {
+	sessionAffinity, timeoutSeconds := ocb.GetSessionAffinity()
+	return service.Builder().
+		SetName(name).
+		SetNamespace(ocb.Namespace).
+		SetSelector(selector).
+		SetLabels(map[string]string{"app": ocb.ServiceName()}).
+		SetServiceType(corev1.ServiceTypeClusterIP).
+		SetSessionAffinity(sessionAffinity, timeoutSeconds).
+		SetOwnerReferences(ocb.GetOwnerReferences()).
+		AddPort(&corev1.ServicePort{Port: int32(ocb.GetRestPort()), Name: construct.RestPortName}).
+		Build()
+}
+
+// ensureIngress creates or updates the Ingress exposing the REST API, or deletes it if the REST API is not exposed.
+func (r *OpenCGACommunityReconciler) ensureIngress(ocb opencgav1.OpenCGACommunity) error {
+	if ocb.Spec.Ingress == nil {
+		return ingress.DeleteIngressIfItExists(r.client, types.NamespacedName{Name: ocb.IngressName(), Namespace: ocb.Namespace})
+	}
+	return ingress.CreateOrUpdate(r.client, buildIngress(ocb))
+}
+
+// buildIngress returns the Ingress routing the configured host and path to the REST port of the query Service.
+func buildIngress(ocb opencgav1.OpenCGACommunity) networkingv1.Ingress {
+	spec := ocb.Spec.Ingress
+	pathType := networkingv1.PathTypePrefix
+	ing := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ocb.IngressName(),
+			Namespace:       ocb.Namespace,
+			Labels:          map[string]string{"app": ocb.ServiceName()},
+			Annotations:     spec.Annotations,
+			OwnerReferences: ocb.GetOwnerReferences(),
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: spec.IngressClassName,
+			Rules: []networkingv1.IngressRule{{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     ocb.GetIngressPath(),
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: ocb.QueryServiceName(),
+							Port: networkingv1.ServiceBackendPort{Name: construct.RestPortName},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		ing.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{spec.Host}, SecretName: spec.TLSSecretName}}
+	}
+	return ing
+}
+
+// ensurePodDisruptionBudget creates or updates the PodDisruptionBudget of the REST members, or deletes it if there is a
+// single member.
+func (r *OpenCGACommunityReconciler) ensurePodDisruptionBudget(ocb opencgav1.OpenCGACommunity) error {
+	maxUnavailable := ocb.GetMaxUnavailable()
+	if maxUnavailable == nil {
+		return poddisruptionbudget.DeletePodDisruptionBudgetIfItExists(r.client, types.NamespacedName{Name: ocb.PodDisruptionBudgetName(), Namespace: ocb.Namespace})
+	}
+
+	labels := map[string]string{"app": ocb.ServiceName()}
+	return poddisruptionbudget.CreateOrUpdate(r.client, policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ocb.PodDisruptionBudgetName(),
+			Namespace:       ocb.Namespace,
+			Labels:          labels,
+			OwnerReferences: ocb.GetOwnerReferences(),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: maxUnavailable,
+			Selector:       &metav1.LabelSelector{MatchLabels: labels},
+		},
+	})
+}
+
+// ensureHorizontalPodAutoscaler creates or updates the HorizontalPodAutoscaler of the resource, or deletes it if
+// autoscaling is disabled. The autoscaler targets the scale subresource of the resource rather than the StatefulSet,
+// so the members it chooses are rolled out like any other change of spec.members.
+func (r *OpenCGACommunityReconciler) ensureHorizontalPodAutoscaler(ocb opencgav1.OpenCGACommunity) error {
+	if ocb.Spec.Autoscaling == nil {
+		return horizontalpodautoscaler.DeleteHorizontalPodAutoscalerIfItExists(r.client, types.NamespacedName{Name: ocb.HorizontalPodAutoscalerName(), Namespace: ocb.Namespace})
+	}
+	return horizontalpodautoscaler.CreateOrUpdate(r.client, buildHorizontalPodAutoscaler(ocb))
+}
+
+func buildHorizontalPodAutoscaler(ocb opencgav1.OpenCGACommunity) autoscalingv2.HorizontalPodAutoscaler {
+	var metrics []autoscalingv2.MetricSpec
+	if target := ocb.GetTargetCPUUtilizationPercentage(); target != nil {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: target,
+				},
+			},
+		})
+	}
+	if requestRate := ocb.Spec.Autoscaling.RequestRate; requestRate != nil {
+		targetAverageValue := requestRate.TargetAverageValue.DeepCopy()
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: ocb.GetRequestRateMetricName()},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: &targetAverageValue,
+				},
+			},
+		})
+	}
+
+	minMembers := ocb.GetMinMembers()
+	return autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ocb.HorizontalPodAutoscalerName(),
+			Namespace:       ocb.Namespace,
+			Labels:          map[string]string{"app": ocb.ServiceName()},
+			OwnerReferences: ocb.GetOwnerReferences(),
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: opencgav1.GroupVersion.String(),
+				Kind:       "OpenCGACommunity",
+				Name:       ocb.Name,
+			},
+			MinReplicas: &minMembers,
+			MaxReplicas: ocb.Spec.Autoscaling.MaxMembers,
+			Metrics:     metrics,
+		},
+	}
+}
+
+// ensureUserConnectionSecrets publishes, for every user of the resource, a Secret with the URL clients should use to
+// reach the REST API, the user name, the reference to its password and a client-configuration.yml pointing at the REST API.
+func (r *OpenCGACommunityReconciler) ensureUserConnectionSecrets(ocb opencgav1.OpenCGACommunity) error {
+	restURL := clientRestURI(ocb)
+	clientConfiguration, err := buildUserClientConfiguration(ocb, restURL)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range ocb.GetUsers() {
+		connectionSecret := secret.Builder().
+			SetName(user.GetConnectionStringSecretName(ocb.NamespacedName())).
+			SetNamespace(ocb.Namespace).
+			SetLabels(map[string]string{"app": ocb.ServiceName()}).
+			SetField(opencgav1.UserRestURLKey, restURL).
+			SetField(opencgav1.UserUsernameKey, user.Username).
+			SetField(opencgav1.UserPasswordSecretNameKey, user.PasswordSecretName).
+			SetField(opencgav1.UserPasswordSecretKeyKey, user.PasswordSecretKey).
+			SetField(opencgaconfig.ClientConfigurationKey, clientConfiguration).
+			SetOwnerReferences(ocb.GetOwnerReferences()).
+			Build()
+		if err := secret.CreateOrUpdate(r.client, connectionSecret); err != nil {
+			return errors.Errorf("could not publish the connection secret of user %s: %s", user.Username, err)
+		}
+	}
+	return nil
+}
+
+// deleteRemovedUserSecrets deletes the connection and SCRAM credentials Secrets of the users recorded in the status
+// which have been removed from the resource, and records the current users in the status.
+func (r *OpenCGACommunityReconciler) deleteRemovedUserSecrets(ocb *opencgav1.OpenCGACommunity) error {
+	current := map[string]bool{}
+	var users []opencgav1.UserStatus
+	for _, user := range ocb.GetUsers() {
+		current[user.Username] = true
+		users = append(users, opencgav1.UserStatus{
+			Name:                       user.Username,
+			ConnectionSecretName:       user.GetConnectionStringSecretName(ocb.NamespacedName()),
+			ScramCredentialsSecretName: user.ScramCredentialsSecretName,
+		})
+	}
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the OpenCGACommunity object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.2/pkg/reconcile
-func (r *OpenCGACommunityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	var removed []string
+	for _, user := range ocb.Status.Users {
+		if current[user.Name] {
+			continue
+		}
+		for _, name := range []string{user.ConnectionSecretName, user.ScramCredentialsSecretName} {
+			if err := r.client.DeleteSecret(types.NamespacedName{Name: name, Namespace: ocb.Namespace}); err != nil && !apiErrors.IsNotFound(err) {
+				return errors.Errorf("could not delete secret %s of user %s: %s", name, user.Name, err)
+			}
+		}
+		r.log.Infof("Deleted the secrets of user %s", user.Name)
+		removed = append(removed, user.Name)
+	}
 
-	// TODO(user): your logic here
+	if len(removed) > 0 {
+		meta.SetStatusCondition(&ocb.Status.Conditions, metav1.Condition{
+			Type:    opencgav1.ConditionUsersDeleted,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SecretsDeleted",
+			Message: fmt.Sprintf("The connection and SCRAM credentials secrets of users %s have been deleted", strings.Join(removed, ", ")),
+		})
+	}
+	ocb.Status.Users = users
+	return nil
+}
+
+// buildUserClientConfiguration renders the client-configuration.yml published for the users, connecting to the given URL.
+func buildUserClientConfiguration(ocb opencgav1.OpenCGACommunity, restURL string) (string, error) {
+	data, err := opencgaconfig.NewBuilder().
+		SetLogLevel(ocb.GetLogLevel()).
+		SetRestHost(restURL).
+		SetAdditionalClientConfiguration(ocb.Spec.AdditionalClientConfig.Object).
+		Build().
+		Data()
+	if err != nil {
+		return "", errors.Errorf("could not render the client configuration: %s", err)
+	}
+	return data[opencgaconfig.ClientConfigurationKey], nil
+}
+
+// clientRestURI returns the URL of the REST API given to clients, in the status and the user connection secrets: the
+// Ingress one if it is exposed, the query Service one otherwise. The headless Service is never used as it resolves to
+// every member, including the ones which are not ready.
+func clientRestURI(ocb opencgav1.OpenCGACommunity) string {
+	if uri := ocb.ExternalRestURI(); uri != "" {
+		return uri
+	}
+	return ocb.RestURI(os.Getenv(clusterDomain))
+}
+
+// deployAutomationConfig builds the automation config from the resource and stores it in a Secret
+// which is read by the agents. The TLS mode the members are configured with is returned.
+func (r *OpenCGACommunityReconciler) deployAutomationConfig(ocb opencgav1.OpenCGACommunity, tls tlsFiles) (automationconfig.TLSMode, error) {
+	currentAC, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: ocb.AutomationConfigSecretName(), Namespace: ocb.Namespace})
+	if err != nil {
+		return "", errors.Errorf("could not read existing automation config: %s", err)
+	}
+	agentsReachedGoal := r.agentsReachedVersion(ocb, currentAC.Version)
+
+	if err := r.completeAgentCredentialsRotation(ocb, currentAC, agentsReachedGoal); err != nil {
+		return "", errors.Errorf("could not complete the rotation of the agent credentials: %s", err)
+	}
+
+	auth := automationconfig.Auth{}
+	if err := scram.Enable(&auth, r.client, ocb); err != nil {
+		return "", errors.Errorf("could not configure the users: %s", err)
+	}
+	auth.Users = automationConfigUsers(ocb, auth.Users, currentAC, agentsReachedGoal)
+
+	tlsMode := nextTLSMode(currentTLSMode(currentAC), ocb.GetTLSMode(), len(currentAC.Processes) == 0, agentsReachedGoal)
+	if tlsMode != currentTLSMode(currentAC) {
+		r.log.Infof("Moving the TLS mode from %s to %s", currentTLSMode(currentAC), tlsMode)
+	}
+
+	tlsConfig := automationconfig.TLS{CAFilePath: tls.caFile, ClientCertificateMode: ocb.GetClientCertificateMode(tlsMode)}
+	ac, err := buildAutomationConfig(ocb, currentAC, restTLS(tlsMode, tls), tlsConfig, authModification(auth), x509Modification(ocb))
+	if err != nil {
+		return "", errors.Errorf("could not build automation config: %s", err)
+	}
+
+	_, err = automationconfig.EnsureSecret(r.client, types.NamespacedName{Name: ocb.AutomationConfigSecretName(), Namespace: ocb.Namespace}, ocb.GetOwnerReferences(), ac)
+	return tlsMode, err
+}
+
+// restTLS returns the TLS configuration of the members for the given mode, or nil if TLS is disabled.
+func restTLS(mode automationconfig.TLSMode, tls tlsFiles) *automationconfig.RestTLS {
+	if mode == automationconfig.TLSModeDisabled {
+		return nil
+	}
+	return &automationconfig.RestTLS{Mode: mode, CertificateKeyFile: tls.certificateKeyFile}
+}
+
+// automationConfigUsers returns the SCRAM users of the resource followed by its X.509 users, and a deletion request for
+// every user removed from the resource since the current automation config.
+func automationConfigUsers(ocb opencgav1.OpenCGACommunity, scramUsers []automationconfig.MongoDBUser, currentAC automationconfig.AutomationConfig, agentsReachedGoal bool) []automationconfig.MongoDBUser {
+	desired := append(scramUsers, x509.ConvertUsers(ocb.GetX509Users())...)
+	return scram.RequestUserDeletions(desired, currentAC.Auth.Users, agentsReachedGoal)
+}
+
+// completeAgentCredentialsRotation drops the previous agent password and keyfile once every agent has reached the goal
+// state of the current automation config, which holds the new ones. The automation config built next only accepts the
+// new credentials.
+func (r *OpenCGACommunityReconciler) completeAgentCredentialsRotation(ocb opencgav1.OpenCGACommunity, currentAC automationconfig.AutomationConfig, agentsReachedGoal bool) error {
+	if !agentsReachedGoal || (currentAC.Auth.PreviousKey == "" && currentAC.Auth.PreviousAutoPwd == "") {
+		return nil
+	}
+	rotating, err := scram.IsRotatingAgentCredentials(r.client, ocb)
+	if err != nil || !rotating {
+		return err
+	}
+	return scram.CompleteAgentCredentialsRotation(r.client, ocb)
+}
+
+// x509Modification lets the users of the resource authenticate with their client certificates if it maps any to users.
+func x509Modification(ocb opencgav1.OpenCGACommunity) automationconfig.Modification {
+	if len(ocb.Spec.Security.X509Users) == 0 {
+		return automationconfig.NOOP()
+	}
+	return func(config *automationconfig.AutomationConfig) {
+		x509.EnableDeploymentMechanism(&config.Auth)
+	}
+}
 
-	return ctrl.Result{}, nil
+// agentsReachedVersion returns true if the agent of every REST member has reached the goal state of the given
+// automation config version. The version reached is read from the Pod annotations written by the readiness probe,
+// or scraped from the agent version sidecar if the probe runs without access to the Kubernetes API.
+func (r *OpenCGACommunityReconciler) agentsReachedVersion(ocb opencgav1.OpenCGACommunity, version int) bool {
+	for i := 0; i < scale.ReplicasThisReconciliation(ocb); i++ {
+		member := corev1.Pod{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("%s-%d", ocb.Name, i), Namespace: ocb.Namespace}, &member); err != nil {
+			return false
+		}
+		if ocb.IsReadinessProbeLocalMode() {
+			if !r.scrapedAgentVersionReached(member, version) {
+				return false
+			}
+			continue
+		}
+		if !pod.ReachedAutomationConfigVersion(member, version) {
+			return false
+		}
+	}
+	return true
+}
+
+// scrapedAgentVersionReached returns true if the agent version sidecar of the member reports that the agent has
+// reached the given automation config version. It is used instead of the Pod annotation when the readiness probe
+// runs without access to the Kubernetes API.
+func (r *OpenCGACommunityReconciler) scrapedAgentVersionReached(member corev1.Pod, version int) bool {
+	if member.Status.PodIP == "" {
+		return false
+	}
+	baseURL := "http://" + net.JoinHostPort(member.Status.PodIP, strconv.Itoa(construct.AgentVersionPort))
+	agentVersion, err := headless.FetchAgentVersion(context.TODO(), r.httpClient, baseURL)
+	if err != nil {
+		r.log.Debugf("Could not fetch the agent version of member %s: %s", member.Name, err)
+		return false
+	}
+	return agentVersion >= int64(version)
+}
+
+// authModification sets the authentication settings and the users of the automation config.
+func authModification(auth automationconfig.Auth) automationconfig.Modification {
+	return func(config *automationconfig.AutomationConfig) {
+		config.Auth = auth
+	}
+}
+
+// buildAutomationConfig builds the automation config of the resource. TLS is configured, along with tlsConfig, if tls is not nil.
+func buildAutomationConfig(ocb opencgav1.OpenCGACommunity, currentAC automationconfig.AutomationConfig, tls *automationconfig.RestTLS, tlsConfig automationconfig.TLS, modifications ...automationconfig.Modification) (automationconfig.AutomationConfig, error) {
+	domain := service.FQDN(ocb.ServiceName(), ocb.Namespace, envvar.GetEnvOrDefault(clusterDomain, "cluster.local"))
+	builder := automationconfig.NewBuilder().
+		SetTopology(automationconfig.ReplicaSetTopology).
+		SetName(ocb.Name).
+		SetDomain(domain).
+		SetMembers(scale.ReplicasThisReconciliation(ocb)).
+		SetOpenCGAVersion(ocb.GetAutomationConfigOpenCGAVersion()).
+		SetPreviousAutomationConfig(currentAC).
+		SetOptions(automationconfig.Options{DownloadBase: automationDownloadBase}).
+		SetPort(ocb.GetRestPort()).
+		SetWorkspace(ocb.GetWorkspace()).
+		SetLogLevel(ocb.GetLogLevel()).
+		SetCatalogDatabasePrefix(ocb.GetDatabasePrefix()).
+		SetThreadPool(ocb.GetThreadPool()).
+		SetJVMOptions(ocb.GetJVMOptions()).
+		AddRoles(ocb.GetCustomRoles()...).
+		AddModifications(modifications...)
+
+	if tls != nil {
+		builder.SetRestTLS(*tls).SetTLSConfig(tlsConfig)
+	}
+	return builder.Build()
+}
+
+// ensureOpenCGAConfig renders the OpenCGA configuration files into a Secret and returns their hash.
+func (r *OpenCGACommunityReconciler) ensureOpenCGAConfig(ocb opencgav1.OpenCGACommunity, search *opencgaconfig.Search) (string, error) {
+	catalog, err := r.ensureCatalogConnection(ocb)
+	if err != nil {
+		return "", errors.Errorf("could not configure the catalog connection: %s", err)
+	}
+
+	ldap, err := r.readLDAPConnection(ocb)
+	if err != nil {
+		return "", errors.Errorf("could not configure the LDAP authentication: %s", err)
+	}
+
+	oidc, err := r.readOIDCConnection(ocb)
+	if err != nil {
+		return "", errors.Errorf("could not configure the OIDC authentication: %s", err)
+	}
+
+	config := buildOpenCGAConfig(ocb, catalog, search, ldap, oidc)
+	return opencgaconfig.EnsureSecret(r.client, types.NamespacedName{Name: ocb.OpenCGAConfigSecretName(), Namespace: ocb.Namespace}, ocb.GetOwnerReferences(), config)
+}
+
+// ensureCatalogConnection returns the settings used to connect to the catalog. The connection string is read from its
+// Secret if one is referenced. If OpenCGA authenticates with a client certificate no password is used, otherwise, unless
+// the connection string contains credentials, the password of the catalog user is read from its Secret, which is
+// generated if it does not exist.
+func (r *OpenCGACommunityReconciler) ensureCatalogConnection(ocb opencgav1.OpenCGACommunity) (opencgaconfig.Catalog, error) {
+	spec := ocb.Spec.Catalog
+	catalog := opencgaconfig.Catalog{Hosts: spec.Hosts}
+
+	if ref := ocb.GetCatalogConnectionStringSecretKeyRef(); ref != nil {
+		connectionString, err := secret.ReadKey(r.client, ref.Key, types.NamespacedName{Name: ref.Name, Namespace: ocb.Namespace})
+		if err != nil {
+			return opencgaconfig.Catalog{}, errors.Errorf("could not read the connection string: %s", err)
+		}
+		catalog, err = opencgaconfig.ParseConnectionString(connectionString)
+		if err != nil {
+			return opencgaconfig.Catalog{}, errors.Errorf("invalid connection string in secret %s/%s: %s", ocb.Namespace, ref.Name, err)
+		}
+	}
+
+	// the settings in the resource take precedence over the ones in the connection string
+	if spec.ReplicaSetName != "" {
+		catalog.ReplicaSet = spec.ReplicaSetName
+	}
+	if spec.AuthenticationDatabase != "" || catalog.AuthenticationDatabase == "" {
+		catalog.AuthenticationDatabase = ocb.GetCatalogAuthenticationDatabase()
+	}
+	catalog.SSLEnabled = catalog.SSLEnabled || spec.TLS.Enabled
+
+	if ocb.GetCatalogClientCertificateSecretName() != "" {
+		// the user defaults to the subject of the client certificate
+		catalog.X509Authentication = true
+		catalog.User = spec.User.Name
+		catalog.Password = ""
+		return catalog, nil
+	}
+
+	if catalog.User == "" {
+		user := ocb.GetCatalogUser()
+		password, err := scram.EnsurePassword(r.client, user, ocb.Namespace, ocb.GetOwnerReferences())
+		if err != nil {
+			return opencgaconfig.Catalog{}, err
+		}
+		catalog.User = user.Username
+		catalog.Password = password
+	}
+	return catalog, nil
+}
+
+// readSearchConnection returns the settings used to connect to Solr, or nil if no search is configured.
+func (r *OpenCGACommunityReconciler) readSearchConnection(ocb opencgav1.OpenCGACommunity) (*opencgaconfig.Search, error) {
+	spec := ocb.Spec.Search
+	if spec == nil {
+		return nil, nil
+	}
+
+	search := opencgaconfig.Search{
+		Mode:             spec.Mode,
+		Hosts:            spec.Hosts,
+		CollectionPrefix: spec.CollectionPrefix,
+		TimeoutMs:        spec.TimeoutMs,
+	}
+	if len(spec.ZooKeeperHosts) > 0 {
+		search.Hosts = spec.ZooKeeperHosts
+	}
+
+	if ref := spec.CredentialsSecretRef; ref != nil {
+		credentials, err := secret.ReadStringData(r.client, types.NamespacedName{Name: ref.Name, Namespace: ocb.Namespace})
+		if err != nil {
+			return nil, errors.Errorf("could not read the Solr credentials: %s", err)
+		}
+		search.User = credentials[opencgav1.SearchUsernameKey]
+		search.Password = credentials[opencgav1.SearchPasswordKey]
+		if search.User == "" || search.Password == "" {
+			return nil, errors.Errorf("secret %s/%s must contain the %s and %s keys", ocb.Namespace, ref.Name, opencgav1.SearchUsernameKey, opencgav1.SearchPasswordKey)
+		}
+	}
+	return &search, nil
+}
+
+// readLDAPConnection returns the settings used to authenticate users against LDAP, or nil if LDAP is not configured.
+// The bind password is read from its Secret.
+func (r *OpenCGACommunityReconciler) readLDAPConnection(ocb opencgav1.OpenCGACommunity) (*opencgaconfig.LDAP, error) {
+	spec := ocb.Spec.Security.LDAP
+	if spec == nil {
+		return nil, nil
+	}
+
+	ldap := opencgaconfig.LDAP{
+		Servers:         spec.Servers,
+		BindDN:          spec.BindDN,
+		UserSearchBase:  spec.UserSearchBase,
+		GroupSearchBase: spec.GroupSearchBase,
+	}
+	if ref := ocb.GetLDAPBindPasswordSecretKeyRef(); ref != nil {
+		password, err := secret.ReadKey(r.client, ref.Key, types.NamespacedName{Name: ref.Name, Namespace: ocb.Namespace})
+		if err != nil {
+			return nil, errors.Errorf("could not read the bind password: %s", err)
+		}
+		ldap.BindPassword = password
+	}
+	return &ldap, nil
+}
+
+// readOIDCConnection returns the settings used to authenticate users with an OpenID provider, or nil if OIDC is not
+// configured. The client secret is read from its Secret.
+func (r *OpenCGACommunityReconciler) readOIDCConnection(ocb opencgav1.OpenCGACommunity) (*opencgaconfig.OIDC, error) {
+	spec := ocb.Spec.Security.OIDC
+	if spec == nil {
+		return nil, nil
+	}
+
+	ref := ocb.GetOIDCClientSecretSecretKeyRef()
+	clientSecret, err := secret.ReadKey(r.client, ref.Key, types.NamespacedName{Name: ref.Name, Namespace: ocb.Namespace})
+	if err != nil {
+		return nil, errors.Errorf("could not read the client secret: %s", err)
+	}
+	return &opencgaconfig.OIDC{
+		Issuer:       spec.Issuer,
+		ClientID:     spec.ClientID,
+		ClientSecret: clientSecret,
+		Scopes:       spec.Scopes,
+		UserIDClaim:  spec.Claims.UserID,
+		NameClaim:    spec.Claims.Name,
+		EmailClaim:   spec.Claims.Email,
+		GroupsClaim:  spec.Claims.Groups,
+	}, nil
+}
+
+// checkDependencies returns an error if one of the external services OpenCGA depends on can't be reached.
+func (r *OpenCGACommunityReconciler) checkDependencies(ctx context.Context, search *opencgaconfig.Search) error {
+	if search == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	return preflight.CheckSolr(ctx, r.httpClient, search.Hosts, preflight.Credentials{User: search.User, Password: search.Password})
+}
+
+// checkOIDCIssuer returns an error if the discovery document of the OpenID provider can't be fetched.
+func (r *OpenCGACommunityReconciler) checkOIDCIssuer(ctx context.Context, ocb opencgav1.OpenCGACommunity) error {
+	if ocb.Spec.Security.OIDC == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	return preflight.CheckOIDCIssuer(ctx, r.httpClient, ocb.Spec.Security.OIDC.Issuer)
+}
+
+func buildOpenCGAConfig(ocb opencgav1.OpenCGACommunity, catalog opencgaconfig.Catalog, search *opencgaconfig.Search, ldap *opencgaconfig.LDAP, oidc *opencgaconfig.OIDC, modifications ...opencgaconfig.Modification) opencgaconfig.Config {
+	builder := opencgaconfig.NewBuilder().
+		SetLogLevel(ocb.GetLogLevel()).
+		SetWorkspace(ocb.GetWorkspace()).
+		SetDatabasePrefix(ocb.GetDatabasePrefix()).
+		SetRestPort(ocb.GetRestPort()).
+		SetRestHost(ocb.RestURI(os.Getenv(clusterDomain))).
+		SetCatalog(catalog).
+		SetVariantStorage(variantStorage(ocb, catalog))
+	if search != nil {
+		builder.SetSearch(*search)
+	}
+	if ldap != nil {
+		builder.SetLDAP(*ldap)
+	}
+	if oidc != nil {
+		builder.SetOIDC(*oidc)
+	}
+	return builder.
+		AddModifications(modifications...).
+		SetAdditionalConfiguration(ocb.Spec.AdditionalOpenCGAConfig.Object).
+		SetAdditionalStorageConfiguration(ocb.Spec.AdditionalStorageConfig.Object).
+		SetAdditionalClientConfiguration(ocb.Spec.AdditionalClientConfig.Object).
+		Build()
+}
+
+// ensureCatalogInstalled runs the Job installing the catalog and returns whether it has completed. The Job is only
+// created once: nothing is done if the catalog is already installed, and a failed Job has to be deleted to be retried.
+// Deployments whose REST StatefulSet exists predate the install Job, their catalog is considered installed.
+func (r *OpenCGACommunityReconciler) ensureCatalogInstalled(ocb opencgav1.OpenCGACommunity) (bool, error) {
+	if ocb.IsCatalogInstalled() {
+		return true, nil
+	}
+
+	jobName := types.NamespacedName{Name: ocb.CatalogInstallJobName(), Namespace: ocb.Namespace}
+	existingJob, err := r.client.GetJob(jobName)
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil {
+		if job.IsFailed(existingJob) {
+			return false, errors.Errorf("job %s failed, delete it to retry the installation", jobName)
+		}
+		return job.IsComplete(existingJob), nil
+	}
+
+	if _, err := r.client.GetStatefulSet(ocb.NamespacedName()); err == nil {
+		return true, nil
+	} else if !apiErrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if err := r.ensureAdminPassword(ocb); err != nil {
+		return false, err
+	}
+	r.log.Infof("Creating Job %s to install the catalog", jobName)
+	return false, r.client.CreateJob(construct.BuildCatalogInstallJob(&ocb))
+}
+
+// ensureMigration runs the Job migrating the catalog to the version of the resource and returns the state of the
+// migration. The StatefulSets must not be updated until it has succeeded. A migration which succeeded is not run again,
+// and a failed Job has to be deleted to be retried.
+func (r *OpenCGACommunityReconciler) ensureMigration(ctx context.Context, ocb opencgav1.OpenCGACommunity) (opencgav1.MigrationStatus, error) {
+	migration := opencgav1.MigrationStatus{
+		FromVersion: ocb.GetLastAppliedOpenCGAVersion(),
+		ToVersion:   ocb.GetOpenCGAVersion(),
+		JobName:     ocb.MigrationJobName(),
+	}
+	if previous := ocb.Status.Migration; previous != nil && previous.ToVersion == migration.ToVersion && previous.Phase == opencgav1.MigrationSucceeded {
+		return *previous, nil
+	}
+
+	jobName := types.NamespacedName{Name: migration.JobName, Namespace: ocb.Namespace}
+	existingJob, err := r.client.GetJob(jobName)
+	if err != nil && !apiErrors.IsNotFound(err) {
+		migration.Phase = opencgav1.MigrationFailed
+		return migration, err
+	}
+	if apiErrors.IsNotFound(err) {
+		if !ocb.IsMigrationApproved() {
+			migration.Phase = opencgav1.MigrationAwaitingApproval
+			return migration, nil
+		}
+		// the admin password is not generated here, it would not match the one the catalog was installed with
+		passwordRef := ocb.GetAdminPasswordSecretKeyRef()
+		if _, err := secret.ReadKey(r.client, passwordRef.Key, types.NamespacedName{Name: passwordRef.Name, Namespace: ocb.Namespace}); err != nil {
+			migration.Phase = opencgav1.MigrationFailed
+			return migration, errors.Errorf("could not read the admin password from secret %s: %s", passwordRef.Name, err)
+		}
+		r.log.Infof("Creating Job %s to migrate the catalog from version %s to %s", jobName, migration.FromVersion, migration.ToVersion)
+		migration.Phase = opencgav1.MigrationRunning
+		if err := r.client.CreateJob(construct.BuildMigrationJob(&ocb)); err != nil {
+			migration.Phase = opencgav1.MigrationFailed
+			return migration, err
+		}
+		return migration, nil
+	}
+
+	if migration.Logs, err = r.lastJobPod(ctx, jobName); err != nil {
+		r.log.Warnf("Could not find the Pods of Job %s: %s", jobName, err)
+	}
+	switch {
+	case job.IsComplete(existingJob):
+		migration.Phase = opencgav1.MigrationSucceeded
+	case job.IsFailed(existingJob):
+		migration.Phase = opencgav1.MigrationFailed
+		return migration, errors.Errorf("job %s failed, see the logs of %s and delete the Job to retry the migration", jobName, migration.Logs)
+	default:
+		migration.Phase = opencgav1.MigrationRunning
+	}
+	return migration, nil
+}
+
+// lastJobPod returns the most recently created Pod of the given Job in the form namespace/name,
+// or an empty string if it has none.
+func (r *OpenCGACommunityReconciler) lastJobPod(ctx context.Context, jobName types.NamespacedName) (string, error) {
+	pods := corev1.PodList{}
+	if err := r.client.List(ctx, &pods, client.InNamespace(jobName.Namespace), client.MatchingLabels{"job-name": jobName.Name}); err != nil {
+		return "", err
+	}
+	var last *corev1.Pod
+	for i := range pods.Items {
+		if last == nil || last.CreationTimestamp.Before(&pods.Items[i].CreationTimestamp) {
+			last = &pods.Items[i]
+		}
+	}
+	if last == nil {
+		return "", nil
+	}
+	return types.NamespacedName{Name: last.Name, Namespace: last.Namespace}.String(), nil
+}
+
+// ensureAdminPassword makes sure the Secret holding the password of the admin user exists, generating the password
+// if it does not.
+func (r *OpenCGACommunityReconciler) ensureAdminPassword(ocb opencgav1.OpenCGACommunity) error {
+	generatedPassword, err := generate.RandomFixedLengthStringOfSize(20)
+	if err != nil {
+		return errors.Errorf("could not generate the admin password: %s", err)
+	}
+
+	ref := ocb.GetAdminPasswordSecretKeyRef()
+	passwordSecretName := types.NamespacedName{Name: ref.Name, Namespace: ocb.Namespace}
+	password, err := secret.EnsureSecretWithKey(r.client, passwordSecretName, ocb.GetOwnerReferences(), ref.Key, generatedPassword)
+	if err != nil {
+		return errors.Errorf("could not ensure the admin password secret %s: %s", passwordSecretName, err)
+	}
+	if password == "" {
+		return errors.Errorf("secret %s does not contain a password in key %s", passwordSecretName, ref.Key)
+	}
+	return nil
+}
+
+// ensureAgentAPIKey makes sure the Secret holding the API key of the agents exists, and regenerates the key when
+// the rotation annotation of the resource changes. The value of the annotation last acted upon is recorded on the
+// Secret. The hash of the key is returned.
+func (r *OpenCGACommunityReconciler) ensureAgentAPIKey(ocb opencgav1.OpenCGACommunity) (string, error) {
+	secretName := ocb.GetAgentAPIKeySecretNamespacedName()
+	rotation := annotations.GetAnnotation(&ocb, opencgav1.AgentAPIKeyRotationAnnotation)
+
+	existing, err := r.client.GetSecret(secretName)
+	if err != nil && !secret.SecretNotExist(err) {
+		return "", errors.Errorf("could not read the agent API key secret %s: %s", secretName, err)
+	}
+	if err == nil && secret.HasAllKeys(existing, construct.AgentAPIKeySecretKey) &&
+		annotations.GetAnnotation(&existing, opencgav1.AgentAPIKeyRotationAnnotation) == rotation {
+		return agentAPIKeyHash(string(existing.Data[construct.AgentAPIKeySecretKey])), nil
+	}
+
+	if err == nil {
+		r.log.Infof("Rotating the agent API key stored in secret %s", secretName)
+	}
+	apiKey, err := generate.RandomFixedLengthStringOfSize(32)
+	if err != nil {
+		return "", errors.Errorf("could not generate the agent API key: %s", err)
+	}
+	apiKeySecret := secret.Builder().
+		SetName(secretName.Name).
+		SetNamespace(secretName.Namespace).
+		SetField(construct.AgentAPIKeySecretKey, apiKey).
+		SetAnnotations(map[string]string{opencgav1.AgentAPIKeyRotationAnnotation: rotation}).
+		SetOwnerReferences(ocb.GetOwnerReferences()).
+		Build()
+	if err := secret.CreateOrUpdate(r.client, apiKeySecret); err != nil {
+		return "", errors.Errorf("could not store the agent API key in secret %s: %s", secretName, err)
+	}
+	return agentAPIKeyHash(apiKey), nil
+}
+
+func agentAPIKeyHash(apiKey string) string {
+	return opencgaconfig.Hash(map[string]string{construct.AgentAPIKeySecretKey: apiKey})
+}
+
+// variantStorage returns the settings of the variant storage engine. The MongoDB engine uses the catalog
+// deployment and user unless other hosts are configured.
+func variantStorage(ocb opencgav1.OpenCGACommunity, catalog opencgaconfig.Catalog) opencgaconfig.VariantStorage {
+	variantStorage := opencgaconfig.VariantStorage{Engine: ocb.GetVariantStorageEngine()}
+	if hadoop := ocb.GetHadoopVariantStorage(); hadoop != nil {
+		variantStorage.HBaseNamespace = hadoop.HBaseNamespace
+		return variantStorage
+	}
+
+	variantStorage.Database = catalog
+	if mongodb := ocb.Spec.VariantStorage.MongoDB; mongodb != nil && len(mongodb.Hosts) > 0 {
+		variantStorage.Database.Hosts = mongodb.Hosts
+		variantStorage.Database.ReplicaSet = ""
+	}
+	return variantStorage
+}
+
+// createOrUpdateStatefulSets creates or updates the StatefulSets running the REST members and the master.
+func (r *OpenCGACommunityReconciler) createOrUpdateStatefulSets(ocb opencgav1.OpenCGACommunity, configHash, agentAPIKeyHash string) error {
+	restSts, err := construct.BuildOpenCGAReplicaSetStatefulSet(&ocb, ocb, configHash, agentAPIKeyHash).Build()
+	if err != nil {
+		return errors.Errorf("error building REST StatefulSet: %s", err)
+	}
+	if err := r.createOrUpdateStatefulSet(restSts); err != nil {
+		return errors.Errorf("error creating/updating REST StatefulSet: %s", err)
+	}
+
+	masterSts, err := construct.BuildOpenCGAMasterStatefulSet(&ocb, configHash).Build()
+	if err != nil {
+		return errors.Errorf("error building master StatefulSet: %s", err)
+	}
+	if err := r.createOrUpdateStatefulSet(masterSts); err != nil {
+		return errors.Errorf("error creating/updating master StatefulSet: %s", err)
+	}
+	return nil
+}
+
+// createOrUpdateStatefulSet merges the desired StatefulSet on top of the existing one, so that the
+// fields set by Kubernetes are kept, and then creates or updates it.
+func (r *OpenCGACommunityReconciler) createOrUpdateStatefulSet(desired appsv1.StatefulSet) error {
+	existing, err := r.client.GetStatefulSet(types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if apiErrors.IsNotFound(err) {
+		return r.client.CreateStatefulSet(desired)
+	}
+
+	_, err = r.client.UpdateStatefulSet(merge.StatefulSets(existing, desired))
+	return err
+}
+
+// statefulSetsAreReady returns true if both the REST and the master StatefulSets are ready.
+func (r *OpenCGACommunityReconciler) statefulSetsAreReady(ocb opencgav1.OpenCGACommunity) (bool, error) {
+	restSts, err := r.client.GetStatefulSet(ocb.NamespacedName())
+	if err != nil {
+		return false, err
+	}
+	masterSts, err := r.client.GetStatefulSet(types.NamespacedName{Name: ocb.MasterName(), Namespace: ocb.Namespace})
+	if err != nil {
+		return false, err
+	}
+
+	restIsReady := statefulset.IsReady(restSts, scale.ReplicasThisReconciliation(ocb))
+	masterIsReady := statefulset.IsReady(masterSts, 1)
+	r.log.Debugf("REST StatefulSet ready: %t, master StatefulSet ready: %t", restIsReady, masterIsReady)
+	return restIsReady && masterIsReady, nil
+}
+
+const (
+	// userPasswordSecretIndex indexes OpenCGACommunity resources by the names of the Secrets
+	// holding the passwords of their users.
+	userPasswordSecretIndex = ".spec.users.passwordSecretRef.name"
+	// tlsSecretIndex indexes OpenCGACommunity resources by the name of their TLS Secret.
+	tlsSecretIndex = ".spec.security.tls.certificateKeySecretRef.name"
+	// tlsCAConfigMapIndex indexes OpenCGACommunity resources by the name of their CA ConfigMap.
+	tlsCAConfigMapIndex = ".spec.security.tls.caConfigMapRef.name"
+	// ldapBindPasswordSecretIndex indexes OpenCGACommunity resources by the name of the Secret holding their
+	// LDAP bind password.
+	ldapBindPasswordSecretIndex = ".spec.security.ldap.bindPasswordSecretRef.name"
+	// oidcClientSecretIndex indexes OpenCGACommunity resources by the name of the Secret holding their OIDC
+	// client secret.
+	oidcClientSecretIndex = ".spec.security.oidc.clientSecretRef.name"
+)
+
+// userPasswordSecretNames returns the names of the password Secrets referenced by the
+// users of the given OpenCGACommunity, used as the values of userPasswordSecretIndex.
+func userPasswordSecretNames(obj client.Object) []string {
+	ocb, ok := obj.(*opencgav1.OpenCGACommunity)
+	if !ok {
+		return nil
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, user := range ocb.GetUsers() {
+		if user.PasswordSecretName == "" || seen[user.PasswordSecretName] {
+			continue
+		}
+		seen[user.PasswordSecretName] = true
+		names = append(names, user.PasswordSecretName)
+	}
+	return names
+}
+
+// tlsSecretNames returns the name of the TLS Secret of the given OpenCGACommunity, used as the value of tlsSecretIndex.
+func tlsSecretNames(obj client.Object) []string {
+	ocb, ok := obj.(*opencgav1.OpenCGACommunity)
+	if !ok || !ocb.IsTLSEnabled() {
+		return nil
+	}
+	return []string{ocb.Spec.Security.TLS.CertificateKeySecretRef.Name}
+}
+
+// tlsCAConfigMapNames returns the name of the CA ConfigMap of the given OpenCGACommunity, used as the value
+// of tlsCAConfigMapIndex.
+func tlsCAConfigMapNames(obj client.Object) []string {
+	ocb, ok := obj.(*opencgav1.OpenCGACommunity)
+	if !ok || !ocb.IsTLSEnabled() {
+		return nil
+	}
+	return []string{ocb.Spec.Security.TLS.CAConfigMapRef.Name}
+}
+
+// ldapBindPasswordSecretNames returns the name of the Secret holding the LDAP bind password of the given
+// OpenCGACommunity, used as the value of ldapBindPasswordSecretIndex.
+func ldapBindPasswordSecretNames(obj client.Object) []string {
+	ocb, ok := obj.(*opencgav1.OpenCGACommunity)
+	if !ok {
+		return nil
+	}
+	ref := ocb.GetLDAPBindPasswordSecretKeyRef()
+	if ref == nil {
+		return nil
+	}
+	return []string{ref.Name}
+}
+
+// oidcClientSecretNames returns the name of the Secret holding the OIDC client secret of the given OpenCGACommunity,
+// used as the value of oidcClientSecretIndex.
+func oidcClientSecretNames(obj client.Object) []string {
+	ocb, ok := obj.(*opencgav1.OpenCGACommunity)
+	if !ok {
+		return nil
+	}
+	ref := ocb.GetOIDCClientSecretSecretKeyRef()
+	if ref == nil {
+		return nil
+	}
+	return []string{ref.Name}
+}
+
+// findResourcesReferencing returns a MapFunc mapping an object to the OpenCGACommunity resources in its namespace
+// which reference it by name in any of the given indexes.
+func (r *OpenCGACommunityReconciler) findResourcesReferencing(indexes ...string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		seen := map[types.NamespacedName]bool{}
+		for _, index := range indexes {
+			resources := opencgav1.OpenCGACommunityList{}
+			listOpts := []client.ListOption{
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{index: obj.GetName()},
+			}
+			if err := r.client.List(context.TODO(), &resources, listOpts...); err != nil {
+				r.log.Errorf("Error listing resources referencing %s/%s: %s", obj.GetNamespace(), obj.GetName(), err)
+				continue
+			}
+			for _, ocb := range resources.Items {
+				if seen[ocb.NamespacedName()] {
+					continue
+				}
+				seen[ocb.NamespacedName()] = true
+				r.log.Debugf("%s changed, enqueuing %s", obj.GetName(), ocb.NamespacedName())
+				requests = append(requests, reconcile.Request{NamespacedName: ocb.NamespacedName()})
+			}
+		}
+		return requests
+	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *OpenCGACommunityReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexes := map[string]client.IndexerFunc{
+		userPasswordSecretIndex:     userPasswordSecretNames,
+		tlsSecretIndex:              tlsSecretNames,
+		tlsCAConfigMapIndex:         tlsCAConfigMapNames,
+		ldapBindPasswordSecretIndex: ldapBindPasswordSecretNames,
+		oidcClientSecretIndex:       oidcClientSecretNames,
+	}
+	for index, indexerFunc := range indexes {
+		if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &opencgav1.OpenCGACommunity{}, index, indexerFunc); err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&opencgav1.OpenCGACommunity{}).
+		For(&opencgav1.OpenCGACommunity{}, builder.WithPredicates(predicates.OnlyOnSpecChange())).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.findResourcesReferencing(userPasswordSecretIndex, tlsSecretIndex, ldapBindPasswordSecretIndex, oidcClientSecretIndex)),
+			builder.WithPredicates(predicates.OnlyOnSecretDataChange()),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.findResourcesReferencing(tlsCAConfigMapIndex)),
+			builder.WithPredicates(predicates.OnlyOnConfigMapDataChange()),
+		).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&batchv1.Job{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
 		Complete(r)
 }
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/controllers/construct"
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+)
+
+const (
+	// tlsCACertKey is the key of the CA ConfigMap holding the CA certificate.
+	tlsCACertKey = "ca.crt"
+)
+
+// tlsModeOrder lists the TLS modes from the least to the most strict.
+var tlsModeOrder = []automationconfig.TLSMode{
+	automationconfig.TLSModeDisabled,
+	automationconfig.TLSModeAllowed,
+	automationconfig.TLSModePreferred,
+	automationconfig.TLSModeRequired,
+}
+
+// tlsFiles are the paths, as mounted in the members, of the files the agent configures TLS with.
+type tlsFiles struct {
+	certificateKeyFile string
+	caFile             string
+}
+
+// ensureTLSResources reads the certificate of the members from the kubernetes.io/tls Secret and the CA certificate
+// from the ConfigMap referenced by the resource, and stores them in the Secret mounted in the members. The files are
+// keyed by the hash of their contents: a renewed certificate is written to a new file, and pointing the automation
+// config at it makes the agent reload it without restarting the Pods. The previous files are kept until the agents have
+// moved over to the new ones.
+func (r *OpenCGACommunityReconciler) ensureTLSResources(ocb opencgav1.OpenCGACommunity) (tlsFiles, error) {
+	if !ocb.IsTLSEnabled() {
+		return tlsFiles{}, nil
+	}
+
+	caConfigMapName := ocb.TLSCAConfigMapNamespacedName()
+	caConfigMap := corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), caConfigMapName, &caConfigMap); err != nil {
+		return tlsFiles{}, errors.Errorf("could not read the CA ConfigMap %s: %s", caConfigMapName, err)
+	}
+	ca, ok := caConfigMap.Data[tlsCACertKey]
+	if !ok || ca == "" {
+		return tlsFiles{}, errors.Errorf("CA ConfigMap %s has no %s key", caConfigMapName, tlsCACertKey)
+	}
+
+	certificateSecretName := ocb.TLSSecretNamespacedName()
+	certificateSecret, err := r.client.GetSecret(certificateSecretName)
+	if err != nil {
+		return tlsFiles{}, errors.Errorf("could not read the TLS Secret %s: %s", certificateSecretName, err)
+	}
+	if !secret.HasAllKeys(certificateSecret, corev1.TLSCertKey, corev1.TLSPrivateKeyKey) {
+		return tlsFiles{}, errors.Errorf("TLS Secret %s must have the %s and %s keys", certificateSecretName, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+	pem := combineCertificateAndKey(string(certificateSecret.Data[corev1.TLSCertKey]), string(certificateSecret.Data[corev1.TLSPrivateKeyKey]))
+
+	filesInUse, err := r.tlsFilesInUse(ocb)
+	if err != nil {
+		return tlsFiles{}, errors.Errorf("could not read the certificates in use: %s", err)
+	}
+
+	pemFileName := contentHash(pem) + ".pem"
+	caFileName := contentHash(ca) + ".crt"
+	operatorSecretBuilder := secret.Builder().
+		SetName(ocb.TLSOperatorSecretName()).
+		SetNamespace(ocb.Namespace).
+		SetOwnerReferences(ocb.GetOwnerReferences())
+	for name, contents := range filesInUse {
+		operatorSecretBuilder.SetField(name, contents)
+	}
+	operatorSecret := operatorSecretBuilder.
+		SetField(pemFileName, pem).
+		SetField(caFileName, ca).
+		Build()
+	if err := secret.CreateOrUpdate(r.client, operatorSecret); err != nil {
+		return tlsFiles{}, errors.Errorf("could not store the certificates in secret %s: %s", ocb.TLSOperatorSecretName(), err)
+	}
+
+	return tlsFiles{
+		certificateKeyFile: path.Join(construct.TLSOperatorSecretMountPath, pemFileName),
+		caFile:             path.Join(construct.TLSOperatorSecretMountPath, caFileName),
+	}, nil
+}
+
+// tlsFilesInUse returns the files of the operator Secret the agents may still be configured with: the ones the current
+// automation config points at and, until the agents have reached its goal state, every file of the Secret, as the
+// previous automation config may point at any of them. The other files are pruned.
+func (r *OpenCGACommunityReconciler) tlsFilesInUse(ocb opencgav1.OpenCGACommunity) (map[string]string, error) {
+	operatorSecret, err := r.client.GetSecret(types.NamespacedName{Name: ocb.TLSOperatorSecretName(), Namespace: ocb.Namespace})
+	if err != nil {
+		if secret.SecretNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	currentAC, err := automationconfig.ReadFromSecret(r.client, types.NamespacedName{Name: ocb.AutomationConfigSecretName(), Namespace: ocb.Namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	agentsReachedGoal := r.agentsReachedVersion(ocb, currentAC.Version)
+	referenced := referencedTLSFiles(currentAC)
+	filesInUse := map[string]string{}
+	for name, contents := range operatorSecret.Data {
+		if !agentsReachedGoal || referenced[name] {
+			filesInUse[name] = string(contents)
+		}
+	}
+	return filesInUse, nil
+}
+
+// referencedTLSFiles returns the names of the operator Secret files the given automation config points at.
+func referencedTLSFiles(ac automationconfig.AutomationConfig) map[string]bool {
+	referenced := map[string]bool{}
+	for _, process := range ac.Processes {
+		if process.OpenCGA.Rest.TLS != nil {
+			referenced[path.Base(process.OpenCGA.Rest.TLS.CertificateKeyFile)] = true
+		}
+	}
+	if ac.TLSConfig != nil && ac.TLSConfig.CAFilePath != "" {
+		referenced[path.Base(ac.TLSConfig.CAFilePath)] = true
+	}
+	return referenced
+}
+
+// combineCertificateAndKey returns the PEM file the agent expects: the certificate followed by its private key.
+func combineCertificateAndKey(cert, key string) string {
+	return strings.TrimSuffix(cert, "\n") + "\n" + strings.TrimSuffix(key, "\n") + "\n"
+}
+
+func contentHash(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// currentTLSMode returns the TLS mode of the members in the given automation config.
+func currentTLSMode(ac automationconfig.AutomationConfig) automationconfig.TLSMode {
+	if len(ac.Processes) == 0 {
+		return automationconfig.TLSModeDisabled
+	}
+	return ac.Processes[0].OpenCGA.Rest.GetTLSMode()
+}
+
+// nextTLSMode returns the TLS mode of the members for this reconciliation. TLS is enabled one mode at a time,
+// from allowTLS to preferTLS to requireTLS, moving on only once the agents have reached the goal state of
+// the current automation config, so that clients can switch to TLS before it is required. Members which
+// were never deployed, and less strict modes, are configured at once.
+func nextTLSMode(current, desired automationconfig.TLSMode, isNewDeployment, agentsReachedGoal bool) automationconfig.TLSMode {
+	currentIndex, desiredIndex := tlsModeIndex(current), tlsModeIndex(desired)
+	if isNewDeployment || desiredIndex <= currentIndex {
+		return desired
+	}
+	if !agentsReachedGoal {
+		return current
+	}
+	return tlsModeOrder[currentIndex+1]
+}
+
+func tlsModeIndex(mode automationconfig.TLSMode) int {
+	for i, m := range tlsModeOrder {
+		if m == mode {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,476 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+	"github.com/phamidko/opencga-operator/pkg/authentication/scramcredentials"
+	"github.com/phamidko/opencga-operator/pkg/authentication/x509"
+	"github.com/phamidko/opencga-operator/pkg/opencgaconfig"
+	"github.com/phamidko/opencga-operator/pkg/util/contains"
+)
+
+// ValidateSpec checks the resource for invalid settings which can't be expressed in the CRD schema.
+// All the problems found are returned.
+func ValidateSpec(ocb opencgav1.OpenCGACommunity) error {
+	var errs error
+	if err := validateCatalog(ocb.Spec.Catalog); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if ocb.Spec.Search != nil {
+		if err := validateSearch(*ocb.Spec.Search); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := validateVariantStorage(ocb.Spec.VariantStorage); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if maxUnavailable := ocb.Spec.PodDisruptionBudget.MaxUnavailable; maxUnavailable != nil {
+		if value, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, 100, false); err != nil || value < 0 {
+			errs = multierror.Append(errs, fmt.Errorf("spec.podDisruptionBudget.maxUnavailable: %q is not a non-negative number or a percentage", maxUnavailable.String()))
+		}
+	}
+	if interval := ocb.Spec.Agent.CredentialsRotationInterval; interval != nil && interval.Duration <= 0 {
+		errs = multierror.Append(errs, fmt.Errorf("spec.agent.credentialsRotationInterval: %q must be a positive duration", interval.Duration))
+	}
+	if interval := ocb.Spec.Agent.ReadinessMetricsInterval; interval != nil {
+		if interval.Duration <= 0 {
+			errs = multierror.Append(errs, fmt.Errorf("spec.agent.readinessMetricsInterval: %q must be a positive duration", interval.Duration))
+		} else if !ocb.Spec.Agent.LocalReadinessProbe {
+			errs = multierror.Append(errs, fmt.Errorf("spec.agent.readinessMetricsInterval requires spec.agent.localReadinessProbe"))
+		}
+	}
+	if ocb.Spec.Ingress != nil {
+		if err := validateIngress(*ocb.Spec.Ingress); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := validateUsers(ocb); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if err := validateSecurity(ocb); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if err := validateTLS(ocb.Spec.Security); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if ocb.Spec.Security.LDAP != nil {
+		if err := validateLDAP(*ocb.Spec.Security.LDAP); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if ocb.Spec.Security.OIDC != nil {
+		if err := validateOIDC(*ocb.Spec.Security.OIDC); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if ocb.Spec.Autoscaling != nil {
+		if err := validateAutoscaling(*ocb.Spec.Autoscaling); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+func validateCatalog(catalog opencgav1.CatalogSpec) error {
+	var errs error
+	hasHosts := len(catalog.Hosts) > 0
+	hasConnectionString := catalog.ConnectionStringSecretRef != nil
+
+	if !hasHosts && !hasConnectionString {
+		errs = multierror.Append(errs, errors.New("one of spec.catalog.hosts and spec.catalog.connectionStringSecretRef must be specified"))
+	}
+	if hasHosts && hasConnectionString {
+		errs = multierror.Append(errs, errors.New("spec.catalog.hosts and spec.catalog.connectionStringSecretRef are mutually exclusive"))
+	}
+	for _, host := range catalog.Hosts {
+		if err := opencgaconfig.ValidateHost(host); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("spec.catalog.hosts: %s", err))
+		}
+	}
+	if hasConnectionString && catalog.ConnectionStringSecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.catalog.connectionStringSecretRef.name must be specified"))
+	}
+
+	if catalog.TLS.CaCertificateSecretRef != nil {
+		if !catalog.TLS.Enabled {
+			errs = multierror.Append(errs, errors.New("spec.catalog.tls.caCertificateSecretRef requires spec.catalog.tls.enabled"))
+		}
+		if catalog.TLS.CaCertificateSecretRef.Name == "" {
+			errs = multierror.Append(errs, errors.New("spec.catalog.tls.caCertificateSecretRef.name must be specified"))
+		}
+	}
+	if catalog.TLS.ClientCertificateSecretRef != nil {
+		if !catalog.TLS.Enabled {
+			errs = multierror.Append(errs, errors.New("spec.catalog.tls.clientCertificateSecretRef requires spec.catalog.tls.enabled"))
+		}
+		if catalog.TLS.ClientCertificateSecretRef.Name == "" {
+			errs = multierror.Append(errs, errors.New("spec.catalog.tls.clientCertificateSecretRef.name must be specified"))
+		}
+	}
+	return errs
+}
+
+func validateSearch(search opencgav1.SearchSpec) error {
+	var errs error
+	hasHosts := len(search.Hosts) > 0
+	hasZooKeeperHosts := len(search.ZooKeeperHosts) > 0
+
+	if !hasHosts && !hasZooKeeperHosts {
+		errs = multierror.Append(errs, errors.New("one of spec.search.hosts and spec.search.zookeeperHosts must be specified"))
+	}
+	if hasHosts && hasZooKeeperHosts {
+		errs = multierror.Append(errs, errors.New("spec.search.hosts and spec.search.zookeeperHosts are mutually exclusive"))
+	}
+	for _, host := range search.Hosts {
+		if u, err := url.Parse(host); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = multierror.Append(errs, fmt.Errorf("spec.search.hosts: %q is not an http or https URL", host))
+		}
+	}
+	for _, host := range search.ZooKeeperHosts {
+		if err := opencgaconfig.ValidateHost(host); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("spec.search.zookeeperHosts: %s", err))
+		}
+	}
+	if hasZooKeeperHosts && search.Mode == opencgaconfig.SearchModeCore {
+		errs = multierror.Append(errs, errors.New("spec.search.zookeeperHosts can't be used with the core mode"))
+	}
+	if search.CredentialsSecretRef != nil && search.CredentialsSecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.search.credentialsSecretRef.name must be specified"))
+	}
+	return errs
+}
+
+func validateVariantStorage(variantStorage opencgav1.VariantStorageSpec) error {
+	var errs error
+	isHadoop := variantStorage.Engine == opencgaconfig.VariantStorageEngineHadoop
+
+	if isHadoop {
+		if variantStorage.Hadoop == nil {
+			errs = multierror.Append(errs, errors.New("spec.variantStorage.hadoop must be specified for the hadoop engine"))
+		} else {
+			if variantStorage.Hadoop.HadoopConfigMapRef.Name == "" {
+				errs = multierror.Append(errs, errors.New("spec.variantStorage.hadoop.hadoopConfigMapRef.name must be specified"))
+			}
+			if variantStorage.Hadoop.HBaseConfigMapRef.Name == "" {
+				errs = multierror.Append(errs, errors.New("spec.variantStorage.hadoop.hbaseConfigMapRef.name must be specified"))
+			}
+		}
+		if variantStorage.MongoDB != nil {
+			errs = multierror.Append(errs, errors.New("spec.variantStorage.mongodb can't be used with the hadoop engine"))
+		}
+	} else if variantStorage.Hadoop != nil {
+		errs = multierror.Append(errs, errors.New("spec.variantStorage.hadoop can only be used with the hadoop engine"))
+	}
+
+	if variantStorage.MongoDB != nil {
+		for _, host := range variantStorage.MongoDB.Hosts {
+			if err := opencgaconfig.ValidateHost(host); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("spec.variantStorage.mongodb.hosts: %s", err))
+			}
+		}
+	}
+	return errs
+}
+
+func validateIngress(ingress opencgav1.IngressSpec) error {
+	var errs error
+	if ingress.Host == "" {
+		errs = multierror.Append(errs, errors.New("spec.ingress.host must be specified"))
+	}
+	if strings.ContainsAny(ingress.Host, ":/") {
+		errs = multierror.Append(errs, fmt.Errorf("spec.ingress.host: %q must be a host name without scheme, port or path", ingress.Host))
+	}
+	if ingress.Path != "" && !strings.HasPrefix(ingress.Path, "/") {
+		errs = multierror.Append(errs, fmt.Errorf("spec.ingress.path: %q must start with /", ingress.Path))
+	}
+	return errs
+}
+
+func validateUsers(ocb opencgav1.OpenCGACommunity) error {
+	var errs error
+	names := map[string]bool{}
+	connectionSecretNames := map[string]bool{}
+	for i, user := range ocb.Spec.Users {
+		if user.Name == "" {
+			errs = multierror.Append(errs, fmt.Errorf("spec.users[%d].name must be specified", i))
+		}
+		if user.PasswordSecretRef.Name == "" {
+			errs = multierror.Append(errs, fmt.Errorf("spec.users[%d].passwordSecretRef.name must be specified", i))
+		}
+		if names[user.Name] {
+			errs = multierror.Append(errs, fmt.Errorf("spec.users[%d].name: user %q is declared more than once", i, user.Name))
+		}
+		names[user.Name] = true
+		if err := validateScramIterations(fmt.Sprintf("spec.users[%d].scramSha1Iterations", i), user.ScramSha1Iterations); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		if err := validateScramIterations(fmt.Sprintf("spec.users[%d].scramSha256Iterations", i), user.ScramSha256Iterations); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := validateScramIterations("spec.security.scram.sha1Iterations", ocb.Spec.Security.Scram.Sha1Iterations); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if err := validateScramIterations("spec.security.scram.sha256Iterations", ocb.Spec.Security.Scram.Sha256Iterations); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	for _, user := range ocb.GetUsers() {
+		name := user.GetConnectionStringSecretName(ocb.NamespacedName())
+		if connectionSecretNames[name] {
+			errs = multierror.Append(errs, fmt.Errorf("spec.users: connection secret %q is used by more than one user", name))
+		}
+		connectionSecretNames[name] = true
+	}
+	return errs
+}
+
+// validateScramIterations returns an error if an iteration count is set lower than the one MongoDB accepts.
+func validateScramIterations(path string, iterations int) error {
+	if iterations != 0 && iterations < scramcredentials.MinScramIterations {
+		return fmt.Errorf("%s: %d is lower than the minimum of %d iterations", path, iterations, scramcredentials.MinScramIterations)
+	}
+	return nil
+}
+
+// builtinRoles are the roles which can be granted or inherited without being defined in spec.security.roles.
+var builtinRoles = map[string]bool{
+	"read":                 true,
+	"readWrite":            true,
+	"dbAdmin":              true,
+	"dbOwner":              true,
+	"userAdmin":            true,
+	"clusterAdmin":         true,
+	"clusterManager":       true,
+	"clusterMonitor":       true,
+	"hostManager":          true,
+	"backup":               true,
+	"restore":              true,
+	"readAnyDatabase":      true,
+	"readWriteAnyDatabase": true,
+	"userAdminAnyDatabase": true,
+	"dbAdminAnyDatabase":   true,
+	"root":                 true,
+}
+
+// validateSecurity checks the custom roles, and that every role granted or inherited is either built-in or custom.
+func validateSecurity(ocb opencgav1.OpenCGACommunity) error {
+	var errs error
+	customRoles := map[opencgav1.RoleReference]bool{}
+	for i, role := range ocb.Spec.Security.Roles {
+		path := fmt.Sprintf("spec.security.roles[%d]", i)
+		if role.Name == "" {
+			errs = multierror.Append(errs, fmt.Errorf("%s.role must be specified", path))
+		}
+		if role.DB == "" {
+			errs = multierror.Append(errs, fmt.Errorf("%s.db must be specified", path))
+		}
+		ref := opencgav1.RoleReference{Name: role.Name, DB: role.DB}
+		if customRoles[ref] {
+			errs = multierror.Append(errs, fmt.Errorf("%s: role %s@%s is declared more than once", path, role.Name, role.DB))
+		}
+		customRoles[ref] = true
+	}
+
+	isKnown := func(ref opencgav1.RoleReference) bool {
+		return customRoles[ref] || builtinRoles[ref.Name]
+	}
+	for i, role := range ocb.Spec.Security.Roles {
+		path := fmt.Sprintf("spec.security.roles[%d]", i)
+		if len(role.Privileges) == 0 && len(role.Roles) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("%s: role %s@%s must have privileges or inherit roles", path, role.Name, role.DB))
+		}
+		for j, privilege := range role.Privileges {
+			if err := validatePrivilege(fmt.Sprintf("%s.privileges[%d]", path, j), privilege); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+		for j, inherited := range role.Roles {
+			if !isKnown(inherited) {
+				errs = multierror.Append(errs, fmt.Errorf("%s.roles[%d]: unknown inherited role %s@%s", path, j, inherited.Name, inherited.DB))
+			}
+		}
+		for j, restriction := range role.AuthenticationRestrictions {
+			if err := validateAuthenticationRestriction(fmt.Sprintf("%s.authenticationRestrictions[%d]", path, j), restriction); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	for i, user := range ocb.Spec.Users {
+		for j, role := range user.Roles {
+			if !isKnown(role) {
+				errs = multierror.Append(errs, fmt.Errorf("spec.users[%d].roles[%d]: unknown role %s@%s", i, j, role.Name, role.DB))
+			}
+		}
+	}
+
+	subjects := map[string]bool{}
+	for i, user := range ocb.Spec.Security.X509Users {
+		path := fmt.Sprintf("spec.security.x509Users[%d]", i)
+		if err := x509.ValidateSubject(user.Subject); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s.subject: %s", path, err))
+		}
+		if subjects[user.Subject] {
+			errs = multierror.Append(errs, fmt.Errorf("%s: subject %q is mapped more than once", path, user.Subject))
+		}
+		subjects[user.Subject] = true
+		for j, role := range user.Roles {
+			if !isKnown(role) {
+				errs = multierror.Append(errs, fmt.Errorf("%s.roles[%d]: unknown role %s@%s", path, j, role.Name, role.DB))
+			}
+		}
+	}
+	return errs
+}
+
+func validateLDAP(ldap opencgav1.LDAPSpec) error {
+	var errs error
+	if len(ldap.Servers) == 0 {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.servers must be specified"))
+	}
+	for _, server := range ldap.Servers {
+		if err := opencgaconfig.ValidateLDAPServer(server); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("spec.security.ldap.servers: %s", err))
+		}
+	}
+	if ldap.UserSearchBase == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.userSearchBase must be specified"))
+	}
+	if ldap.GroupSearchBase == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.groupSearchBase must be specified"))
+	}
+	hasBindPassword := ldap.BindPasswordSecretRef != nil
+	if ldap.BindDN != "" && !hasBindPassword {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.bindDN requires spec.security.ldap.bindPasswordSecretRef"))
+	}
+	if ldap.BindDN == "" && hasBindPassword {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.bindPasswordSecretRef requires spec.security.ldap.bindDN"))
+	}
+	if hasBindPassword && ldap.BindPasswordSecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.bindPasswordSecretRef.name must be specified"))
+	}
+	if ldap.CaCertificateSecretRef != nil && ldap.CaCertificateSecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.ldap.caCertificateSecretRef.name must be specified"))
+	}
+	return errs
+}
+
+func validateOIDC(oidc opencgav1.OIDCSpec) error {
+	var errs error
+	if err := opencgaconfig.ValidateOIDCIssuer(oidc.Issuer); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("spec.security.oidc.issuer: %s", err))
+	}
+	if oidc.ClientID == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.oidc.clientId must be specified"))
+	}
+	if oidc.ClientSecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.oidc.clientSecretRef.name must be specified"))
+	}
+	if len(oidc.Scopes) > 0 && !contains.String(oidc.Scopes, "openid") {
+		errs = multierror.Append(errs, errors.New("spec.security.oidc.scopes must include openid"))
+	}
+	return errs
+}
+
+func validateTLS(security opencgav1.SecuritySpec) error {
+	var errs error
+	tls := security.TLS
+	if !tls.Enabled {
+		if tls.Optional {
+			errs = multierror.Append(errs, errors.New("spec.security.tls.optional requires spec.security.tls.enabled"))
+		}
+		if tls.RequireClientCertificates {
+			errs = multierror.Append(errs, errors.New("spec.security.tls.requireClientCertificates requires spec.security.tls.enabled"))
+		}
+		if len(security.X509Users) > 0 {
+			errs = multierror.Append(errs, errors.New("spec.security.x509Users requires spec.security.tls.enabled"))
+		}
+		return errs
+	}
+	if tls.RequireClientCertificates && tls.Optional {
+		errs = multierror.Append(errs, errors.New("spec.security.tls.requireClientCertificates can't be used with spec.security.tls.optional, connections without TLS have no client certificate"))
+	}
+	if tls.CertificateKeySecretRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.tls.certificateKeySecretRef.name must be specified"))
+	}
+	if tls.CAConfigMapRef.Name == "" {
+		errs = multierror.Append(errs, errors.New("spec.security.tls.caConfigMapRef.name must be specified"))
+	}
+	return errs
+}
+
+func validatePrivilege(path string, privilege opencgav1.Privilege) error {
+	var errs error
+	if len(privilege.Actions) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("%s.actions must not be empty", path))
+	}
+	for i, action := range privilege.Actions {
+		if action == "" {
+			errs = multierror.Append(errs, fmt.Errorf("%s.actions[%d] must not be empty", path, i))
+		}
+	}
+
+	resource := privilege.Resource
+	scopes := 0
+	if resource.DB != nil || resource.Collection != nil {
+		scopes++
+	}
+	if resource.Cluster {
+		scopes++
+	}
+	if resource.AnyResource {
+		scopes++
+	}
+	if scopes != 1 {
+		errs = multierror.Append(errs, fmt.Errorf("%s.resource must select exactly one of a database and collection, the cluster or any resource", path))
+	}
+	return errs
+}
+
+func validateAuthenticationRestriction(path string, restriction opencgav1.AuthenticationRestriction) error {
+	var errs error
+	if len(restriction.ClientSource) == 0 && len(restriction.ServerAddress) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("%s: one of clientSource and serverAddress must be specified", path))
+	}
+	for i, address := range restriction.ClientSource {
+		if !isIPOrCIDR(address) {
+			errs = multierror.Append(errs, fmt.Errorf("%s.clientSource[%d]: %q is not an IP address or a CIDR range", path, i, address))
+		}
+	}
+	for i, address := range restriction.ServerAddress {
+		if !isIPOrCIDR(address) {
+			errs = multierror.Append(errs, fmt.Errorf("%s.serverAddress[%d]: %q is not an IP address or a CIDR range", path, i, address))
+		}
+	}
+	return errs
+}
+
+func isIPOrCIDR(address string) bool {
+	if net.ParseIP(address) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(address)
+	return err == nil
+}
+
+func validateAutoscaling(autoscaling opencgav1.AutoscalingSpec) error {
+	var errs error
+	if autoscaling.MaxMembers < 1 {
+		errs = multierror.Append(errs, errors.New("spec.autoscaling.maxMembers must be at least 1"))
+	}
+	if autoscaling.MinMembers != nil && *autoscaling.MinMembers > autoscaling.MaxMembers {
+		errs = multierror.Append(errs, fmt.Errorf("spec.autoscaling.minMembers: %d is greater than spec.autoscaling.maxMembers", *autoscaling.MinMembers))
+	}
+	if autoscaling.RequestRate != nil && autoscaling.RequestRate.TargetAverageValue.Sign() <= 0 {
+		errs = multierror.Append(errs, fmt.Errorf("spec.autoscaling.requestRate.targetAverageValue: %q must be positive", autoscaling.RequestRate.TargetAverageValue.String()))
+	}
+	return errs
+}
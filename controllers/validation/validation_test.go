@@ -0,0 +1,638 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	opencgav1 "github.com/phamidko/opencga-operator/api/v1"
+)
+
+func newResource(catalog opencgav1.CatalogSpec) opencgav1.OpenCGACommunity {
+	return opencgav1.OpenCGACommunity{
+		Spec: opencgav1.OpenCGACommunitySpec{
+			Members: 3,
+			Type:    opencgav1.ReplicaSet,
+			Version: "2.2.0",
+			Catalog: catalog,
+		},
+	}
+}
+
+func TestValidateSpec_Catalog(t *testing.T) {
+	connectionStringRef := &opencgav1.SecretKeyReference{Name: "catalog-connection"}
+	caRef := &opencgav1.SecretKeyReference{Name: "catalog-ca"}
+
+	tests := []struct {
+		name    string
+		catalog opencgav1.CatalogSpec
+		valid   bool
+	}{
+		{
+			name:    "Hosts",
+			catalog: opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017", "mongo-1:27017"}},
+			valid:   true,
+		},
+		{
+			name:    "Connection string",
+			catalog: opencgav1.CatalogSpec{ConnectionStringSecretRef: connectionStringRef},
+			valid:   true,
+		},
+		{
+			name: "TLS with a CA",
+			catalog: opencgav1.CatalogSpec{
+				Hosts: []string{"mongo-0:27017"},
+				TLS:   opencgav1.CatalogTLSSpec{Enabled: true, CaCertificateSecretRef: caRef},
+			},
+			valid: true,
+		},
+		{
+			name: "TLS with a client certificate",
+			catalog: opencgav1.CatalogSpec{
+				Hosts: []string{"mongo-0:27017"},
+				TLS:   opencgav1.CatalogTLSSpec{Enabled: true, ClientCertificateSecretRef: &corev1.LocalObjectReference{Name: "catalog-client"}},
+			},
+			valid: true,
+		},
+		{
+			name:    "Neither hosts nor connection string",
+			catalog: opencgav1.CatalogSpec{},
+		},
+		{
+			name:    "Both hosts and connection string",
+			catalog: opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}, ConnectionStringSecretRef: connectionStringRef},
+		},
+		{
+			name:    "Host without a port",
+			catalog: opencgav1.CatalogSpec{Hosts: []string{"mongo-0"}},
+		},
+		{
+			name:    "Connection string without a secret name",
+			catalog: opencgav1.CatalogSpec{ConnectionStringSecretRef: &opencgav1.SecretKeyReference{Key: "uri"}},
+		},
+		{
+			name: "CA without TLS",
+			catalog: opencgav1.CatalogSpec{
+				Hosts: []string{"mongo-0:27017"},
+				TLS:   opencgav1.CatalogTLSSpec{CaCertificateSecretRef: caRef},
+			},
+		},
+		{
+			name: "Client certificate without TLS",
+			catalog: opencgav1.CatalogSpec{
+				Hosts: []string{"mongo-0:27017"},
+				TLS:   opencgav1.CatalogTLSSpec{ClientCertificateSecretRef: &corev1.LocalObjectReference{Name: "catalog-client"}},
+			},
+		},
+		{
+			name: "Client certificate without a secret name",
+			catalog: opencgav1.CatalogSpec{
+				Hosts: []string{"mongo-0:27017"},
+				TLS:   opencgav1.CatalogTLSSpec{Enabled: true, ClientCertificateSecretRef: &corev1.LocalObjectReference{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSpec(newResource(tt.catalog))
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_Search(t *testing.T) {
+	tests := []struct {
+		name   string
+		search opencgav1.SearchSpec
+		valid  bool
+	}{
+		{
+			name:   "Solr nodes",
+			search: opencgav1.SearchSpec{Hosts: []string{"http://solr-0.solr:8983/solr", "https://solr-1.solr:8983/solr"}},
+			valid:  true,
+		},
+		{
+			name:   "ZooKeeper ensemble",
+			search: opencgav1.SearchSpec{ZooKeeperHosts: []string{"zk-0:2181", "zk-1:2181"}},
+			valid:  true,
+		},
+		{
+			name:   "Neither Solr nodes nor ZooKeeper ensemble",
+			search: opencgav1.SearchSpec{},
+		},
+		{
+			name:   "Both Solr nodes and ZooKeeper ensemble",
+			search: opencgav1.SearchSpec{Hosts: []string{"http://solr:8983/solr"}, ZooKeeperHosts: []string{"zk-0:2181"}},
+		},
+		{
+			name:   "Solr node without scheme",
+			search: opencgav1.SearchSpec{Hosts: []string{"solr:8983"}},
+		},
+		{
+			name:   "ZooKeeper member without port",
+			search: opencgav1.SearchSpec{ZooKeeperHosts: []string{"zk-0"}},
+		},
+		{
+			name:   "ZooKeeper ensemble in core mode",
+			search: opencgav1.SearchSpec{ZooKeeperHosts: []string{"zk-0:2181"}, Mode: "core"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Search = &tt.search
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_VariantStorage(t *testing.T) {
+	hadoop := &opencgav1.HadoopVariantStorageSpec{
+		HadoopConfigMapRef: corev1.LocalObjectReference{Name: "hadoop-conf"},
+		HBaseConfigMapRef:  corev1.LocalObjectReference{Name: "hbase-conf"},
+	}
+
+	tests := []struct {
+		name           string
+		variantStorage opencgav1.VariantStorageSpec
+		valid          bool
+	}{
+		{
+			name:           "Default engine",
+			variantStorage: opencgav1.VariantStorageSpec{},
+			valid:          true,
+		},
+		{
+			name:           "MongoDB engine with hosts",
+			variantStorage: opencgav1.VariantStorageSpec{Engine: "mongodb", MongoDB: &opencgav1.MongoDBVariantStorageSpec{Hosts: []string{"mongo-0:27017"}}},
+			valid:          true,
+		},
+		{
+			name:           "Hadoop engine",
+			variantStorage: opencgav1.VariantStorageSpec{Engine: "hadoop", Hadoop: hadoop},
+			valid:          true,
+		},
+		{
+			name:           "Hadoop engine without settings",
+			variantStorage: opencgav1.VariantStorageSpec{Engine: "hadoop"},
+		},
+		{
+			name: "Hadoop engine without HBase configuration",
+			variantStorage: opencgav1.VariantStorageSpec{Engine: "hadoop", Hadoop: &opencgav1.HadoopVariantStorageSpec{
+				HadoopConfigMapRef: corev1.LocalObjectReference{Name: "hadoop-conf"},
+			}},
+		},
+		{
+			name:           "Hadoop settings with the MongoDB engine",
+			variantStorage: opencgav1.VariantStorageSpec{Hadoop: hadoop},
+		},
+		{
+			name:           "MongoDB host without port",
+			variantStorage: opencgav1.VariantStorageSpec{MongoDB: &opencgav1.MongoDBVariantStorageSpec{Hosts: []string{"mongo-0"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.VariantStorage = tt.variantStorage
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_Ingress(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress opencgav1.IngressSpec
+		valid   bool
+	}{
+		{
+			name:    "Host",
+			ingress: opencgav1.IngressSpec{Host: "opencga.example.com"},
+			valid:   true,
+		},
+		{
+			name:    "Host and path",
+			ingress: opencgav1.IngressSpec{Host: "opencga.example.com", Path: "/opencga"},
+			valid:   true,
+		},
+		{
+			name:    "No host",
+			ingress: opencgav1.IngressSpec{},
+		},
+		{
+			name:    "Host with scheme",
+			ingress: opencgav1.IngressSpec{Host: "https://opencga.example.com"},
+		},
+		{
+			name:    "Relative path",
+			ingress: opencgav1.IngressSpec{Host: "opencga.example.com", Path: "opencga"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Ingress = &tt.ingress
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_PodDisruptionBudget(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxUnavailable intstr.IntOrString
+		valid          bool
+	}{
+		{name: "Number", maxUnavailable: intstr.FromInt(1), valid: true},
+		{name: "Percentage", maxUnavailable: intstr.FromString("50%"), valid: true},
+		{name: "Negative number", maxUnavailable: intstr.FromInt(-1)},
+		{name: "Negative percentage", maxUnavailable: intstr.FromString("-10%")},
+		{name: "Not a percentage", maxUnavailable: intstr.FromString("half")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.PodDisruptionBudget.MaxUnavailable = &tt.maxUnavailable
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_AgentCredentialsRotationInterval(t *testing.T) {
+	ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+	ocb.Spec.Agent.CredentialsRotationInterval = &metav1.Duration{Duration: 720 * time.Hour}
+	assert.NoError(t, ValidateSpec(ocb))
+
+	ocb.Spec.Agent.CredentialsRotationInterval = &metav1.Duration{}
+	assert.Error(t, ValidateSpec(ocb))
+}
+
+func TestValidateSpec_AgentReadinessMetricsInterval(t *testing.T) {
+	ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+	ocb.Spec.Agent.ReadinessMetricsInterval = &metav1.Duration{Duration: 30 * time.Second}
+	assert.Error(t, ValidateSpec(ocb), "the metrics are served by the sidecar of the local readiness probe")
+
+	ocb.Spec.Agent.LocalReadinessProbe = true
+	assert.NoError(t, ValidateSpec(ocb))
+
+	ocb.Spec.Agent.ReadinessMetricsInterval = &metav1.Duration{}
+	assert.Error(t, ValidateSpec(ocb))
+}
+
+func TestValidateSpec_Autoscaling(t *testing.T) {
+	tests := []struct {
+		name        string
+		autoscaling opencgav1.AutoscalingSpec
+		valid       bool
+	}{
+		{name: "Maximum only", autoscaling: opencgav1.AutoscalingSpec{MaxMembers: 5}, valid: true},
+		{name: "Minimum below maximum", autoscaling: opencgav1.AutoscalingSpec{MinMembers: int32Ref(2), MaxMembers: 5}, valid: true},
+		{name: "Minimum above maximum", autoscaling: opencgav1.AutoscalingSpec{MinMembers: int32Ref(6), MaxMembers: 5}},
+		{name: "No maximum", autoscaling: opencgav1.AutoscalingSpec{}},
+		{
+			name: "Request rate",
+			autoscaling: opencgav1.AutoscalingSpec{
+				MaxMembers:  5,
+				RequestRate: &opencgav1.RequestRateMetricSpec{TargetAverageValue: resource.MustParse("50")},
+			},
+			valid: true,
+		},
+		{
+			name: "Zero request rate",
+			autoscaling: opencgav1.AutoscalingSpec{
+				MaxMembers:  5,
+				RequestRate: &opencgav1.RequestRateMetricSpec{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Autoscaling = &tt.autoscaling
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func int32Ref(i int32) *int32 {
+	return &i
+}
+
+func TestValidateSpec_Security(t *testing.T) {
+	catalog := "opencga_catalog"
+	readCatalog := opencgav1.Privilege{Resource: opencgav1.Resource{DB: &catalog}, Actions: []string{"find"}}
+	analyst := func(mod func(*opencgav1.CustomRole)) opencgav1.CustomRole {
+		role := opencgav1.CustomRole{Name: "analyst", DB: "admin", Privileges: []opencgav1.Privilege{readCatalog}}
+		mod(&role)
+		return role
+	}
+	noop := func(*opencgav1.CustomRole) {}
+	tests := []struct {
+		name  string
+		roles []opencgav1.CustomRole
+		users []opencgav1.UserSpec
+		valid bool
+	}{
+		{name: "Custom role", roles: []opencgav1.CustomRole{analyst(noop)}, valid: true},
+		{
+			name: "Inherited built-in and custom roles",
+			roles: []opencgav1.CustomRole{
+				analyst(noop),
+				{Name: "lead", DB: "admin", Roles: []opencgav1.RoleReference{{Name: "analyst", DB: "admin"}, {Name: "read", DB: "opencga_users"}}},
+			},
+			valid: true,
+		},
+		{name: "Unknown inherited role", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.Roles = []opencgav1.RoleReference{{Name: "analyst", DB: "opencga_catalog"}}
+		})}},
+		{name: "Empty actions", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.Privileges = []opencgav1.Privilege{{Resource: opencgav1.Resource{Cluster: true}}}
+		})}},
+		{name: "No privileges nor inherited roles", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) { r.Privileges = nil })}},
+		{name: "No resource", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.Privileges = []opencgav1.Privilege{{Actions: []string{"find"}}}
+		})}},
+		{name: "Cluster and database resource", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.Privileges[0].Resource.Cluster = true
+		})}},
+		{name: "Same role", roles: []opencgav1.CustomRole{analyst(noop), analyst(noop)}},
+		{name: "No database", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) { r.DB = "" })}},
+		{name: "Authentication restrictions", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.AuthenticationRestrictions = []opencgav1.AuthenticationRestriction{{ClientSource: []string{"10.0.0.0/8", "192.168.1.10"}, ServerAddress: []string{"10.1.0.1"}}}
+		})}, valid: true},
+		{name: "Invalid client source", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.AuthenticationRestrictions = []opencgav1.AuthenticationRestriction{{ClientSource: []string{"analysts.example.com"}}}
+		})}},
+		{name: "Empty authentication restriction", roles: []opencgav1.CustomRole{analyst(func(r *opencgav1.CustomRole) {
+			r.AuthenticationRestrictions = []opencgav1.AuthenticationRestriction{{}}
+		})}},
+		{
+			name:  "User granted a custom role",
+			roles: []opencgav1.CustomRole{analyst(noop)},
+			users: []opencgav1.UserSpec{{Name: "alice", PasswordSecretRef: opencgav1.SecretKeyReference{Name: "alice-password"}, Roles: []opencgav1.RoleReference{{Name: "analyst", DB: "admin"}}}},
+			valid: true,
+		},
+		{
+			name:  "User granted an unknown role",
+			users: []opencgav1.UserSpec{{Name: "alice", PasswordSecretRef: opencgav1.SecretKeyReference{Name: "alice-password"}, Roles: []opencgav1.RoleReference{{Name: "analyst", DB: "admin"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Security.Roles = tt.roles
+			ocb.Spec.Users = tt.users
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_TLS(t *testing.T) {
+	certificate := corev1.LocalObjectReference{Name: "my-rs-cert"}
+	ca := corev1.LocalObjectReference{Name: "my-rs-ca"}
+	tests := []struct {
+		name  string
+		tls   opencgav1.TLS
+		valid bool
+	}{
+		{name: "Disabled", tls: opencgav1.TLS{}, valid: true},
+		{name: "Enabled", tls: opencgav1.TLS{Enabled: true, CertificateKeySecretRef: certificate, CAConfigMapRef: ca}, valid: true},
+		{name: "Optional", tls: opencgav1.TLS{Enabled: true, Optional: true, CertificateKeySecretRef: certificate, CAConfigMapRef: ca}, valid: true},
+		{name: "Optional without enabled", tls: opencgav1.TLS{Optional: true}},
+		{name: "Missing certificate", tls: opencgav1.TLS{Enabled: true, CAConfigMapRef: ca}},
+		{name: "Missing CA", tls: opencgav1.TLS{Enabled: true, CertificateKeySecretRef: certificate}},
+		{name: "Client certificates", tls: opencgav1.TLS{Enabled: true, RequireClientCertificates: true, CertificateKeySecretRef: certificate, CAConfigMapRef: ca}, valid: true},
+		{name: "Client certificates without enabled", tls: opencgav1.TLS{RequireClientCertificates: true}},
+		{name: "Client certificates with optional", tls: opencgav1.TLS{Enabled: true, Optional: true, RequireClientCertificates: true, CertificateKeySecretRef: certificate, CAConfigMapRef: ca}},
+		{name: "Client certificates without CA", tls: opencgav1.TLS{Enabled: true, RequireClientCertificates: true, CertificateKeySecretRef: certificate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Security.TLS = tt.tls
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_X509Users(t *testing.T) {
+	tls := opencgav1.TLS{
+		Enabled:                 true,
+		CertificateKeySecretRef: corev1.LocalObjectReference{Name: "my-rs-cert"},
+		CAConfigMapRef:          corev1.LocalObjectReference{Name: "my-rs-ca"},
+	}
+	analysis := opencgav1.X509User{Subject: "CN=analysis,OU=services,O=zetta", Roles: []opencgav1.RoleReference{{Name: "read", DB: "opencga_catalog"}}}
+	tests := []struct {
+		name  string
+		tls   opencgav1.TLS
+		users []opencgav1.X509User
+		valid bool
+	}{
+		{name: "X.509 user", tls: tls, users: []opencgav1.X509User{analysis}, valid: true},
+		{name: "Without TLS", users: []opencgav1.X509User{analysis}},
+		{name: "Invalid subject", tls: tls, users: []opencgav1.X509User{{Subject: "analysis"}}},
+		{name: "Duplicate subject", tls: tls, users: []opencgav1.X509User{analysis, analysis}},
+		{name: "Unknown role", tls: tls, users: []opencgav1.X509User{{Subject: "CN=analysis", Roles: []opencgav1.RoleReference{{Name: "analyst", DB: "admin"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Security.TLS = tt.tls
+			ocb.Spec.Security.X509Users = tt.users
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_LDAP(t *testing.T) {
+	valid := func() opencgav1.LDAPSpec {
+		return opencgav1.LDAPSpec{
+			Servers:               []string{"ldaps://ldap.example.org:636"},
+			BindDN:                "cn=opencga,ou=services,dc=example,dc=org",
+			BindPasswordSecretRef: &opencgav1.SecretKeyReference{Name: "ldap-bind"},
+			UserSearchBase:        "ou=people,dc=example,dc=org",
+			GroupSearchBase:       "ou=groups,dc=example,dc=org",
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*opencgav1.LDAPSpec)
+		valid  bool
+	}{
+		{name: "LDAP", modify: func(l *opencgav1.LDAPSpec) {}, valid: true},
+		{name: "Anonymous bind", modify: func(l *opencgav1.LDAPSpec) { l.BindDN, l.BindPasswordSecretRef = "", nil }, valid: true},
+		{name: "CA certificate", modify: func(l *opencgav1.LDAPSpec) { l.CaCertificateSecretRef = &opencgav1.SecretKeyReference{Name: "ldap-ca"} }, valid: true},
+		{name: "No servers", modify: func(l *opencgav1.LDAPSpec) { l.Servers = nil }},
+		{name: "Invalid server", modify: func(l *opencgav1.LDAPSpec) { l.Servers = []string{"https://ldap.example.org"} }},
+		{name: "No user search base", modify: func(l *opencgav1.LDAPSpec) { l.UserSearchBase = "" }},
+		{name: "No group search base", modify: func(l *opencgav1.LDAPSpec) { l.GroupSearchBase = "" }},
+		{name: "Bind DN without password", modify: func(l *opencgav1.LDAPSpec) { l.BindPasswordSecretRef = nil }},
+		{name: "Password without bind DN", modify: func(l *opencgav1.LDAPSpec) { l.BindDN = "" }},
+		{name: "Unnamed password secret", modify: func(l *opencgav1.LDAPSpec) { l.BindPasswordSecretRef.Name = "" }},
+		{name: "Unnamed CA secret", modify: func(l *opencgav1.LDAPSpec) { l.CaCertificateSecretRef = &opencgav1.SecretKeyReference{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ldap := valid()
+			tt.modify(&ldap)
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Security.LDAP = &ldap
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_OIDC(t *testing.T) {
+	valid := func() opencgav1.OIDCSpec {
+		return opencgav1.OIDCSpec{
+			Issuer:          "https://idp.example.org/realms/opencga",
+			ClientID:        "opencga",
+			ClientSecretRef: opencgav1.SecretKeyReference{Name: "opencga-oidc"},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*opencgav1.OIDCSpec)
+		valid  bool
+	}{
+		{name: "OIDC", modify: func(o *opencgav1.OIDCSpec) {}, valid: true},
+		{name: "Scopes", modify: func(o *opencgav1.OIDCSpec) { o.Scopes = []string{"openid", "groups"} }, valid: true},
+		{name: "Claims", modify: func(o *opencgav1.OIDCSpec) { o.Claims = opencgav1.OIDCClaimsSpec{UserID: "preferred_username"} }, valid: true},
+		{name: "Plain HTTP issuer", modify: func(o *opencgav1.OIDCSpec) { o.Issuer = "http://idp.example.org" }},
+		{name: "No issuer", modify: func(o *opencgav1.OIDCSpec) { o.Issuer = "" }},
+		{name: "No client id", modify: func(o *opencgav1.OIDCSpec) { o.ClientID = "" }},
+		{name: "No client secret", modify: func(o *opencgav1.OIDCSpec) { o.ClientSecretRef = opencgav1.SecretKeyReference{} }},
+		{name: "Scopes without openid", modify: func(o *opencgav1.OIDCSpec) { o.Scopes = []string{"profile"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidc := valid()
+			tt.modify(&oidc)
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Security.OIDC = &oidc
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_Users(t *testing.T) {
+	passwordSecretRef := opencgav1.SecretKeyReference{Name: "alice-password"}
+	tests := []struct {
+		name  string
+		users []opencgav1.UserSpec
+		valid bool
+	}{
+		{name: "User", users: []opencgav1.UserSpec{{Name: "alice", PasswordSecretRef: passwordSecretRef}}, valid: true},
+		{name: "No name", users: []opencgav1.UserSpec{{PasswordSecretRef: passwordSecretRef}}},
+		{name: "No password", users: []opencgav1.UserSpec{{Name: "alice"}}},
+		{
+			name: "Same name",
+			users: []opencgav1.UserSpec{
+				{Name: "alice", PasswordSecretRef: passwordSecretRef},
+				{Name: "alice", PasswordSecretRef: passwordSecretRef, ConnectionSecretName: "alice-connection"},
+			},
+		},
+		{
+			name: "Same connection secret",
+			users: []opencgav1.UserSpec{
+				{Name: "alice", PasswordSecretRef: passwordSecretRef, ConnectionSecretName: "connection"},
+				{Name: "bob", PasswordSecretRef: passwordSecretRef, ConnectionSecretName: "connection"},
+			},
+		},
+		{name: "Iteration counts", users: []opencgav1.UserSpec{{Name: "alice", PasswordSecretRef: passwordSecretRef, ScramSha1Iterations: 5000, ScramSha256Iterations: 20000}}, valid: true},
+		{name: "Too few iterations", users: []opencgav1.UserSpec{{Name: "alice", PasswordSecretRef: passwordSecretRef, ScramSha256Iterations: 4096}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+			ocb.Spec.Users = tt.users
+			err := ValidateSpec(ocb)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSpec_ScramIterations(t *testing.T) {
+	ocb := newResource(opencgav1.CatalogSpec{Hosts: []string{"mongo-0:27017"}})
+	ocb.Spec.Security.Scram = opencgav1.ScramSpec{Sha1Iterations: 5000, Sha256Iterations: 20000, Sha256Only: true}
+	assert.NoError(t, ValidateSpec(ocb))
+
+	ocb.Spec.Security.Scram.Sha1Iterations = 1000
+	assert.Error(t, ValidateSpec(ocb))
+}
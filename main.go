@@ -20,6 +20,7 @@ import (
 	"flag"
 	"os"
 
+	uberzap "go.uber.org/zap"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -64,6 +65,8 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// the reconciler logs through the global zap logger
+	uberzap.ReplaceGlobals(zap.NewRaw(zap.UseFlagOptions(&opts)))
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -78,10 +81,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.OpenCGACommunityReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	if err = controllers.NewReconciler(mgr).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "OpenCGACommunity")
 		os.Exit(1)
 	}
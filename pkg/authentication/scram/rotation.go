@@ -0,0 +1,221 @@
+package scram
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+	"github.com/phamidko/opencga-operator/pkg/util/generate"
+)
+
+const (
+	// the credentials being rotated out are kept next to the new ones until every agent has moved over.
+	previousAgentPasswordKey = "previous-password"
+	previousAgentKeyfileKey  = "previous-keyfile"
+
+	// the keyfile secret records the trigger of the last rotation and when it happened.
+	agentCredentialsRotationAnnotation  = "opencga.zetta.com/agent-credentials-rotation"
+	agentCredentialsRotatedAtAnnotation = "opencga.zetta.com/agent-credentials-rotated-at"
+)
+
+// now is replaced in the tests.
+var now = time.Now
+
+// Rotation configures when the agent password and keyfile are rotated. A rotation writes new credentials
+// while keeping the previous ones in the automation config, it is completed by CompleteAgentCredentialsRotation.
+type Rotation struct {
+	// Trigger rotates the credentials whenever its value changes.
+	Trigger string
+
+	// Interval rotates the credentials once they are older than it. Scheduled rotations are disabled if it is zero.
+	Interval time.Duration
+}
+
+// agentCredentials are the password and keyfile contents of the agent, and the ones being rotated out if a
+// rotation is in progress.
+type agentCredentials struct {
+	password         string
+	keyfile          string
+	previousPassword string
+	previousKeyfile  string
+}
+
+// ensureAgentCredentials makes sure the agent password and keyfile secrets exist and returns their contents.
+// New credentials are written if a rotation is due and no other rotation is in progress.
+func ensureAgentCredentials(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) (agentCredentials, error) {
+	generatedPassword, err := generate.RandomFixedLengthStringOfSize(20)
+	if err != nil {
+		return agentCredentials{}, errors.Errorf("could not generate password: %s", err)
+	}
+
+	generatedContents, err := generate.KeyFileContents()
+	if err != nil {
+		return agentCredentials{}, errors.Errorf("could not generate keyfile contents: %s", err)
+	}
+
+	if _, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentPasswordSecretNamespacedName(), mdb.GetOwnerReferences(), AgentPasswordKey, generatedPassword); err != nil {
+		return agentCredentials{}, err
+	}
+	if _, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentKeyfileSecretNamespacedName(), mdb.GetOwnerReferences(), AgentKeyfileKey, generatedContents); err != nil {
+		return agentCredentials{}, err
+	}
+
+	passwordSecret, err := secretGetUpdateCreateDeleter.GetSecret(mdb.GetAgentPasswordSecretNamespacedName())
+	if err != nil {
+		return agentCredentials{}, err
+	}
+	keyfileSecret, err := secretGetUpdateCreateDeleter.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+	if err != nil {
+		return agentCredentials{}, err
+	}
+
+	credentials := agentCredentials{
+		password:         string(passwordSecret.Data[AgentPasswordKey]),
+		keyfile:          string(keyfileSecret.Data[AgentKeyfileKey]),
+		previousPassword: string(passwordSecret.Data[previousAgentPasswordKey]),
+		previousKeyfile:  string(keyfileSecret.Data[previousAgentKeyfileKey]),
+	}
+	if credentials.previousPassword != "" || credentials.previousKeyfile != "" {
+		zap.S().Debugf("Rotation of the agent credentials stored in secret/%s is in progress", keyfileSecret.Name)
+		return credentials, nil
+	}
+
+	rotation := mdb.GetScramOptions().Rotation
+	_, hasRotatedAt := keyfileSecret.Annotations[agentCredentialsRotatedAtAnnotation]
+	// the keyfile has just been created, or predates rotations: the trigger is recorded without rotating.
+	if credentials.keyfile == generatedContents || !hasRotatedAt {
+		return credentials, recordRotation(secretGetUpdateCreateDeleter, keyfileSecret, rotation.Trigger)
+	}
+	if !rotationIsDue(keyfileSecret, rotation) {
+		return credentials, nil
+	}
+
+	zap.S().Infof("Rotating the agent credentials stored in secret/%s and secret/%s", passwordSecret.Name, keyfileSecret.Name)
+	passwordSecret.Data[previousAgentPasswordKey] = passwordSecret.Data[AgentPasswordKey]
+	passwordSecret.Data[AgentPasswordKey] = []byte(generatedPassword)
+	if err := secretGetUpdateCreateDeleter.UpdateSecret(passwordSecret); err != nil {
+		return agentCredentials{}, errors.Errorf("could not rotate the agent password: %s", err)
+	}
+
+	keyfileSecret.Data[previousAgentKeyfileKey] = keyfileSecret.Data[AgentKeyfileKey]
+	keyfileSecret.Data[AgentKeyfileKey] = []byte(generatedContents)
+	if err := recordRotation(secretGetUpdateCreateDeleter, keyfileSecret, rotation.Trigger); err != nil {
+		return agentCredentials{}, errors.Errorf("could not rotate the agent keyfile: %s", err)
+	}
+
+	return agentCredentials{
+		password:         generatedPassword,
+		keyfile:          generatedContents,
+		previousPassword: credentials.password,
+		previousKeyfile:  credentials.keyfile,
+	}, nil
+}
+
+// rotationIsDue returns true if the trigger changed since the last rotation, or if the credentials are older
+// than the rotation interval.
+func rotationIsDue(keyfileSecret corev1.Secret, rotation Rotation) bool {
+	if keyfileSecret.Annotations[agentCredentialsRotationAnnotation] != rotation.Trigger {
+		return true
+	}
+	if rotation.Interval <= 0 {
+		return false
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, keyfileSecret.Annotations[agentCredentialsRotatedAtAnnotation])
+	if err != nil {
+		zap.S().Warnf("Invalid rotation time on secret/%s, rotating the agent credentials: %s", keyfileSecret.Name, err)
+		return true
+	}
+	return !now().Before(rotatedAt.Add(rotation.Interval))
+}
+
+// TimeUntilScheduledRotation returns how long until the agent credentials are older than the rotation interval,
+// or zero if scheduled rotations are disabled or the credentials do not exist yet.
+func TimeUntilScheduledRotation(secretGetter secret.Getter, mdb Configurable) (time.Duration, error) {
+	interval := mdb.GetScramOptions().Rotation.Interval
+	if interval <= 0 {
+		return 0, nil
+	}
+	keyfileSecret, err := secretGetter.GetSecret(mdb.GetAgentKeyfileSecretNamespacedName())
+	if err != nil {
+		if secret.SecretNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, keyfileSecret.Annotations[agentCredentialsRotatedAtAnnotation])
+	if err != nil {
+		return 0, nil
+	}
+	if remaining := rotatedAt.Add(interval).Sub(now()); remaining > time.Second {
+		return remaining, nil
+	}
+	return time.Second, nil
+}
+
+// recordRotation stores the trigger and the current time on the keyfile secret and updates it.
+func recordRotation(updater secret.Updater, keyfileSecret corev1.Secret, trigger string) error {
+	if keyfileSecret.Annotations == nil {
+		keyfileSecret.Annotations = map[string]string{}
+	}
+	keyfileSecret.Annotations[agentCredentialsRotationAnnotation] = trigger
+	keyfileSecret.Annotations[agentCredentialsRotatedAtAnnotation] = now().UTC().Format(time.RFC3339)
+	return updater.UpdateSecret(keyfileSecret)
+}
+
+// previousAgentCredential is the key of a secret holding agent credentials being rotated out.
+type previousAgentCredential struct {
+	nsName types.NamespacedName
+	key    string
+}
+
+func previousAgentCredentials(mdb Configurable) []previousAgentCredential {
+	return []previousAgentCredential{
+		{nsName: mdb.GetAgentPasswordSecretNamespacedName(), key: previousAgentPasswordKey},
+		{nsName: mdb.GetAgentKeyfileSecretNamespacedName(), key: previousAgentKeyfileKey},
+	}
+}
+
+// IsRotatingAgentCredentials returns true if the previous agent password or keyfile are still kept.
+func IsRotatingAgentCredentials(secretGetter secret.Getter, mdb Configurable) (bool, error) {
+	for _, previous := range previousAgentCredentials(mdb) {
+		s, err := secretGetter.GetSecret(previous.nsName)
+		if err != nil {
+			if secret.SecretNotExist(err) {
+				continue
+			}
+			return false, err
+		}
+		if secret.HasAllKeys(s, previous.key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CompleteAgentCredentialsRotation drops the previous agent password and keyfile. It must only be called once every
+// agent has reached the goal state of an automation config holding the new credentials, the next automation config
+// built by Enable no longer accepts the previous ones.
+func CompleteAgentCredentialsRotation(secretGetUpdater secret.GetUpdater, mdb Configurable) error {
+	for _, previous := range previousAgentCredentials(mdb) {
+		s, err := secretGetUpdater.GetSecret(previous.nsName)
+		if err != nil {
+			if secret.SecretNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if !secret.HasAllKeys(s, previous.key) {
+			continue
+		}
+		delete(s.Data, previous.key)
+		if err := secretGetUpdater.UpdateSecret(s); err != nil {
+			return errors.Errorf("could not remove the previous agent credentials from secret %s: %s", previous.nsName, err)
+		}
+	}
+	zap.S().Infof("Completed the rotation of the agent credentials")
+	return nil
+}
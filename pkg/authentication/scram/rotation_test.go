@@ -0,0 +1,124 @@
+package scram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+)
+
+func buildRotatingConfigurable(rotation Rotation) mockConfigurable {
+	mdb := buildConfigurable("mdb-0").(mockConfigurable)
+	mdb.opts.Rotation = rotation
+	return mdb
+}
+
+func enableAndAssertNoError(t *testing.T, s secret.GetUpdateCreateDeleter, mdb Configurable) automationconfig.Auth {
+	auth := automationconfig.Auth{}
+	assert.NoError(t, Enable(&auth, s, mdb))
+	return auth
+}
+
+func TestAgentCredentialsRotation(t *testing.T) {
+	t.Run("Credentials are not rotated when they are created", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter()
+		auth := enableAndAssertNoError(t, s, buildRotatingConfigurable(Rotation{Trigger: "first"}))
+		assert.NotEmpty(t, auth.Key)
+		assert.Empty(t, auth.PreviousKey)
+		assert.Empty(t, auth.PreviousAutoPwd)
+
+		sameAuth := enableAndAssertNoError(t, s, buildRotatingConfigurable(Rotation{Trigger: "first"}))
+		assert.Equal(t, auth.Key, sameAuth.Key)
+		assert.Equal(t, auth.AutoPwd, sameAuth.AutoPwd)
+	})
+
+	t.Run("Changing the trigger rotates the credentials until the rotation is completed", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter()
+		initial := enableAndAssertNoError(t, s, buildRotatingConfigurable(Rotation{}))
+
+		mdb := buildRotatingConfigurable(Rotation{Trigger: "2022-06-01"})
+		rotating := enableAndAssertNoError(t, s, mdb)
+		assert.NotEqual(t, initial.Key, rotating.Key)
+		assert.NotEqual(t, initial.AutoPwd, rotating.AutoPwd)
+		assert.Equal(t, initial.Key, rotating.PreviousKey, "the agents should accept both keys during the rotation")
+		assert.Equal(t, initial.AutoPwd, rotating.PreviousAutoPwd)
+
+		isRotating, err := IsRotatingAgentCredentials(s, mdb)
+		assert.NoError(t, err)
+		assert.True(t, isRotating)
+
+		stillRotating := enableAndAssertNoError(t, s, buildRotatingConfigurable(Rotation{Trigger: "2022-07-01"}))
+		assert.Equal(t, rotating, stillRotating, "a rotation should not start before the previous one is completed")
+
+		assert.NoError(t, CompleteAgentCredentialsRotation(s, mdb))
+		isRotating, err = IsRotatingAgentCredentials(s, mdb)
+		assert.NoError(t, err)
+		assert.False(t, isRotating)
+
+		completed := enableAndAssertNoError(t, s, mdb)
+		assert.Equal(t, rotating.Key, completed.Key)
+		assert.Equal(t, rotating.AutoPwd, completed.AutoPwd)
+		assert.Empty(t, completed.PreviousKey)
+		assert.Empty(t, completed.PreviousAutoPwd)
+	})
+
+	t.Run("Credentials are rotated once they are older than the interval", func(t *testing.T) {
+		defer func() { now = time.Now }()
+		start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+		now = func() time.Time { return start }
+
+		s := newMockedSecretGetUpdateCreateDeleter()
+		mdb := buildRotatingConfigurable(Rotation{Interval: 24 * time.Hour})
+		initial := enableAndAssertNoError(t, s, mdb)
+
+		now = func() time.Time { return start.Add(23 * time.Hour) }
+		notYet := enableAndAssertNoError(t, s, mdb)
+		assert.Equal(t, initial.Key, notYet.Key)
+		assert.Empty(t, notYet.PreviousKey)
+
+		now = func() time.Time { return start.Add(24 * time.Hour) }
+		rotated := enableAndAssertNoError(t, s, mdb)
+		assert.NotEqual(t, initial.Key, rotated.Key)
+		assert.Equal(t, initial.Key, rotated.PreviousKey)
+	})
+
+	t.Run("Credentials which predate rotations are not rotated right away", func(t *testing.T) {
+		mdb := buildRotatingConfigurable(Rotation{Trigger: "2022-06-01"})
+		keyfileSecret := secret.Builder().
+			SetName(mdb.GetAgentKeyfileSecretNamespacedName().Name).
+			SetNamespace(mdb.GetAgentKeyfileSecretNamespacedName().Namespace).
+			SetField(AgentKeyfileKey, "RuPeMaIe2g0SNTTa").
+			Build()
+
+		auth := enableAndAssertNoError(t, newMockedSecretGetUpdateCreateDeleter(keyfileSecret), mdb)
+		assert.Equal(t, "RuPeMaIe2g0SNTTa", auth.Key)
+		assert.Empty(t, auth.PreviousKey)
+	})
+
+	t.Run("Time until the scheduled rotation", func(t *testing.T) {
+		defer func() { now = time.Now }()
+		start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+		now = func() time.Time { return start }
+
+		s := newMockedSecretGetUpdateCreateDeleter()
+		unscheduled := buildRotatingConfigurable(Rotation{})
+		enableAndAssertNoError(t, s, unscheduled)
+		untilRotation, err := TimeUntilScheduledRotation(s, unscheduled)
+		assert.NoError(t, err)
+		assert.Zero(t, untilRotation, "no rotation is scheduled without an interval")
+
+		scheduled := buildRotatingConfigurable(Rotation{Interval: 24 * time.Hour})
+		now = func() time.Time { return start.Add(20 * time.Hour) }
+		untilRotation, err = TimeUntilScheduledRotation(s, scheduled)
+		assert.NoError(t, err)
+		assert.Equal(t, 4*time.Hour, untilRotation)
+
+		now = func() time.Time { return start.Add(30 * time.Hour) }
+		untilRotation, err = TimeUntilScheduledRotation(s, scheduled)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second, untilRotation, "an overdue rotation should be requeued right away")
+	})
+}
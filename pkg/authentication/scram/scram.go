@@ -3,6 +3,7 @@ package scram
 import (
 	"encoding/base64"
 	"fmt"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -26,6 +27,15 @@ const (
 
 	sha1StoredKeyKey   = "sha-1-stored-key"
 	sha256StoredKeyKey = "sha-256-stored-key"
+
+	// credentials secrets created before the iteration counts were stored use the default ones.
+	sha1IterationsKey   = "sha-1-iterations"
+	sha256IterationsKey = "sha-256-iterations"
+)
+
+var (
+	sha1CredentialsKeys   = []string{sha1SaltKey, sha1ServerKeyKey, sha1StoredKeyKey}
+	sha256CredentialsKeys = []string{sha256SaltKey, sha256ServerKeyKey, sha256StoredKeyKey}
 )
 
 // Configurable is an interface which any resource which can configure ScramSha authentication should implement.
@@ -80,6 +90,16 @@ type User struct {
 	// for this user. These credentials will be generated if they do not exist, or used if they do.
 	// Note: there will be one secret with credentials per user created.
 	ScramCredentialsSecretName string
+
+	// ConnectionStringSecretName is the name of the secret the connection details of this user are published in.
+	// GetConnectionStringSecretName computes a name if it is empty.
+	ConnectionStringSecretName string
+
+	// ScramSha1Iterations overrides the number of SCRAM-SHA-1 iterations of the Options for this user.
+	ScramSha1Iterations int
+
+	// ScramSha256Iterations overrides the number of SCRAM-SHA-256 iterations of the Options for this user.
+	ScramSha256Iterations int
 }
 
 // Options contains a set of values that can be used for more fine grained configuration of authentication.
@@ -98,48 +118,110 @@ type Options struct {
 
 	// AutoAuthMechanism is the desired authentication mechanism that the agents will use.
 	AutoAuthMechanism string
+
+	// Rotation configures when the agent password and keyfile are rotated.
+	Rotation Rotation
+
+	// ScramSha1Iterations is the number of iterations of the SCRAM-SHA-1 credentials of the users.
+	// Defaults to scramcredentials.DefaultScramSha1Iterations.
+	ScramSha1Iterations int
+
+	// ScramSha256Iterations is the number of iterations of the SCRAM-SHA-256 credentials of the users.
+	// Defaults to scramcredentials.DefaultScramSha256Iterations.
+	ScramSha256Iterations int
+
+	// Sha256Only only generates and stores SCRAM-SHA-256 credentials. Existing SCRAM-SHA-1 credentials are removed.
+	Sha256Only bool
 }
 
-// Enable will configure all of the required Kubernetes resources for SCRAM-SHA to be enabled.
-// The agent password and keyfile contents will be configured and stored in a secret.
-// the user credentials will be generated if not present, or existing credentials will be read.
-func Enable(auth *automationconfig.Auth, secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) error {
-	generatedPassword, err := generate.RandomFixedLengthStringOfSize(20)
-	if err != nil {
-		return errors.Errorf("could not generate password: %s", err)
+// credentialsOptions are the mechanisms and iteration counts of the credentials of a single user.
+type credentialsOptions struct {
+	sha1Iterations   int
+	sha256Iterations int
+	sha256Only       bool
+}
+
+// credentialsOptionsFor returns the credentials options of the given user. The iteration counts of the user
+// take precedence over the ones of the Options.
+func credentialsOptionsFor(opts Options, user User) credentialsOptions {
+	return credentialsOptions{
+		sha1Iterations:   firstPositive(user.ScramSha1Iterations, opts.ScramSha1Iterations, scramcredentials.DefaultScramSha1Iterations),
+		sha256Iterations: firstPositive(user.ScramSha256Iterations, opts.ScramSha256Iterations, scramcredentials.DefaultScramSha256Iterations),
+		sha256Only:       opts.Sha256Only,
 	}
+}
 
-	generatedContents, err := generate.KeyFileContents()
-	if err != nil {
-		return errors.Errorf("could not generate keyfile contents: %s", err)
+// validate returns an error if an iteration count is lower than the one MongoDB accepts.
+func (c credentialsOptions) validate() error {
+	if c.sha256Iterations < scramcredentials.MinScramIterations {
+		return errors.Errorf("the SCRAM-SHA-256 iteration count must be at least %d, got %d", scramcredentials.MinScramIterations, c.sha256Iterations)
+	}
+	if !c.sha256Only && c.sha1Iterations < scramcredentials.MinScramIterations {
+		return errors.Errorf("the SCRAM-SHA-1 iteration count must be at least %d, got %d", scramcredentials.MinScramIterations, c.sha1Iterations)
 	}
+	return nil
+}
 
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// Enable will configure all of the required Kubernetes resources for SCRAM-SHA to be enabled.
+// The agent password and keyfile contents will be configured and stored in a secret, and rotated
+// according to the Rotation options.
+// the user credentials will be generated if not present, or existing credentials will be read.
+func Enable(auth *automationconfig.Auth, secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) error {
 	desiredUsers, err := convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDeleter, mdb)
 	if err != nil {
 		return errors.Errorf("could not convert users to Automation Config users: %s", err)
 	}
 
-	// ensure that the agent password secret exists or read existing password.
-	agentPassword, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentPasswordSecretNamespacedName(), mdb.GetOwnerReferences(), AgentPasswordKey, generatedPassword)
+	// ensure that the agent password and keyfile secrets exist, rotating them if needed, or read the existing ones.
+	credentials, err := ensureAgentCredentials(secretGetUpdateCreateDeleter, mdb)
 	if err != nil {
 		return err
 	}
 
-	// ensure that the agent keyfile secret exists or read existing keyfile.
-	agentKeyFile, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, mdb.GetAgentKeyfileSecretNamespacedName(), mdb.GetOwnerReferences(), AgentKeyfileKey, generatedContents)
-	if err != nil {
+	if err := configureScramInAutomationConfig(auth,
+		credentials.password,
+		credentials.keyfile, desiredUsers, mdb.GetScramOptions(),
+	); err != nil {
 		return err
 	}
 
-	return configureScramInAutomationConfig(auth,
-		agentPassword,
-		agentKeyFile, desiredUsers, mdb.GetScramOptions(),
-	)
+	// while a rotation is in progress the agents accept both the previous and the new credentials.
+	auth.PreviousAutoPwd = credentials.previousPassword
+	auth.PreviousKey = credentials.previousKeyfile
+	return nil
+}
+
+// EnsurePassword returns the password of the given user. If the secret referenced by the user does not exist,
+// a password is generated and stored in it, owned by the given resource.
+func EnsurePassword(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, user User, namespace string, ownerReferences []metav1.OwnerReference) (string, error) {
+	generatedPassword, err := generate.RandomFixedLengthStringOfSize(20)
+	if err != nil {
+		return "", errors.Errorf("could not generate password: %s", err)
+	}
+
+	passwordSecretNsName := types.NamespacedName{Name: user.PasswordSecretName, Namespace: namespace}
+	password, err := secret.EnsureSecretWithKey(secretGetUpdateCreateDeleter, passwordSecretNsName, ownerReferences, user.PasswordSecretKey, generatedPassword)
+	if err != nil {
+		return "", errors.Errorf("could not ensure password secret %s: %s", passwordSecretNsName, err)
+	}
+	if password == "" {
+		return "", errors.Errorf("secret %s does not contain a password in key %s", passwordSecretNsName, user.PasswordSecretKey)
+	}
+	return password, nil
 }
 
 // ensureScramCredentials will ensure that the ScramSha1 & ScramSha256 credentials exist and are stored in the credentials
-// secret corresponding to user of the given MongoDB deployment.
-func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User, mdbNamespacedName types.NamespacedName) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+// secret corresponding to user of the given MongoDB deployment. The ScramSha1 credentials are empty in SHA-256-only mode.
+func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User, mdbNamespacedName types.NamespacedName, credsOpts credentialsOptions) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
 
 	password, err := secret.ReadKey(getUpdateCreator, user.PasswordSecretKey, types.NamespacedName{Name: user.PasswordSecretName, Namespace: mdbNamespacedName.Namespace})
 	if err != nil {
@@ -151,10 +233,11 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not read secret key: %s", err)
 	}
 
-	// we should only need to generate new credentials in two situations.
+	// we should only need to generate new credentials in three situations.
 	// 1. We are creating the credentials for the first time
 	// 2. We are changing the password
-	shouldGenerateNewCredentials, err := needToGenerateNewCredentials(getUpdateCreator, user.Username, user.ScramCredentialsSecretName, mdbNamespacedName, password)
+	// 3. We are changing the mechanisms or the iteration counts
+	shouldGenerateNewCredentials, err := needToGenerateNewCredentials(getUpdateCreator, user.Username, user.ScramCredentialsSecretName, mdbNamespacedName, password, credsOpts)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not determine if new credentials need to be generated: %s", err)
 	}
@@ -165,15 +248,15 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 		return readExistingCredentials(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName)
 	}
 
-	// the password has changed, or we are generating it for the first time
+	// the password or the credentials options have changed, or we are generating it for the first time
 	zap.S().Debugf("Generating new credentials and storing in secret/%s", user.ScramCredentialsSecretName)
-	sha1Creds, sha256Creds, err := generateScramShaCredentials(user.Username, password)
+	sha1Creds, sha256Creds, err := generateScramShaCredentials(user.Username, password, credsOpts)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("failed generating scram credentials: %s", err)
 	}
 
 	// create or update our credentials secret for this user
-	if err := createScramCredentialsSecret(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName, sha1Creds, sha256Creds); err != nil {
+	if err := createScramCredentialsSecret(getUpdateCreator, mdbNamespacedName, user.ScramCredentialsSecretName, sha1Creds, sha256Creds, credsOpts); err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("faild to create scram credentials secret %s: %s", user.ScramCredentialsSecretName, err)
 	}
 
@@ -182,8 +265,9 @@ func ensureScramCredentials(getUpdateCreator secret.GetUpdateCreator, user User,
 }
 
 // needToGenerateNewCredentials determines if it is required to generate new credentials or not.
-// this will be the case if we are either changing password, or are generating credentials for the first time.
-func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCredentialsSecretName string, mdbNamespacedName types.NamespacedName, password string) (bool, error) {
+// this will be the case if we are either changing password, mechanisms or iteration counts, or are generating
+// credentials for the first time.
+func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCredentialsSecretName string, mdbNamespacedName types.NamespacedName, password string, credsOpts credentialsOptions) (bool, error) {
 	s, err := secretGetter.GetSecret(types.NamespacedName{Name: scramCredentialsSecretName, Namespace: mdbNamespacedName.Namespace})
 	if err != nil {
 		// haven't generated credentials yet, so we are changing password
@@ -194,27 +278,30 @@ func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCre
 		return false, err
 	}
 
-	existingSha1Salt := s.Data[sha1SaltKey]
-	existingSha256Salt := s.Data[sha256SaltKey]
+	// the ScramSha1 credentials need to be added, or removed in SHA-256-only mode.
+	if secret.HasAllKeys(s, sha1CredentialsKeys...) == credsOpts.sha256Only {
+		zap.S().Debugf("SCRAM mechanisms have changed, generating new credentials")
+		return true, nil
+	}
 
-	// the salts are stored encoded, we need to decode them before we use them for
-	// salt generation
-	decodedSha1Salt, err := base64.StdEncoding.DecodeString(string(existingSha1Salt))
+	existingSha1Creds, existingSha256Creds, err := readExistingCredentials(secretGetter, mdbNamespacedName, scramCredentialsSecretName)
 	if err != nil {
 		return false, err
 	}
-	decodedSha256Salt, err := base64.StdEncoding.DecodeString(string(existingSha256Salt))
+
+	// the salts are stored encoded, we need to decode them before we use them for
+	// salt generation
+	decodedSha1Salt, err := base64.StdEncoding.DecodeString(existingSha1Creds.Salt)
 	if err != nil {
 		return false, err
 	}
-
-	// regenerate credentials using the existing salts in order to see if the password has changed.
-	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, decodedSha1Salt, decodedSha256Salt)
+	decodedSha256Salt, err := base64.StdEncoding.DecodeString(existingSha256Creds.Salt)
 	if err != nil {
 		return false, err
 	}
 
-	existingSha1Creds, existingSha256Creds, err := readExistingCredentials(secretGetter, mdbNamespacedName, scramCredentialsSecretName)
+	// regenerate credentials using the existing salts in order to see if the password or the iteration counts have changed.
+	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, decodedSha1Salt, decodedSha256Salt, credsOpts)
 	if err != nil {
 		return false, err
 	}
@@ -227,29 +314,34 @@ func needToGenerateNewCredentials(secretGetter secret.Getter, username, scramCre
 
 // generateScramShaCredentials creates a new set of credentials using randomly generated salts. The first returned element is
 // sha1 credentials, the second is sha256 credentials
-func generateScramShaCredentials(username string, password string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+func generateScramShaCredentials(username string, password string, credsOpts credentialsOptions) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
 	sha1Salt, sha256Salt, err := generate.Salts()
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
 	}
 
-	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, sha1Salt, sha256Salt)
+	sha1Creds, sha256Creds, err := computeScramShaCredentials(username, password, sha1Salt, sha256Salt, credsOpts)
 	if err != nil {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
 	}
 	return sha1Creds, sha256Creds, nil
 }
 
-// computeScramShaCredentials computes ScramSha 1 & 256 credentials using the provided salts
-func computeScramShaCredentials(username, password string, sha1Salt, sha256Salt []byte) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
-	scram1Creds, err := scramcredentials.ComputeScramSha1Creds(username, password, sha1Salt)
+// computeScramShaCredentials computes ScramSha 1 & 256 credentials using the provided salts. The ScramSha1 credentials
+// are not computed in SHA-256-only mode.
+func computeScramShaCredentials(username, password string, sha1Salt, sha256Salt []byte, credsOpts credentialsOptions) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
+	scram256Creds, err := scramcredentials.ComputeScramSha256Creds(password, sha256Salt, credsOpts.sha256Iterations)
 	if err != nil {
-		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha1Creds: %s", err)
+		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha256Creds: %s", err)
+	}
+
+	if credsOpts.sha256Only {
+		return scramcredentials.ScramCreds{}, scram256Creds, nil
 	}
 
-	scram256Creds, err := scramcredentials.ComputeScramSha256Creds(password, sha256Salt)
+	scram1Creds, err := scramcredentials.ComputeScramSha1Creds(username, password, sha1Salt, credsOpts.sha1Iterations)
 	if err != nil {
-		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha256Creds: %s", err)
+		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("could not generate scramSha1Creds: %s", err)
 	}
 
 	return scram1Creds, scram256Creds, nil
@@ -257,21 +349,27 @@ func computeScramShaCredentials(username, password string, sha1Salt, sha256Salt
 
 // createScramCredentialsSecret will create a Secret that contains all of the fields required to read these credentials
 // back in the future.
-func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds) error {
-	scramCredsSecret := secret.Builder().
+func createScramCredentialsSecret(getUpdateCreator secret.GetUpdateCreator, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string, sha1Creds, sha256Creds scramcredentials.ScramCreds, credsOpts credentialsOptions) error {
+	scramCredsSecretBuilder := secret.Builder().
 		SetName(scramCredentialsSecretName).
 		SetNamespace(mdbObjectKey.Namespace).
-		SetField(sha1SaltKey, sha1Creds.Salt).
-		SetField(sha1StoredKeyKey, sha1Creds.StoredKey).
-		SetField(sha1ServerKeyKey, sha1Creds.ServerKey).
 		SetField(sha256SaltKey, sha256Creds.Salt).
 		SetField(sha256StoredKeyKey, sha256Creds.StoredKey).
 		SetField(sha256ServerKeyKey, sha256Creds.ServerKey).
-		Build()
-	return secret.CreateOrUpdate(getUpdateCreator, scramCredsSecret)
+		SetField(sha256IterationsKey, strconv.Itoa(sha256Creds.IterationCount))
+
+	if !credsOpts.sha256Only {
+		scramCredsSecretBuilder.
+			SetField(sha1SaltKey, sha1Creds.Salt).
+			SetField(sha1StoredKeyKey, sha1Creds.StoredKey).
+			SetField(sha1ServerKeyKey, sha1Creds.ServerKey).
+			SetField(sha1IterationsKey, strconv.Itoa(sha1Creds.IterationCount))
+	}
+	return secret.CreateOrUpdate(getUpdateCreator, scramCredsSecretBuilder.Build())
 }
 
-// readExistingCredentials reads the existing set of credentials for both ScramSha 1 & 256
+// readExistingCredentials reads the existing set of credentials for both ScramSha 1 & 256. The ScramSha1 credentials
+// are empty if they are not stored.
 func readExistingCredentials(secretGetter secret.Getter, mdbObjectKey types.NamespacedName, scramCredentialsSecretName string) (scramcredentials.ScramCreds, scramcredentials.ScramCreds, error) {
 	credentialsSecret, err := secretGetter.GetSecret(types.NamespacedName{Name: scramCredentialsSecretName, Namespace: mdbObjectKey.Namespace})
 	if err != nil {
@@ -279,32 +377,64 @@ func readExistingCredentials(secretGetter secret.Getter, mdbObjectKey types.Name
 	}
 
 	// we should really never hit this situation. It would only be possible if the secret storing credentials is manually edited.
-	if !secret.HasAllKeys(credentialsSecret, sha1SaltKey, sha1ServerKeyKey, sha1ServerKeyKey, sha256SaltKey, sha256ServerKeyKey, sha256StoredKeyKey) {
+	if !secret.HasAllKeys(credentialsSecret, sha256CredentialsKeys...) {
 		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, errors.Errorf("credentials secret did not have all of the required keys")
 	}
 
-	scramSha1Creds := scramcredentials.ScramCreds{
-		IterationCount: scramcredentials.DefaultScramSha1Iterations,
-		Salt:           string(credentialsSecret.Data[sha1SaltKey]),
-		ServerKey:      string(credentialsSecret.Data[sha1ServerKeyKey]),
-		StoredKey:      string(credentialsSecret.Data[sha1StoredKeyKey]),
+	sha256Iterations, err := readIterationCount(credentialsSecret.Data, sha256IterationsKey, scramcredentials.DefaultScramSha256Iterations)
+	if err != nil {
+		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
 	}
-
 	scramSha256Creds := scramcredentials.ScramCreds{
-		IterationCount: scramcredentials.DefaultScramSha256Iterations,
+		IterationCount: sha256Iterations,
 		Salt:           string(credentialsSecret.Data[sha256SaltKey]),
 		ServerKey:      string(credentialsSecret.Data[sha256ServerKeyKey]),
 		StoredKey:      string(credentialsSecret.Data[sha256StoredKeyKey]),
 	}
 
+	// the ScramSha1 credentials are not stored in SHA-256-only mode.
+	if !secret.HasAllKeys(credentialsSecret, sha1CredentialsKeys...) {
+		return scramcredentials.ScramCreds{}, scramSha256Creds, nil
+	}
+
+	sha1Iterations, err := readIterationCount(credentialsSecret.Data, sha1IterationsKey, scramcredentials.DefaultScramSha1Iterations)
+	if err != nil {
+		return scramcredentials.ScramCreds{}, scramcredentials.ScramCreds{}, err
+	}
+	scramSha1Creds := scramcredentials.ScramCreds{
+		IterationCount: sha1Iterations,
+		Salt:           string(credentialsSecret.Data[sha1SaltKey]),
+		ServerKey:      string(credentialsSecret.Data[sha1ServerKeyKey]),
+		StoredKey:      string(credentialsSecret.Data[sha1StoredKeyKey]),
+	}
+
 	return scramSha1Creds, scramSha256Creds, nil
 }
 
+// readIterationCount returns the iteration count stored in the given key, or the default one if it is absent.
+func readIterationCount(data map[string][]byte, key string, defaultIterations int) (int, error) {
+	value, ok := data[key]
+	if !ok {
+		return defaultIterations, nil
+	}
+	iterations, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, errors.Errorf("invalid iteration count in key %s: %s", key, err)
+	}
+	return iterations, nil
+}
+
 // convertMongoDBResourceUsersToAutomationConfigUsers returns a list of users that are able to be set in the AutomationConfig
 func convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdb Configurable) ([]automationconfig.MongoDBUser, error) {
+	return ConvertUsers(secretGetUpdateCreateDeleter, mdb.NamespacedName(), mdb.GetScramUsers(), mdb.GetScramOptions())
+}
+
+// ConvertUsers returns the AutomationConfig users of the given users. Their SCRAM credentials are generated if they
+// do not exist, or read from the credentials secrets if they do.
+func ConvertUsers(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdbNsName types.NamespacedName, users []User, opts Options) ([]automationconfig.MongoDBUser, error) {
 	var usersWanted []automationconfig.MongoDBUser
-	for _, u := range mdb.GetScramUsers() {
-		acUser, err := convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter, mdb.NamespacedName(), u)
+	for _, u := range users {
+		acUser, err := convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter, mdbNsName, u, opts)
 		if err != nil {
 			return nil, errors.Errorf("failed to convert scram user %s to Automation Config user: %s", u.Username, err)
 		}
@@ -313,9 +443,48 @@ func convertMongoDBResourceUsersToAutomationConfigUsers(secretGetUpdateCreateDel
 	return usersWanted, nil
 }
 
+// RequestUserDeletions returns the desired users, followed by a deleteRequested entry for every user of the previous
+// AutomationConfig which is no longer desired. A deleteRequested entry is kept until the agents have applied the
+// previous AutomationConfig, so it is part of a single AutomationConfig version they reach.
+func RequestUserDeletions(desired, previous []automationconfig.MongoDBUser, previousIsApplied bool) []automationconfig.MongoDBUser {
+	users := append([]automationconfig.MongoDBUser{}, desired...)
+	for _, previousUser := range previous {
+		if containsUser(desired, previousUser) {
+			continue
+		}
+		if previousUser.DeleteRequested && previousIsApplied {
+			zap.S().Debugf("The deletion of user %s/%s has been applied", previousUser.Database, previousUser.Username)
+			continue
+		}
+		users = append(users, automationconfig.MongoDBUser{
+			Username:                   previousUser.Username,
+			Database:                   previousUser.Database,
+			Roles:                      []automationconfig.Role{},
+			Mechanisms:                 []string{},
+			AuthenticationRestrictions: []string{},
+			DeleteRequested:            true,
+		})
+	}
+	return users
+}
+
+func containsUser(users []automationconfig.MongoDBUser, user automationconfig.MongoDBUser) bool {
+	for _, u := range users {
+		if u.Username == user.Username && u.Database == user.Database {
+			return true
+		}
+	}
+	return false
+}
+
 // convertMongoDBUserToAutomationConfigUser converts a single user configured in the MongoDB resource and converts it to a user
 // that can be added directly to the AutomationConfig.
-func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdbNsName types.NamespacedName, user User) (automationconfig.MongoDBUser, error) {
+func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secret.GetUpdateCreateDeleter, mdbNsName types.NamespacedName, user User, opts Options) (automationconfig.MongoDBUser, error) {
+	credsOpts := credentialsOptionsFor(opts, user)
+	if err := credsOpts.validate(); err != nil {
+		return automationconfig.MongoDBUser{}, err
+	}
+
 	acUser := automationconfig.MongoDBUser{
 		Username: user.Username,
 		Database: user.Database,
@@ -326,18 +495,24 @@ func convertMongoDBUserToAutomationConfigUser(secretGetUpdateCreateDeleter secre
 			Database: role.Database,
 		})
 	}
-	sha1Creds, sha256Creds, err := ensureScramCredentials(secretGetUpdateCreateDeleter, user, mdbNsName)
+	sha1Creds, sha256Creds, err := ensureScramCredentials(secretGetUpdateCreateDeleter, user, mdbNsName, credsOpts)
 	if err != nil {
 		return automationconfig.MongoDBUser{}, errors.Errorf("could not ensure scram credentials: %s", err)
 	}
 	acUser.AuthenticationRestrictions = []string{}
 	acUser.Mechanisms = []string{}
-	acUser.ScramSha1Creds = &sha1Creds
 	acUser.ScramSha256Creds = &sha256Creds
+	// the stored credentials can still hold ScramSha1 credentials if the password secret was deleted.
+	if !credsOpts.sha256Only {
+		acUser.ScramSha1Creds = &sha1Creds
+	}
 	return acUser, nil
 }
 
 // GetConnectionStringSecretName returns the name of the secret where the operator stores the connection string for current user
-func (u User) GetConnectionStringSecretName(mdb Configurable) string {
-	return fmt.Sprintf("%s-%s-%s", mdb.NamespacedName().Name, u.Database, u.Username)
+func (u User) GetConnectionStringSecretName(mdbNsName types.NamespacedName) string {
+	if u.ConnectionStringSecretName != "" {
+		return u.ConnectionStringSecretName
+	}
+	return fmt.Sprintf("%s-%s-%s", mdbNsName.Name, u.Database, u.Username)
 }
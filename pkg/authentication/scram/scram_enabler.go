@@ -85,5 +85,8 @@ func validateScramOptions(opts Options) error {
 	if opts.KeyFile == "" {
 		errs = multierror.Append(errs, errors.New("KeyFile must be specified"))
 	}
+	if opts.Sha256Only && (opts.AutoAuthMechanism == Sha1 || contains.String(opts.AutoAuthMechanisms, Sha1)) {
+		errs = multierror.Append(errs, errors.New("Sha1 must not be an AutoAuthMechanism when Sha256Only is set"))
+	}
 	return errs
 }
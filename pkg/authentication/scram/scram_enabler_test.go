@@ -93,3 +93,20 @@ func TestScramAutomationConfig(t *testing.T) {
 		assert.Equal(t, []string{Sha1}, auth.DeploymentAuthMechanisms)
 	})
 }
+
+func TestScramAutomationConfig_Sha256Only(t *testing.T) {
+	opts := Options{
+		AuthoritativeSet:   false,
+		KeyFile:            AutomationAgentKeyFilePathInContainer,
+		AutoAuthMechanisms: []string{Sha256},
+		AgentName:          "mms-automation",
+		AutoAuthMechanism:  Sha256,
+		Sha256Only:         true,
+	}
+	auth := automationconfig.Auth{}
+	assert.NoError(t, configureScramInAutomationConfig(&auth, "password", "keyfilecontents", []automationconfig.MongoDBUser{}, opts))
+	assert.Equal(t, []string{Sha256}, auth.DeploymentAuthMechanisms)
+
+	opts.AutoAuthMechanisms = []string{Sha256, Sha1}
+	assert.Error(t, configureScramInAutomationConfig(&automationconfig.Auth{}, "password", "keyfilecontents", []automationconfig.MongoDBUser{}, opts))
+}
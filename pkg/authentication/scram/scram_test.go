@@ -85,9 +85,9 @@ func TestComputeScramCredentials_ComputesSameStoredAndServerKey_WithSameSalt(t *
 	password := "X6oSVAfD1la8fJwhfN" // nolint
 
 	for i := 0; i < 10; i++ {
-		sha1Creds0, sha256Creds0, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey)
+		sha1Creds0, sha256Creds0, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey, defaultCredentialsOptions())
 		assert.NoError(t, err)
-		sha1Creds1, sha256Creds1, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey)
+		sha1Creds1, sha256Creds1, err := computeScramShaCredentials(username, password, sha1Salt, sha256SaltKey, defaultCredentialsOptions())
 		assert.NoError(t, err)
 
 		assert.True(t, reflect.DeepEqual(sha1Creds0, sha1Creds1))
@@ -98,12 +98,12 @@ func TestComputeScramCredentials_ComputesSameStoredAndServerKey_WithSameSalt(t *
 func TestEnsureScramCredentials(t *testing.T) {
 	mdb, user := buildConfigurableAndUser("mdb-0")
 	t.Run("Fails when there is no password secret, and no credentials secret", func(t *testing.T) {
-		_, _, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(), user, mdb.NamespacedName())
+		_, _, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(), user, mdb.NamespacedName(), defaultCredentialsOptions())
 		assert.Error(t, err)
 	})
 	t.Run("Existing credentials are used when password does not exist, but credentials secret has been created", func(t *testing.T) {
 		scramCredentialsSecret := validScramCredentialsSecret(mdb.NamespacedName(), user.ScramCredentialsSecretName)
-		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret), user, mdb.NamespacedName())
+		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret), user, mdb.NamespacedName(), defaultCredentialsOptions())
 		assert.NoError(t, err)
 		assertScramCredsCredentialsValidity(t, scram1Creds, scram256Creds)
 	})
@@ -118,7 +118,7 @@ func TestEnsureScramCredentials(t *testing.T) {
 			Build()
 
 		scramCredentialsSecret := validScramCredentialsSecret(mdb.NamespacedName(), user.ScramCredentialsSecretName)
-		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret, differentPasswordSecret), user, mdb.NamespacedName())
+		scram1Creds, scram256Creds, err := ensureScramCredentials(newMockedSecretGetUpdateCreateDeleter(scramCredentialsSecret, differentPasswordSecret), user, mdb.NamespacedName(), defaultCredentialsOptions())
 		assert.NoError(t, err)
 		assert.NotEqual(t, testSha1Salt, scram1Creds.Salt)
 		assert.NotEmpty(t, scram1Creds.Salt)
@@ -136,7 +136,57 @@ func TestEnsureScramCredentials(t *testing.T) {
 		assert.NotEmpty(t, scram256Creds.ServerKey)
 		assert.Equal(t, 15000, scram256Creds.IterationCount)
 	})
+	t.Run("Changing the iteration count results in different credentials being returned", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user))
 
+		initialScram1Creds, initialScram256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName(), defaultCredentialsOptions())
+		assert.NoError(t, err)
+		assert.Equal(t, 15000, initialScram256Creds.IterationCount)
+
+		credsOpts := defaultCredentialsOptions()
+		credsOpts.sha256Iterations = 20000
+		scram1Creds, scram256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName(), credsOpts)
+		assert.NoError(t, err)
+		assert.Equal(t, 20000, scram256Creds.IterationCount)
+		assert.NotEqual(t, initialScram256Creds.StoredKey, scram256Creds.StoredKey)
+		assert.Equal(t, 10000, scram1Creds.IterationCount)
+		assert.NotEqual(t, initialScram1Creds.Salt, scram1Creds.Salt, "new salts should be generated")
+
+		credentialsSecret, err := s.GetSecret(types.NamespacedName{Name: user.ScramCredentialsSecretName, Namespace: mdb.NamespacedName().Namespace})
+		assert.NoError(t, err)
+		assert.Equal(t, "20000", string(credentialsSecret.Data[sha256IterationsKey]))
+
+		_, sameScram256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName(), credsOpts)
+		assert.NoError(t, err)
+		assert.Equal(t, scram256Creds, sameScram256Creds, "the stored credentials should be reused")
+	})
+	t.Run("Only SCRAM-SHA-256 credentials are stored in SHA-256-only mode", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user))
+		_, _, err := ensureScramCredentials(s, user, mdb.NamespacedName(), defaultCredentialsOptions())
+		assert.NoError(t, err)
+
+		credsOpts := defaultCredentialsOptions()
+		credsOpts.sha256Only = true
+		scram1Creds, scram256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName(), credsOpts)
+		assert.NoError(t, err)
+		assert.Equal(t, scramcredentials.ScramCreds{}, scram1Creds)
+		assert.NotEmpty(t, scram256Creds.StoredKey)
+
+		credentialsSecret, err := s.GetSecret(types.NamespacedName{Name: user.ScramCredentialsSecretName, Namespace: mdb.NamespacedName().Namespace})
+		assert.NoError(t, err)
+		assert.True(t, secret.HasAllKeys(credentialsSecret, sha256CredentialsKeys...))
+		for _, key := range sha1CredentialsKeys {
+			assert.NotContains(t, credentialsSecret.Data, key)
+		}
+
+		_, sameScram256Creds, err := ensureScramCredentials(s, user, mdb.NamespacedName(), credsOpts)
+		assert.NoError(t, err)
+		assert.Equal(t, scram256Creds, sameScram256Creds, "the stored credentials should be reused")
+
+		scram1Creds, _, err = ensureScramCredentials(s, user, mdb.NamespacedName(), defaultCredentialsOptions())
+		assert.NoError(t, err)
+		assert.NotEmpty(t, scram1Creds.StoredKey, "SCRAM-SHA-1 credentials should be generated when the mode is disabled")
+	})
 }
 
 func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
@@ -149,7 +199,7 @@ func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 			SetField(user.PasswordSecretKey, "TDg_DESiScDrJV6").
 			Build()
 
-		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb.NamespacedName(), user)
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(passwordSecret), mdb.NamespacedName(), user, mdb.GetScramOptions())
 
 		assert.NoError(t, err)
 		assert.Equal(t, user.Username, acUser.Username)
@@ -164,9 +214,71 @@ func TestConvertMongoDBUserToAutomationConfigUser(t *testing.T) {
 	})
 
 	t.Run("If there is no password secret, the creation fails", func(t *testing.T) {
-		_, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(), mdb.NamespacedName(), user)
+		_, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(), mdb.NamespacedName(), user, mdb.GetScramOptions())
 		assert.Error(t, err)
 	})
+
+	t.Run("The user has no SCRAM-SHA-1 credentials in SHA-256-only mode", func(t *testing.T) {
+		opts := mdb.GetScramOptions()
+		opts.Sha256Only = true
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user)), mdb.NamespacedName(), user, opts)
+		assert.NoError(t, err)
+		assert.Nil(t, acUser.ScramSha1Creds)
+		assert.NotNil(t, acUser.ScramSha256Creds)
+	})
+
+	t.Run("The iteration counts of the user take precedence", func(t *testing.T) {
+		opts := mdb.GetScramOptions()
+		opts.ScramSha256Iterations = 20000
+		userWithIterations := user
+		userWithIterations.ScramSha256Iterations = 25000
+		acUser, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user)), mdb.NamespacedName(), userWithIterations, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 25000, acUser.ScramSha256Creds.IterationCount)
+		assert.Equal(t, 10000, acUser.ScramSha1Creds.IterationCount)
+	})
+
+	t.Run("Iteration counts lower than the minimum are rejected", func(t *testing.T) {
+		opts := mdb.GetScramOptions()
+		opts.ScramSha1Iterations = 1000
+		_, err := convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user)), mdb.NamespacedName(), user, opts)
+		assert.Error(t, err)
+
+		opts.Sha256Only = true
+		_, err = convertMongoDBUserToAutomationConfigUser(newMockedSecretGetUpdateCreateDeleter(userPasswordSecret(mdb.NamespacedName(), user)), mdb.NamespacedName(), user, opts)
+		assert.NoError(t, err, "the SCRAM-SHA-1 iteration count is not used in SHA-256-only mode")
+	})
+}
+
+func TestRequestUserDeletions(t *testing.T) {
+	alice := automationconfig.MongoDBUser{Username: "alice", Database: "opencga"}
+	bob := automationconfig.MongoDBUser{Username: "bob", Database: "opencga"}
+
+	t.Run("Users removed from the desired users are marked for deletion", func(t *testing.T) {
+		users := RequestUserDeletions([]automationconfig.MongoDBUser{alice}, []automationconfig.MongoDBUser{alice, bob}, true)
+		assert.Len(t, users, 2)
+		assert.Equal(t, alice, users[0])
+		assert.Equal(t, "bob", users[1].Username)
+		assert.Equal(t, "opencga", users[1].Database)
+		assert.True(t, users[1].DeleteRequested)
+		assert.Nil(t, users[1].ScramSha256Creds)
+	})
+
+	t.Run("Deletion requests are kept until the previous automation config is applied", func(t *testing.T) {
+		deletedBob := RequestUserDeletions(nil, []automationconfig.MongoDBUser{bob}, true)[0]
+
+		users := RequestUserDeletions([]automationconfig.MongoDBUser{alice}, []automationconfig.MongoDBUser{alice, deletedBob}, false)
+		assert.Equal(t, []automationconfig.MongoDBUser{alice, deletedBob}, users)
+
+		users = RequestUserDeletions([]automationconfig.MongoDBUser{alice}, []automationconfig.MongoDBUser{alice, deletedBob}, true)
+		assert.Equal(t, []automationconfig.MongoDBUser{alice}, users)
+	})
+
+	t.Run("Users added back are not deleted", func(t *testing.T) {
+		deletedBob := RequestUserDeletions(nil, []automationconfig.MongoDBUser{bob}, true)[0]
+		users := RequestUserDeletions([]automationconfig.MongoDBUser{alice, bob}, []automationconfig.MongoDBUser{alice, deletedBob}, false)
+		assert.Equal(t, []automationconfig.MongoDBUser{alice, bob}, users)
+	})
 }
 
 func TestConfigureScram(t *testing.T) {
@@ -248,6 +360,47 @@ func TestConfigureScram(t *testing.T) {
 	})
 }
 
+func TestEnsurePassword(t *testing.T) {
+	user := buildMongoDBUser("opencga")
+	passwordSecretNsName := types.NamespacedName{Name: user.PasswordSecretName, Namespace: "default"}
+
+	t.Run("Password is generated and stored if the secret does not exist", func(t *testing.T) {
+		s := newMockedSecretGetUpdateCreateDeleter()
+		password, err := EnsurePassword(s, user, "default", nil)
+		assert.NoError(t, err)
+		assert.Len(t, password, 20)
+
+		storedPassword, err := secret.ReadKey(s, user.PasswordSecretKey, passwordSecretNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, password, storedPassword)
+
+		samePassword, err := EnsurePassword(s, user, "default", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, password, samePassword, "the generated password should be reused")
+	})
+
+	t.Run("Existing password is read", func(t *testing.T) {
+		passwordSecret := secret.Builder().
+			SetName(passwordSecretNsName.Name).
+			SetNamespace(passwordSecretNsName.Namespace).
+			SetField(user.PasswordSecretKey, "my-password").
+			Build()
+		password, err := EnsurePassword(newMockedSecretGetUpdateCreateDeleter(passwordSecret), user, "default", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "my-password", password)
+	})
+
+	t.Run("Error is returned if the secret does not contain the key", func(t *testing.T) {
+		passwordSecret := secret.Builder().
+			SetName(passwordSecretNsName.Name).
+			SetNamespace(passwordSecretNsName.Namespace).
+			SetField("other-key", "my-password").
+			Build()
+		_, err := EnsurePassword(newMockedSecretGetUpdateCreateDeleter(passwordSecret), user, "default", nil)
+		assert.Error(t, err)
+	})
+}
+
 func buildConfigurable(name string, users ...User) Configurable {
 	return mockConfigurable{
 		opts: Options{
@@ -335,6 +488,20 @@ func assertScramCredsCredentialsValidity(t *testing.T, scram1Creds, scram256Cred
 	assert.Equal(t, 15000, scram256Creds.IterationCount)
 }
 
+func defaultCredentialsOptions() credentialsOptions {
+	return credentialsOptionsFor(Options{}, User{})
+}
+
+// passwordSecretMatchingTestCredentials returns a password secret of the given user, any password works as long as
+// the credentials options match the ones of the stored credentials.
+func userPasswordSecret(objectKey types.NamespacedName, user User) corev1.Secret {
+	return secret.Builder().
+		SetName(user.PasswordSecretName).
+		SetNamespace(objectKey.Namespace).
+		SetField(user.PasswordSecretKey, "TDg_DESiScDrJV6").
+		Build()
+}
+
 // validScramCredentialsSecret returns a secret that has all valid scram credentials
 func validScramCredentialsSecret(objectKey types.NamespacedName, scramCredentialsSecretName string) corev1.Secret {
 	return secret.Builder().
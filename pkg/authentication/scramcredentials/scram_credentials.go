@@ -23,6 +23,9 @@ const (
 	// using the default MongoDB values for the number of iterations depending on mechanism
 	DefaultScramSha1Iterations   = 10000
 	DefaultScramSha256Iterations = 15000
+
+	// MinScramIterations is the lowest number of iterations MongoDB accepts for either mechanism.
+	MinScramIterations = 5000
 )
 
 type ScramCreds struct {
@@ -32,15 +35,17 @@ type ScramCreds struct {
 	StoredKey      string `json:"storedKey"`
 }
 
-func ComputeScramSha256Creds(password string, salt []byte) (ScramCreds, error) {
+// ComputeScramSha256Creds computes SCRAM-SHA-256 credentials with the given number of iterations.
+func ComputeScramSha256Creds(password string, salt []byte, iterationCount int) (ScramCreds, error) {
 	base64EncodedSalt := base64.StdEncoding.EncodeToString(salt)
-	return computeScramCredentials(sha256.New, DefaultScramSha256Iterations, base64EncodedSalt, password)
+	return computeScramCredentials(sha256.New, iterationCount, base64EncodedSalt, password)
 }
 
-func ComputeScramSha1Creds(username, password string, salt []byte) (ScramCreds, error) {
+// ComputeScramSha1Creds computes SCRAM-SHA-1 credentials with the given number of iterations.
+func ComputeScramSha1Creds(username, password string, salt []byte, iterationCount int) (ScramCreds, error) {
 	base64EncodedSalt := base64.StdEncoding.EncodeToString(salt)
 	password = md5Hex(username + ":mongo:" + password)
-	return computeScramCredentials(sha1.New, DefaultScramSha1Iterations, base64EncodedSalt, password)
+	return computeScramCredentials(sha1.New, iterationCount, base64EncodedSalt, password)
 }
 
 func md5Hex(s string) string {
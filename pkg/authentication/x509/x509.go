@@ -0,0 +1,88 @@
+package x509
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+	"github.com/phamidko/opencga-operator/pkg/util/contains"
+)
+
+const (
+	// Mechanism is the name of the X.509 authentication mechanism in the automation config.
+	Mechanism = "MONGODB-X509"
+
+	// ExternalDatabase is the database users authenticated outside of the deployment are defined in.
+	ExternalDatabase = "$external"
+)
+
+// User is a user authenticated by the subject of its client certificate.
+type User struct {
+	// Subject is the distinguished name of the client certificate, which is also the name of the user.
+	Subject string
+
+	// Roles are the roles granted to the user.
+	Roles []automationconfig.Role
+}
+
+// ConvertUsers converts the X.509 users into users that can be added directly to the AutomationConfig.
+func ConvertUsers(users []User) []automationconfig.MongoDBUser {
+	acUsers := make([]automationconfig.MongoDBUser, 0, len(users))
+	for _, user := range users {
+		acUsers = append(acUsers, automationconfig.MongoDBUser{
+			Username:                   user.Subject,
+			Database:                   ExternalDatabase,
+			Roles:                      append([]automationconfig.Role{}, user.Roles...),
+			Mechanisms:                 []string{},
+			AuthenticationRestrictions: []string{},
+		})
+	}
+	return acUsers
+}
+
+// EnableDeploymentMechanism allows the users of the deployment to authenticate with their client certificates.
+func EnableDeploymentMechanism(auth *automationconfig.Auth) {
+	if !contains.String(auth.DeploymentAuthMechanisms, Mechanism) {
+		auth.DeploymentAuthMechanisms = append(auth.DeploymentAuthMechanisms, Mechanism)
+	}
+}
+
+// ValidateSubject returns an error if the subject is not a distinguished name in the RFC 4514 string format,
+// e.g. "CN=opencga-analysis,OU=services,O=zetta".
+func ValidateSubject(subject string) error {
+	if strings.TrimSpace(subject) == "" {
+		return errors.New("the subject must not be empty")
+	}
+	for _, rdn := range splitUnescaped(subject, ',') {
+		for _, attribute := range splitUnescaped(rdn, '+') {
+			parts := strings.SplitN(attribute, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+				return fmt.Errorf("%q is not an attribute of the form type=value", attribute)
+			}
+		}
+	}
+	return nil
+}
+
+// splitUnescaped splits s around the separator, unless it is escaped with a backslash.
+func splitUnescaped(s string, separator rune) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == separator:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(c)
+	}
+	return append(parts, current.String())
+}
@@ -0,0 +1,55 @@
+package x509
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+)
+
+func TestConvertUsers(t *testing.T) {
+	users := ConvertUsers([]User{
+		{Subject: "CN=analysis,OU=services,O=zetta", Roles: []automationconfig.Role{{Role: "read", Database: "opencga_catalog"}}},
+		{Subject: "CN=ingest,OU=services,O=zetta"},
+	})
+
+	assert.Len(t, users, 2)
+	assert.Equal(t, "CN=analysis,OU=services,O=zetta", users[0].Username)
+	assert.Equal(t, "$external", users[0].Database)
+	assert.Equal(t, []automationconfig.Role{{Role: "read", Database: "opencga_catalog"}}, users[0].Roles)
+	assert.Nil(t, users[0].ScramSha256Creds)
+	assert.Nil(t, users[0].ScramSha1Creds)
+	assert.NotNil(t, users[1].Roles, "the agent expects a list of roles")
+}
+
+func TestEnableDeploymentMechanism(t *testing.T) {
+	auth := automationconfig.Auth{DeploymentAuthMechanisms: []string{"SCRAM-SHA-256"}}
+	EnableDeploymentMechanism(&auth)
+	EnableDeploymentMechanism(&auth)
+	assert.Equal(t, []string{"SCRAM-SHA-256", Mechanism}, auth.DeploymentAuthMechanisms)
+}
+
+func TestValidateSubject(t *testing.T) {
+	valid := []string{
+		"CN=analysis",
+		"CN=analysis,OU=services,O=zetta",
+		"CN=analysis+UID=42,O=zetta",
+		`CN=Smith\, John,O=zetta`,
+	}
+	for _, subject := range valid {
+		assert.NoError(t, ValidateSubject(subject), subject)
+	}
+
+	invalid := []string{
+		"",
+		"analysis",
+		"CN=analysis,",
+		"CN=,O=zetta",
+		"=analysis",
+		"CN=Smith, John,O=zetta",
+	}
+	for _, subject := range invalid {
+		assert.Error(t, ValidateSubject(subject), subject)
+	}
+}
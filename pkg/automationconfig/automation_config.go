@@ -5,14 +5,20 @@ import (
 	"encoding/json"
 
 	"github.com/phamidko/opencga-operator/pkg/authentication/scramcredentials"
-	"github.com/stretchr/objx"
 )
 
 const (
-	Mongod                ProcessType = "mongod"
-	DefaultMongoDBDataDir string      = "/data"
-	DefaultDBPort         int         = 27017
-	DefaultAgentLogPath   string      = "/var/log/mongodb-mms-automation"
+	OpenCGARest                  ProcessType = "opencgaRest"
+	DefaultWorkspace             string      = "/opt/opencga/sessions"
+	DefaultRestPort              int         = 9090
+	DefaultCatalogDatabasePrefix string      = "opencga"
+	DefaultLogLevel              string      = "info"
+	DefaultMinThreads            int         = 8
+	DefaultMaxThreads            int         = 200
+	DefaultIdleTimeoutMs         int         = 60000
+	DefaultInitialHeap           string      = "512m"
+	DefaultMaxHeap               string      = "2g"
+	DefaultAgentLogPath          string      = "/var/log/mongodb-mms-automation"
 )
 
 type AutomationConfig struct {
@@ -45,53 +51,119 @@ type MonitoringVersion struct {
 }
 
 type Process struct {
-	Name                        string      `json:"name"`
-	Disabled                    bool        `json:"disabled"`
-	HostName                    string      `json:"hostname"`
-	Args26                      objx.Map    `json:"args2_6"`
-	FeatureCompatibilityVersion string      `json:"featureCompatibilityVersion"`
-	ProcessType                 ProcessType `json:"processType"`
-	Version                     string      `json:"version"`
-	AuthSchemaVersion           int         `json:"authSchemaVersion"`
+	Name                        string               `json:"name"`
+	Disabled                    bool                 `json:"disabled"`
+	HostName                    string               `json:"hostname"`
+	OpenCGA                     OpenCGAProcessConfig `json:"opencga"`
+	FeatureCompatibilityVersion string               `json:"featureCompatibilityVersion"`
+	ProcessType                 ProcessType          `json:"processType"`
+	Version                     string               `json:"version"`
+	AuthSchemaVersion           int                  `json:"authSchemaVersion"`
+}
+
+// OpenCGAProcessConfig describes how the agent should run an OpenCGA server.
+type OpenCGAProcessConfig struct {
+	// Workspace is the directory OpenCGA stores the session and job files in.
+	Workspace string `json:"workspace"`
+	// LogLevel is the log level of the OpenCGA server, e.g. "info" or "debug".
+	LogLevel string `json:"logLevel"`
+	// CatalogDatabasePrefix is prepended to the name of every catalog database.
+	CatalogDatabasePrefix string     `json:"catalogDatabasePrefix"`
+	Rest                  RestServer `json:"rest"`
+	JVM                   JVMOptions `json:"jvm"`
+}
+
+// RestServer configures the embedded Jetty server the REST API is served from.
+type RestServer struct {
+	Port       int        `json:"port"`
+	ThreadPool ThreadPool `json:"threadPool"`
+	// TLS configures TLS for the REST API, it is disabled if nil.
+	TLS *RestTLS `json:"tls,omitempty"`
+}
+
+// RestTLS configures TLS for the REST API of a process.
+type RestTLS struct {
+	Mode TLSMode `json:"mode"`
+	// CertificateKeyFile is the path of the PEM file holding the certificate followed by its private key.
+	CertificateKeyFile string `json:"certificateKeyFile,omitempty"`
+}
+
+// GetTLSMode returns the TLS mode of the REST API, disabled unless TLS has been configured.
+func (r RestServer) GetTLSMode() TLSMode {
+	if r.TLS == nil {
+		return TLSModeDisabled
+	}
+	return r.TLS.Mode
+}
+
+type ThreadPool struct {
+	MinThreads    int `json:"minThreads"`
+	MaxThreads    int `json:"maxThreads"`
+	IdleTimeoutMs int `json:"idleTimeoutMs"`
+}
+
+type JVMOptions struct {
+	// InitialHeap is passed to the JVM as -Xms, e.g. "512m".
+	InitialHeap string `json:"xms"`
+	// MaxHeap is passed to the JVM as -Xmx, e.g. "2g".
+	MaxHeap      string   `json:"xmx"`
+	ExtraOptions []string `json:"extraOptions,omitempty"`
+}
+
+// defaultOpenCGAProcessConfig returns the configuration OpenCGA ships with.
+func defaultOpenCGAProcessConfig() OpenCGAProcessConfig {
+	return OpenCGAProcessConfig{
+		Workspace:             DefaultWorkspace,
+		LogLevel:              DefaultLogLevel,
+		CatalogDatabasePrefix: DefaultCatalogDatabasePrefix,
+		Rest: RestServer{
+			Port: DefaultRestPort,
+			ThreadPool: ThreadPool{
+				MinThreads:    DefaultMinThreads,
+				MaxThreads:    DefaultMaxThreads,
+				IdleTimeoutMs: DefaultIdleTimeoutMs,
+			},
+		},
+		JVM: JVMOptions{
+			InitialHeap: DefaultInitialHeap,
+			MaxHeap:     DefaultMaxHeap,
+		},
+	}
 }
 
 func (p *Process) SetPort(port int) *Process {
-	return p.SetArgs26Field("net.port", port)
+	p.OpenCGA.Rest.Port = port
+	return p
 }
 
-func (p *Process) SetStoragePath(storagePath string) *Process {
-	return p.SetArgs26Field("storage.dbPath", storagePath)
+func (p *Process) SetWorkspace(workspace string) *Process {
+	p.OpenCGA.Workspace = workspace
+	return p
 }
 
-func (p *Process) SetReplicaSetName(replSetName string) *Process {
-	return p.SetArgs26Field("replication.replSetName", replSetName)
+func (p *Process) SetLogLevel(logLevel string) *Process {
+	p.OpenCGA.LogLevel = logLevel
+	return p
 }
 
-func (p *Process) SetSystemLog(systemLog SystemLog) *Process {
-	return p.SetArgs26Field("systemLog.path", systemLog.Path).
-		SetArgs26Field("systemLog.destination", systemLog.Destination).
-		SetArgs26Field("systemLog.logAppend", systemLog.LogAppend)
+func (p *Process) SetCatalogDatabasePrefix(prefix string) *Process {
+	p.OpenCGA.CatalogDatabasePrefix = prefix
+	return p
 }
 
-func (p *Process) SetWiredTigerCache(cacheSizeGb *float32) *Process {
-	if cacheSizeGb == nil {
-		return p
-	}
-	return p.SetArgs26Field("storage.wiredTiger.engineConfig.cacheSizeGB", cacheSizeGb)
+func (p *Process) SetThreadPool(threadPool ThreadPool) *Process {
+	p.OpenCGA.Rest.ThreadPool = threadPool
+	return p
 }
 
-// SetArgs26Field should be used whenever any args26 field needs to be set. It ensures
-// that the args26 map is non nil and assigns the given value.
-func (p *Process) SetArgs26Field(fieldName string, value interface{}) *Process {
-	p.ensureArgs26()
-	p.Args26.Set(fieldName, value)
+func (p *Process) SetRestTLS(tls *RestTLS) *Process {
+	p.OpenCGA.Rest.TLS = tls
 	return p
 }
 
-func (p *Process) ensureArgs26() {
-	if p.Args26 == nil {
-		p.Args26 = objx.New(map[string]interface{}{})
-	}
+func (p *Process) SetJVMOptions(jvm JVMOptions) *Process {
+	p.OpenCGA.JVM = jvm
+	return p
 }
 
 type TLSMode string
@@ -105,20 +177,6 @@ const (
 
 type ProcessType string
 
-type SystemLog struct {
-	Destination string `json:"destination"`
-	Path        string `json:"path"`
-	LogAppend   bool   `json:"logAppend"`
-}
-
-type WiredTiger struct {
-	EngineConfig EngineConfig `json:"engineConfig"`
-}
-
-type EngineConfig struct {
-	CacheSizeGB float32 `json:"cacheSizeGB"`
-}
-
 type ReplicaSet struct {
 	Id              string             `json:"_id"`
 	Members         []ReplicaSetMember `json:"members"`
@@ -183,6 +241,10 @@ type Auth struct {
 	KeyFileWindows string `json:"keyfileWindows,omitempty"`
 	// AutoPwd is a required field when going from `Disabled=false` to `Disabled=true`
 	AutoPwd string `json:"autoPwd,omitempty"`
+	// PreviousKey is the contents of the KeyFile being rotated out, the agents accept both keys until it is removed
+	PreviousKey string `json:"previousKey,omitempty"`
+	// PreviousAutoPwd is the password of the Automation Agent being rotated out, the agents accept both passwords until it is removed
+	PreviousAutoPwd string `json:"previousAutoPwd,omitempty"`
 }
 
 type CustomRole struct {
@@ -220,6 +282,9 @@ type MongoDBUser struct {
 	// ScramShaCreds are generated by the operator.
 	ScramSha256Creds *scramcredentials.ScramCreds `json:"scramSha256Creds"`
 	ScramSha1Creds   *scramcredentials.ScramCreds `json:"scramSha1Creds"`
+
+	// DeleteRequested asks the agents to remove the user from the deployment.
+	DeleteRequested bool `json:"deleteRequested,omitempty"`
 }
 
 type Role struct {
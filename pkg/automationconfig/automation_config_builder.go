@@ -46,11 +46,11 @@ type Builder struct {
 	processModifications []func(int, *Process)
 	modifications        []Modification
 	auth                 *Auth
+	roles                []CustomRole
 	cafilePath           string
 	sslConfig            *TLS
 	tlsConfig            *TLS
-	dataDir              string
-	port                 int
+	opencga              OpenCGAProcessConfig
 }
 
 func NewBuilder() *Builder {
@@ -62,7 +62,7 @@ func NewBuilder() *Builder {
 		backupVersions:       []BackupVersion{},
 		monitoringVersions:   []MonitoringVersion{},
 		processModifications: []func(int, *Process){},
-		port:                 DefaultDBPort,
+		opencga:              defaultOpenCGAProcessConfig(),
 		tlsConfig:            nil,
 		sslConfig:            nil,
 	}
@@ -118,13 +118,39 @@ func (b *Builder) SetName(name string) *Builder {
 	return b
 }
 
-func (b *Builder) SetDataDir(dataDir string) *Builder {
-	b.dataDir = dataDir
+func (b *Builder) SetWorkspace(workspace string) *Builder {
+	b.opencga.Workspace = workspace
 	return b
 }
 
 func (b *Builder) SetPort(port int) *Builder {
-	b.port = port
+	b.opencga.Rest.Port = port
+	return b
+}
+
+func (b *Builder) SetLogLevel(logLevel string) *Builder {
+	b.opencga.LogLevel = logLevel
+	return b
+}
+
+func (b *Builder) SetCatalogDatabasePrefix(prefix string) *Builder {
+	b.opencga.CatalogDatabasePrefix = prefix
+	return b
+}
+
+func (b *Builder) SetThreadPool(threadPool ThreadPool) *Builder {
+	b.opencga.Rest.ThreadPool = threadPool
+	return b
+}
+
+// SetRestTLS configures TLS for the REST API of every process.
+func (b *Builder) SetRestTLS(tls RestTLS) *Builder {
+	b.opencga.Rest.TLS = &tls
+	return b
+}
+
+func (b *Builder) SetJVMOptions(jvm JVMOptions) *Builder {
+	b.opencga.JVM = jvm
 	return b
 }
 
@@ -180,6 +206,32 @@ func (b *Builder) SetAuth(auth Auth) *Builder {
 	return b
 }
 
+// AddRoles adds custom roles. The privileges, inherited roles and authentication restrictions of every role are
+// set to empty lists if they are nil, as the agents reject null values.
+func (b *Builder) AddRoles(roles ...CustomRole) *Builder {
+	for _, role := range roles {
+		if role.Privileges == nil {
+			role.Privileges = []Privilege{}
+		}
+		if role.Roles == nil {
+			role.Roles = []Role{}
+		}
+		restrictions := make([]AuthenticationRestriction, 0, len(role.AuthenticationRestrictions))
+		for _, restriction := range role.AuthenticationRestrictions {
+			if restriction.ClientSource == nil {
+				restriction.ClientSource = []string{}
+			}
+			if restriction.ServerAddress == nil {
+				restriction.ServerAddress = []string{}
+			}
+			restrictions = append(restrictions, restriction)
+		}
+		role.AuthenticationRestrictions = restrictions
+		b.roles = append(b.roles, role)
+	}
+	return b
+}
+
 func (b *Builder) AddProcessModification(f func(int, *Process)) *Builder {
 	b.processModifications = append(b.processModifications, f)
 	return b
@@ -193,7 +245,8 @@ func (b *Builder) AddModifications(mod ...Modification) *Builder {
 func (b *Builder) setFeatureCompatibilityVersionIfUpgradeIsHappening() error {
 	// If we are upgrading, we can't increase featureCompatibilityVersion
 	// as that will make the agent never reach goal state
-	if len(b.previousAC.Processes) > 0 && b.fcv == "" {
+	// OpenCGA versions don't have a featureCompatibilityVersion, so there is nothing to keep.
+	if len(b.previousAC.Processes) > 0 && b.fcv == "" && b.previousAC.Processes[0].FeatureCompatibilityVersion != "" {
 
 		// Create a x.y.0 version from FCV x.y
 		previousFCV := b.previousAC.Processes[0].FeatureCompatibilityVersion
@@ -238,11 +291,6 @@ func (b *Builder) Build() (AutomationConfig, error) {
 		return AutomationConfig{}, errors.Errorf("can't build the automation config: %s", err)
 	}
 
-	dataDir := DefaultMongoDBDataDir
-	if b.dataDir != "" {
-		dataDir = b.dataDir
-	}
-
 	for i, h := range hostnames {
 		// Arbiters start counting from b.members and up
 		isArbiter := i >= b.members
@@ -268,14 +316,17 @@ func (b *Builder) Build() (AutomationConfig, error) {
 			Name:                        toProcessName(b.name, processIndex, isArbiter),
 			HostName:                    h,
 			FeatureCompatibilityVersion: fcv,
-			ProcessType:                 Mongod,
+			ProcessType:                 OpenCGARest,
 			Version:                     b.openCGAVersion,
 			AuthSchemaVersion:           5,
 		}
-
-		process.SetPort(b.port)
-		process.SetStoragePath(dataDir)
-		process.SetReplicaSetName(b.name)
+		process.SetWorkspace(b.opencga.Workspace).
+			SetPort(b.opencga.Rest.Port).
+			SetLogLevel(b.opencga.LogLevel).
+			SetCatalogDatabasePrefix(b.opencga.CatalogDatabasePrefix).
+			SetThreadPool(b.opencga.Rest.ThreadPool).
+			SetJVMOptions(copyJVMOptions(b.opencga.JVM)).
+			SetRestTLS(copyRestTLS(b.opencga.Rest.TLS))
 
 		for _, mod := range b.processModifications {
 			mod(i, process)
@@ -326,6 +377,7 @@ func (b *Builder) Build() (AutomationConfig, error) {
 		Versions:           b.versions,
 		Options:            b.options,
 		Auth:               *b.auth,
+		Roles:              b.roles,
 		TLSConfig: &TLS{
 			ClientCertificateMode: ClientCertificateModeOptional,
 			CAFilePath:            b.cafilePath,
@@ -356,6 +408,23 @@ func (b *Builder) Build() (AutomationConfig, error) {
 	return currentAc, nil
 }
 
+// copyJVMOptions makes sure processes don't share the ExtraOptions slice, so that a
+// process modification can't change the options of every other process.
+func copyJVMOptions(jvm JVMOptions) JVMOptions {
+	if jvm.ExtraOptions != nil {
+		jvm.ExtraOptions = append([]string{}, jvm.ExtraOptions...)
+	}
+	return jvm
+}
+
+func copyRestTLS(tls *RestTLS) *RestTLS {
+	if tls == nil {
+		return nil
+	}
+	tlsCopy := *tls
+	return &tlsCopy
+}
+
 func toProcessName(name string, index int, isArbiter bool) string {
 	if isArbiter {
 		return fmt.Sprintf("%s-arb-%d", name, index)
@@ -1,14 +1,32 @@
 package automationconfig
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"io/ioutil"
 
 	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
-const ConfigKey = "cluster-config.json"
+const (
+	ConfigKey = "cluster-config.json"
+
+	// EncodingKey is set to GzipEncoding in the Secrets whose ConfigKey holds the automation config compressed
+	// with gzip. The automation config is stored as plain JSON if it is not set.
+	EncodingKey  = "cluster-config.encoding"
+	GzipEncoding = "gzip"
+
+	// CompressionThreshold is the size of the JSON automation config above which it is compressed.
+	CompressionThreshold = 256 * 1024
+
+	// MaxSize is the size of the stored automation config above which it is rejected. Secrets hold up to 1 MiB,
+	// some room is left for the metadata of the Secret.
+	MaxSize = 1000 * 1024
+)
 
 // ReadFromSecret returns the AutomationConfig present in the given Secret. If the Secret is not
 // found, it is not considered an error and an empty AutomationConfig is returned.
@@ -20,7 +38,7 @@ func ReadFromSecret(secretGetter secret.Getter, secretNsName types.NamespacedNam
 		}
 		return AutomationConfig{}, err
 	}
-	return FromBytes(acSecret.Data[ConfigKey])
+	return fromSecretData(acSecret.Data)
 }
 
 // EnsureSecret makes sure that the AutomationConfig secret exists with the desired config.
@@ -36,16 +54,9 @@ func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName t
 		return AutomationConfig{}, err
 	}
 
-	acBytes, err := json.Marshal(desiredAutomationConfig)
-	if err != nil {
-		return AutomationConfig{}, err
-	}
-	if existingAcBytes, ok := existingSecret.Data[ConfigKey]; !ok {
-		// the secret exists but the key is not present. We can update the secret
-		existingSecret.Data[ConfigKey] = acBytes
-	} else {
+	if _, ok := existingSecret.Data[ConfigKey]; ok {
 		// the secret already exists, we should check to see if we're making any changes.
-		existingAutomationConfig, err := FromBytes(existingAcBytes)
+		existingAutomationConfig, err := fromSecretData(existingSecret.Data)
 		if err != nil {
 			return AutomationConfig{}, err
 		}
@@ -57,7 +68,19 @@ func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName t
 		if areEqual {
 			return existingAutomationConfig, nil
 		}
-		existingSecret.Data[ConfigKey] = acBytes
+	}
+
+	// the secret exists but the key is not present, or the config changed. We can update the secret
+	data, err := toSecretData(desiredAutomationConfig)
+	if err != nil {
+		return AutomationConfig{}, err
+	}
+	if existingSecret.Data == nil {
+		existingSecret.Data = map[string][]byte{}
+	}
+	delete(existingSecret.Data, EncodingKey)
+	for key, value := range data {
+		existingSecret.Data[key] = value
 	}
 
 	existingSecret.Name = secretNsName.Name
@@ -66,7 +89,7 @@ func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName t
 }
 
 func createNewAutomationConfigSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName types.NamespacedName, owner []metav1.OwnerReference, desiredAutomation AutomationConfig) (AutomationConfig, error) {
-	acBytes, err := json.Marshal(desiredAutomation)
+	data, err := toSecretData(desiredAutomation)
 	if err != nil {
 		return AutomationConfig{}, err
 	}
@@ -74,7 +97,7 @@ func createNewAutomationConfigSecret(secretGetUpdateCreator secret.GetUpdateCrea
 	newSecret := secret.Builder().
 		SetName(secretNsName.Name).
 		SetNamespace(secretNsName.Namespace).
-		SetField(ConfigKey, string(acBytes)).
+		SetByteData(data).
 		SetOwnerReferences(owner).
 		Build()
 
@@ -83,3 +106,48 @@ func createNewAutomationConfigSecret(secretGetUpdateCreator secret.GetUpdateCrea
 	}
 	return desiredAutomation, nil
 }
+
+// toSecretData returns the Secret data holding the given AutomationConfig. Automation configs larger than
+// CompressionThreshold are compressed, and an error is returned if the result is still larger than MaxSize.
+func toSecretData(ac AutomationConfig) (map[string][]byte, error) {
+	acBytes, err := json.Marshal(ac)
+	if err != nil {
+		return nil, err
+	}
+	if len(acBytes) <= CompressionThreshold {
+		return map[string][]byte{ConfigKey: acBytes}, nil
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(acBytes); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if compressed.Len() > MaxSize {
+		return nil, errors.Errorf("the automation config is %d bytes once compressed, more than the %d bytes a Secret can hold, reduce the number of users, roles or versions",
+			compressed.Len(), MaxSize)
+	}
+	return map[string][]byte{ConfigKey: compressed.Bytes(), EncodingKey: []byte(GzipEncoding)}, nil
+}
+
+// fromSecretData returns the AutomationConfig held by the given Secret data, decompressing it if needed.
+func fromSecretData(data map[string][]byte) (AutomationConfig, error) {
+	acBytes := data[ConfigKey]
+	if encoding, ok := data[EncodingKey]; ok {
+		if string(encoding) != GzipEncoding {
+			return AutomationConfig{}, errors.Errorf("unsupported automation config encoding %q", encoding)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(acBytes))
+		if err != nil {
+			return AutomationConfig{}, errors.Errorf("could not decompress the automation config: %s", err)
+		}
+		defer reader.Close()
+		if acBytes, err = ioutil.ReadAll(reader); err != nil {
+			return AutomationConfig{}, errors.Errorf("could not decompress the automation config: %s", err)
+		}
+	}
+	return FromBytes(acBytes)
+}
@@ -1,7 +1,10 @@
 package automationconfig
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/phamidko/opencga-operator/pkg/kube/secret"
@@ -57,6 +60,65 @@ func TestEnsureSecret(t *testing.T) {
 	})
 
 }
+func TestEnsureSecret_Compression(t *testing.T) {
+	secretNsName := types.NamespacedName{Name: "ac-secret", Namespace: "test-namespace"}
+	withRole := func(name string) AutomationConfig {
+		ac, err := newAutomationConfigBuilder().AddRoles(CustomRole{Role: name, DB: "admin"}).Build()
+		assert.NoError(t, err)
+		return ac
+	}
+	assertEqual := func(t *testing.T, expected, actual AutomationConfig, msgAndArgs ...interface{}) {
+		areEqual, err := AreEqual(expected, actual)
+		assert.NoError(t, err)
+		assert.True(t, areEqual, msgAndArgs...)
+	}
+	secretGetUpdateCreator := &mockSecretGetUpdateCreator{}
+
+	t.Run("Large automation configs are compressed", func(t *testing.T) {
+		large := withRole(strings.Repeat("a", CompressionThreshold))
+		_, err := EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, large)
+		assert.NoError(t, err)
+
+		acSecret, err := secretGetUpdateCreator.GetSecret(secretNsName)
+		assert.NoError(t, err)
+		assert.Equal(t, GzipEncoding, string(acSecret.Data[EncodingKey]))
+		assert.Less(t, len(acSecret.Data[ConfigKey]), CompressionThreshold)
+
+		ac, err := ReadFromSecret(secretGetUpdateCreator, secretNsName)
+		assert.NoError(t, err)
+		assertEqual(t, large, ac)
+
+		ac, err = EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, large)
+		assert.NoError(t, err)
+		assertEqual(t, large, ac, "the compressed automation config should be compared with the desired one")
+	})
+	t.Run("Small automation configs are stored as plain JSON", func(t *testing.T) {
+		small := withRole("analyst")
+		_, err := EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, small)
+		assert.NoError(t, err)
+
+		acSecret, err := secretGetUpdateCreator.GetSecret(secretNsName)
+		assert.NoError(t, err)
+		assert.NotContains(t, acSecret.Data, EncodingKey)
+		ac, err := FromBytes(acSecret.Data[ConfigKey])
+		assert.NoError(t, err)
+		assertEqual(t, small, ac)
+	})
+	t.Run("Automation configs too large for a Secret are rejected", func(t *testing.T) {
+		random := make([]byte, MaxSize+MaxSize/2)
+		_, err := rand.Read(random)
+		assert.NoError(t, err)
+
+		_, err = EnsureSecret(secretGetUpdateCreator, secretNsName, []metav1.OwnerReference{}, withRole(base64.StdEncoding.EncodeToString(random)))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "reduce the number of users, roles or versions")
+
+		ac, err := ReadFromSecret(secretGetUpdateCreator, secretNsName)
+		assert.NoError(t, err)
+		assertEqual(t, withRole("analyst"), ac, "the stored automation config should be left untouched")
+	})
+}
+
 func newAutomationConfig() (AutomationConfig, error) {
 	return NewBuilder().Build()
 }
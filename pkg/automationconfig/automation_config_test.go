@@ -1,9 +1,11 @@
 package automationconfig
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/objx"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,10 +40,10 @@ func TestBuildAutomationConfig(t *testing.T) {
 	assert.Equal(t, 1, ac.Version)
 
 	for i, p := range ac.Processes {
-		assert.Equal(t, Mongod, p.ProcessType)
+		assert.Equal(t, OpenCGARest, p.ProcessType)
 		assert.Equal(t, fmt.Sprintf("my-rs-%d.my-ns.svc.cluster.local", i), p.HostName)
-		assert.Equal(t, DefaultMongoDBDataDir, p.Args26.Get("storage.dbPath").Data())
-		assert.Equal(t, "my-rs", p.Args26.Get("replication.replSetName").Data())
+		assert.Equal(t, DefaultWorkspace, p.OpenCGA.Workspace)
+		assert.Equal(t, DefaultCatalogDatabasePrefix, p.OpenCGA.CatalogDatabasePrefix)
 		assert.Equal(t, toProcessName("my-rs", i, false), p.Name)
 		assert.Equal(t, "4.2.0", p.Version)
 		assert.Equal(t, "4.0", p.FeatureCompatibilityVersion)
@@ -265,10 +267,140 @@ func TestProcessHasPortSetToDefault(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, ac.Processes, 3)
 	for _, process := range ac.Processes {
-		assert.Equal(t, 27017, process.Args26.Get("net.port").Data())
+		assert.Equal(t, DefaultRestPort, process.OpenCGA.Rest.Port)
 	}
 }
 
+func TestProcessHasOpenCGADefaults(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetOpenCGAVersion("2.2.0").
+		SetMembers(1).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, defaultOpenCGAProcessConfig(), ac.Processes[0].OpenCGA)
+	assert.Equal(t, DefaultLogLevel, ac.Processes[0].OpenCGA.LogLevel)
+	assert.Equal(t, ThreadPool{MinThreads: 8, MaxThreads: 200, IdleTimeoutMs: 60000}, ac.Processes[0].OpenCGA.Rest.ThreadPool)
+	assert.Equal(t, "512m", ac.Processes[0].OpenCGA.JVM.InitialHeap)
+	assert.Equal(t, "2g", ac.Processes[0].OpenCGA.JVM.MaxHeap)
+}
+
+func TestProcessOpenCGASettings(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetOpenCGAVersion("2.2.0").
+		SetMembers(2).
+		SetPort(8080).
+		SetWorkspace("/workspace").
+		SetLogLevel("debug").
+		SetCatalogDatabasePrefix("prod").
+		SetThreadPool(ThreadPool{MinThreads: 4, MaxThreads: 50, IdleTimeoutMs: 1000}).
+		SetJVMOptions(JVMOptions{InitialHeap: "1g", MaxHeap: "4g", ExtraOptions: []string{"-XX:+UseG1GC"}}).
+		AddProcessModification(func(i int, p *Process) {
+			p.OpenCGA.JVM.ExtraOptions[0] = fmt.Sprintf("-Dprocess=%d", i)
+		}).
+		Build()
+
+	assert.NoError(t, err)
+	for i, p := range ac.Processes {
+		assert.Equal(t, 8080, p.OpenCGA.Rest.Port)
+		assert.Equal(t, "/workspace", p.OpenCGA.Workspace)
+		assert.Equal(t, "debug", p.OpenCGA.LogLevel)
+		assert.Equal(t, "prod", p.OpenCGA.CatalogDatabasePrefix)
+		assert.Equal(t, ThreadPool{MinThreads: 4, MaxThreads: 50, IdleTimeoutMs: 1000}, p.OpenCGA.Rest.ThreadPool)
+		assert.Equal(t, "1g", p.OpenCGA.JVM.InitialHeap)
+		assert.Equal(t, "4g", p.OpenCGA.JVM.MaxHeap)
+		assert.Equal(t, []string{fmt.Sprintf("-Dprocess=%d", i)}, p.OpenCGA.JVM.ExtraOptions, "process modifications should not leak into other processes")
+	}
+}
+
+func TestRestTLS(t *testing.T) {
+	ac, err := NewBuilder().SetMembers(3).Build()
+	assert.NoError(t, err)
+	for _, p := range ac.Processes {
+		assert.Nil(t, p.OpenCGA.Rest.TLS)
+		assert.Equal(t, TLSModeDisabled, p.OpenCGA.Rest.GetTLSMode())
+	}
+
+	ac, err = NewBuilder().
+		SetMembers(3).
+		SetCAFilePath("/tls/ca/ca.crt").
+		SetRestTLS(RestTLS{Mode: TLSModeAllowed, CertificateKeyFile: "/tls/abc.pem"}).
+		AddProcessModification(func(i int, p *Process) {
+			if i == 0 {
+				p.OpenCGA.Rest.TLS.Mode = TLSModeRequired
+			}
+		}).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "/tls/ca/ca.crt", ac.TLSConfig.CAFilePath)
+	assert.Equal(t, TLSModeRequired, ac.Processes[0].OpenCGA.Rest.GetTLSMode())
+	for _, p := range ac.Processes[1:] {
+		assert.Equal(t, TLSModeAllowed, p.OpenCGA.Rest.GetTLSMode(), "process modifications should not leak into other processes")
+		assert.Equal(t, "/tls/abc.pem", p.OpenCGA.Rest.TLS.CertificateKeyFile)
+	}
+}
+
+func TestProcessJSONRoundTrip(t *testing.T) {
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetOpenCGAVersion("2.2.0").
+		SetMembers(3).
+		SetLogLevel("warn").
+		SetJVMOptions(JVMOptions{InitialHeap: "1g", MaxHeap: "3g", ExtraOptions: []string{"-XX:+UseG1GC"}}).
+		Build()
+	assert.NoError(t, err)
+
+	bytes, err := json.Marshal(ac)
+	assert.NoError(t, err)
+
+	var fromJSON AutomationConfig
+	assert.NoError(t, json.Unmarshal(bytes, &fromJSON))
+	assert.Equal(t, ac.Processes, fromJSON.Processes)
+
+	t.Run("Fields are serialized with the names the agent expects", func(t *testing.T) {
+		var raw map[string]interface{}
+		assert.NoError(t, json.Unmarshal(bytes, &raw))
+		process := objx.New(raw["processes"].([]interface{})[0])
+
+		assert.Equal(t, "opencgaRest", process.Get("processType").Str())
+		assert.Equal(t, float64(DefaultRestPort), process.Get("opencga.rest.port").Float64())
+		assert.Equal(t, float64(DefaultMaxThreads), process.Get("opencga.rest.threadPool.maxThreads").Float64())
+		assert.Equal(t, "1g", process.Get("opencga.jvm.xms").Str())
+		assert.Equal(t, "3g", process.Get("opencga.jvm.xmx").Str())
+		assert.Equal(t, "warn", process.Get("opencga.logLevel").Str())
+		assert.Equal(t, DefaultCatalogDatabasePrefix, process.Get("opencga.catalogDatabasePrefix").Str())
+		assert.False(t, process.Has("args2_6"))
+	})
+}
+
+func TestPreviousAutomationConfigWithoutFCV(t *testing.T) {
+	previousAC, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetOpenCGAVersion("2.1.0").
+		SetMembers(3).
+		Build()
+	assert.NoError(t, err)
+	assert.Empty(t, previousAC.Processes[0].FeatureCompatibilityVersion)
+
+	ac, err := NewBuilder().
+		SetName("my-rs").
+		SetDomain("my-ns.svc.cluster.local").
+		SetOpenCGAVersion("2.2.0").
+		SetMembers(3).
+		SetPreviousAutomationConfig(previousAC).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.0", ac.Processes[0].Version)
+	assert.Equal(t, previousAC.Version+1, ac.Version)
+}
+
 func TestModifications(t *testing.T) {
 	incrementVersion := func(config *AutomationConfig) {
 		config.Version += 1
@@ -283,6 +415,36 @@ func TestModifications(t *testing.T) {
 	assert.Equal(t, 4, ac.Version)
 }
 
+func TestCustomRoles(t *testing.T) {
+	catalog := "opencga_catalog"
+	ac, err := NewBuilder().
+		AddRoles(CustomRole{
+			Role: "analyst",
+			DB:   "admin",
+			Privileges: []Privilege{
+				{Resource: Resource{DB: &catalog}, Actions: []string{"find"}},
+			},
+			AuthenticationRestrictions: []AuthenticationRestriction{
+				{ClientSource: []string{"10.0.0.0/8"}},
+			},
+		}).
+		AddRoles(CustomRole{Role: "auditor", DB: "admin"}).
+		Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, ac.Roles, 2)
+	assert.Equal(t, "analyst", ac.Roles[0].Role)
+	assert.Equal(t, []string{"find"}, ac.Roles[0].Privileges[0].Actions)
+	assert.Equal(t, []string{"10.0.0.0/8"}, ac.Roles[0].AuthenticationRestrictions[0].ClientSource)
+	assert.NotNil(t, ac.Roles[0].AuthenticationRestrictions[0].ServerAddress, "the agents reject null addresses")
+	assert.NotNil(t, ac.Roles[1].Privileges)
+	assert.NotNil(t, ac.Roles[1].Roles)
+
+	withoutRoles, err := NewBuilder().Build()
+	assert.NoError(t, err)
+	assert.Empty(t, withoutRoles.Roles)
+}
+
 func TestMongoDBVersionsConfig(t *testing.T) {
 
 	t.Run("Dummy Config is used when no versions are set", func(t *testing.T) {
@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+
+	"github.com/phamidko/opencga-operator/pkg/kube/horizontalpodautoscaler"
+	"github.com/phamidko/opencga-operator/pkg/kube/ingress"
+	"github.com/phamidko/opencga-operator/pkg/kube/job"
+	"github.com/phamidko/opencga-operator/pkg/kube/poddisruptionbudget"
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+	"github.com/phamidko/opencga-operator/pkg/kube/service"
+	"github.com/phamidko/opencga-operator/pkg/kube/statefulset"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func NewClient(c k8sClient.Client) Client {
+	return client{
+		Client: c,
+	}
+}
+
+type Client interface {
+	k8sClient.Client
+	KubernetesSecretClient
+	service.GetUpdateCreateDeleter
+	statefulset.GetUpdateCreateDeleter
+	job.GetCreateDeleter
+	ingress.GetUpdateCreateDeleter
+	poddisruptionbudget.GetUpdateCreateDeleter
+	horizontalpodautoscaler.GetUpdateCreateDeleter
+}
+
+type KubernetesSecretClient interface {
+	secret.GetUpdateCreateDeleter
+}
+
+type client struct {
+	k8sClient.Client
+}
+
+// GetSecret provides a thin wrapper and client.client to access corev1.Secret types
+func (c client) GetSecret(objectKey k8sClient.ObjectKey) (corev1.Secret, error) {
+	s := corev1.Secret{}
+	if err := c.Get(context.TODO(), objectKey, &s); err != nil {
+		return corev1.Secret{}, err
+	}
+	return s, nil
+}
+
+// UpdateSecret provides a thin wrapper and client.Client to update corev1.Secret types
+func (c client) UpdateSecret(secret corev1.Secret) error {
+	return c.Update(context.TODO(), &secret)
+}
+
+// CreateSecret provides a thin wrapper and client.Client to create corev1.Secret types
+func (c client) CreateSecret(secret corev1.Secret) error {
+	return c.Create(context.TODO(), &secret)
+}
+
+// DeleteSecret provides a thin wrapper around client.Client to delete corev1.Secret types
+func (c client) DeleteSecret(key k8sClient.ObjectKey) error {
+	s := corev1.Secret{}
+	s.Name = key.Name
+	s.Namespace = key.Namespace
+	return c.Delete(context.TODO(), &s)
+}
+
+// GetService provides a thin wrapper and client.Client to access corev1.Service types
+func (c client) GetService(objectKey k8sClient.ObjectKey) (corev1.Service, error) {
+	s := corev1.Service{}
+	if err := c.Get(context.TODO(), objectKey, &s); err != nil {
+		return corev1.Service{}, err
+	}
+	return s, nil
+}
+
+// UpdateService provides a thin wrapper and client.Client to update corev1.Service types
+func (c client) UpdateService(service corev1.Service) error {
+	return c.Update(context.TODO(), &service)
+}
+
+// CreateService provides a thin wrapper and client.Client to create corev1.Service types
+func (c client) CreateService(service corev1.Service) error {
+	return c.Create(context.TODO(), &service)
+}
+
+// DeleteService provides a thin wrapper around client.Client to delete corev1.Service types
+func (c client) DeleteService(objectKey k8sClient.ObjectKey) error {
+	svc := corev1.Service{}
+	svc.Name = objectKey.Name
+	svc.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &svc)
+}
+
+// GetStatefulSet provides a thin wrapper and client.Client to access appsv1.StatefulSet types
+func (c client) GetStatefulSet(objectKey k8sClient.ObjectKey) (appsv1.StatefulSet, error) {
+	sts := appsv1.StatefulSet{}
+	if err := c.Get(context.TODO(), objectKey, &sts); err != nil {
+		return appsv1.StatefulSet{}, err
+	}
+	return sts, nil
+}
+
+// UpdateStatefulSet provides a thin wrapper and client.Client to update appsv1.StatefulSet types
+// the updated StatefulSet is returned
+func (c client) UpdateStatefulSet(sts appsv1.StatefulSet) (appsv1.StatefulSet, error) {
+	stsToUpdate := &sts
+	err := c.Update(context.TODO(), stsToUpdate)
+	return *stsToUpdate, err
+}
+
+// CreateStatefulSet provides a thin wrapper and client.Client to create appsv1.StatefulSet types
+func (c client) CreateStatefulSet(sts appsv1.StatefulSet) error {
+	return c.Create(context.TODO(), &sts)
+}
+
+// DeleteStatefulSet provides a thin wrapper and client.Client to delete appsv1.StatefulSet types
+func (c client) DeleteStatefulSet(objectKey k8sClient.ObjectKey) error {
+	sts := appsv1.StatefulSet{}
+	sts.Name = objectKey.Name
+	sts.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &sts)
+}
+
+// GetJob provides a thin wrapper and client.Client to access batchv1.Job types
+func (c client) GetJob(objectKey k8sClient.ObjectKey) (batchv1.Job, error) {
+	j := batchv1.Job{}
+	if err := c.Get(context.TODO(), objectKey, &j); err != nil {
+		return batchv1.Job{}, err
+	}
+	return j, nil
+}
+
+// CreateJob provides a thin wrapper and client.Client to create batchv1.Job types
+func (c client) CreateJob(job batchv1.Job) error {
+	return c.Create(context.TODO(), &job)
+}
+
+// DeleteJob provides a thin wrapper around client.Client to delete batchv1.Job types. The Pods of the Job
+// are deleted in the background.
+func (c client) DeleteJob(objectKey k8sClient.ObjectKey) error {
+	j := batchv1.Job{}
+	j.Name = objectKey.Name
+	j.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &j, k8sClient.PropagationPolicy(metav1.DeletePropagationBackground))
+}
+
+// GetIngress provides a thin wrapper and client.Client to access networkingv1.Ingress types
+func (c client) GetIngress(objectKey k8sClient.ObjectKey) (networkingv1.Ingress, error) {
+	i := networkingv1.Ingress{}
+	if err := c.Get(context.TODO(), objectKey, &i); err != nil {
+		return networkingv1.Ingress{}, err
+	}
+	return i, nil
+}
+
+// UpdateIngress provides a thin wrapper and client.Client to update networkingv1.Ingress types
+func (c client) UpdateIngress(ingress networkingv1.Ingress) error {
+	return c.Update(context.TODO(), &ingress)
+}
+
+// CreateIngress provides a thin wrapper and client.Client to create networkingv1.Ingress types
+func (c client) CreateIngress(ingress networkingv1.Ingress) error {
+	return c.Create(context.TODO(), &ingress)
+}
+
+// DeleteIngress provides a thin wrapper around client.Client to delete networkingv1.Ingress types
+func (c client) DeleteIngress(objectKey k8sClient.ObjectKey) error {
+	i := networkingv1.Ingress{}
+	i.Name = objectKey.Name
+	i.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &i)
+}
+
+// GetPodDisruptionBudget provides a thin wrapper and client.Client to access policyv1.PodDisruptionBudget types
+func (c client) GetPodDisruptionBudget(objectKey k8sClient.ObjectKey) (policyv1.PodDisruptionBudget, error) {
+	pdb := policyv1.PodDisruptionBudget{}
+	if err := c.Get(context.TODO(), objectKey, &pdb); err != nil {
+		return policyv1.PodDisruptionBudget{}, err
+	}
+	return pdb, nil
+}
+
+// UpdatePodDisruptionBudget provides a thin wrapper and client.Client to update policyv1.PodDisruptionBudget types
+func (c client) UpdatePodDisruptionBudget(pdb policyv1.PodDisruptionBudget) error {
+	return c.Update(context.TODO(), &pdb)
+}
+
+// CreatePodDisruptionBudget provides a thin wrapper and client.Client to create policyv1.PodDisruptionBudget types
+func (c client) CreatePodDisruptionBudget(pdb policyv1.PodDisruptionBudget) error {
+	return c.Create(context.TODO(), &pdb)
+}
+
+// DeletePodDisruptionBudget provides a thin wrapper around client.Client to delete policyv1.PodDisruptionBudget types
+func (c client) DeletePodDisruptionBudget(objectKey k8sClient.ObjectKey) error {
+	pdb := policyv1.PodDisruptionBudget{}
+	pdb.Name = objectKey.Name
+	pdb.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &pdb)
+}
+
+// GetHorizontalPodAutoscaler provides a thin wrapper and client.Client to access autoscalingv2.HorizontalPodAutoscaler types
+func (c client) GetHorizontalPodAutoscaler(objectKey k8sClient.ObjectKey) (autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpa := autoscalingv2.HorizontalPodAutoscaler{}
+	if err := c.Get(context.TODO(), objectKey, &hpa); err != nil {
+		return autoscalingv2.HorizontalPodAutoscaler{}, err
+	}
+	return hpa, nil
+}
+
+// UpdateHorizontalPodAutoscaler provides a thin wrapper and client.Client to update autoscalingv2.HorizontalPodAutoscaler types
+func (c client) UpdateHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) error {
+	return c.Update(context.TODO(), &hpa)
+}
+
+// CreateHorizontalPodAutoscaler provides a thin wrapper and client.Client to create autoscalingv2.HorizontalPodAutoscaler types
+func (c client) CreateHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) error {
+	return c.Create(context.TODO(), &hpa)
+}
+
+// DeleteHorizontalPodAutoscaler provides a thin wrapper around client.Client to delete autoscalingv2.HorizontalPodAutoscaler types
+func (c client) DeleteHorizontalPodAutoscaler(objectKey k8sClient.ObjectKey) error {
+	hpa := autoscalingv2.HorizontalPodAutoscaler{}
+	hpa.Name = objectKey.Name
+	hpa.Namespace = objectKey.Namespace
+	return c.Delete(context.TODO(), &hpa)
+}
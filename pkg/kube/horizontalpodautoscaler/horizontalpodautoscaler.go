@@ -0,0 +1,67 @@
+package horizontalpodautoscaler
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Getter interface {
+	GetHorizontalPodAutoscaler(objectKey client.ObjectKey) (autoscalingv2.HorizontalPodAutoscaler, error)
+}
+
+type Updater interface {
+	UpdateHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) error
+}
+
+type Creator interface {
+	CreateHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) error
+}
+
+type Deleter interface {
+	DeleteHorizontalPodAutoscaler(objectKey client.ObjectKey) error
+}
+
+type GetDeleter interface {
+	Getter
+	Deleter
+}
+
+type GetUpdateCreator interface {
+	Getter
+	Updater
+	Creator
+}
+
+type GetUpdateCreateDeleter interface {
+	Getter
+	Updater
+	Creator
+	Deleter
+}
+
+// CreateOrUpdate creates the HorizontalPodAutoscaler if it doesn't exist, otherwise it replaces its spec.
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, desired autoscalingv2.HorizontalPodAutoscaler) error {
+	existing, err := getUpdateCreator.GetHorizontalPodAutoscaler(types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return getUpdateCreator.CreateHorizontalPodAutoscaler(desired)
+		}
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	return getUpdateCreator.UpdateHorizontalPodAutoscaler(desired)
+}
+
+// DeleteHorizontalPodAutoscalerIfItExists deletes the HorizontalPodAutoscaler with the given name, nothing is done if it doesn't exist.
+func DeleteHorizontalPodAutoscalerIfItExists(getterDeleter GetDeleter, hpaName types.NamespacedName) error {
+	_, err := getterDeleter.GetHorizontalPodAutoscaler(hpaName)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return getterDeleter.DeleteHorizontalPodAutoscaler(hpaName)
+}
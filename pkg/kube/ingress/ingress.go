@@ -0,0 +1,67 @@
+package ingress
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Getter interface {
+	GetIngress(objectKey client.ObjectKey) (networkingv1.Ingress, error)
+}
+
+type Updater interface {
+	UpdateIngress(ingress networkingv1.Ingress) error
+}
+
+type Creator interface {
+	CreateIngress(ingress networkingv1.Ingress) error
+}
+
+type Deleter interface {
+	DeleteIngress(objectKey client.ObjectKey) error
+}
+
+type GetDeleter interface {
+	Getter
+	Deleter
+}
+
+type GetUpdateCreator interface {
+	Getter
+	Updater
+	Creator
+}
+
+type GetUpdateCreateDeleter interface {
+	Getter
+	Updater
+	Creator
+	Deleter
+}
+
+// CreateOrUpdate creates the Ingress if it doesn't exist, otherwise it replaces its spec, labels and annotations.
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, desired networkingv1.Ingress) error {
+	existing, err := getUpdateCreator.GetIngress(types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return getUpdateCreator.CreateIngress(desired)
+		}
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	return getUpdateCreator.UpdateIngress(desired)
+}
+
+// DeleteIngressIfItExists deletes the Ingress with the given name, nothing is done if it doesn't exist.
+func DeleteIngressIfItExists(getterDeleter GetDeleter, ingressName types.NamespacedName) error {
+	_, err := getterDeleter.GetIngress(ingressName)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return getterDeleter.DeleteIngress(ingressName)
+}
@@ -0,0 +1,49 @@
+package job
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Getter interface {
+	GetJob(objectKey client.ObjectKey) (batchv1.Job, error)
+}
+
+type Creator interface {
+	CreateJob(job batchv1.Job) error
+}
+
+type Deleter interface {
+	DeleteJob(objectKey client.ObjectKey) error
+}
+
+type GetCreator interface {
+	Getter
+	Creator
+}
+
+type GetCreateDeleter interface {
+	Getter
+	Creator
+	Deleter
+}
+
+// IsComplete returns true if the Job finished successfully.
+func IsComplete(job batchv1.Job) bool {
+	return hasCondition(job, batchv1.JobComplete)
+}
+
+// IsFailed returns true if the Job failed and won't be retried anymore.
+func IsFailed(job batchv1.Job) bool {
+	return hasCondition(job, batchv1.JobFailed)
+}
+
+func hasCondition(job batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestIsCompleteAndIsFailed(t *testing.T) {
+	running := batchv1.Job{}
+	assert.False(t, IsComplete(running))
+	assert.False(t, IsFailed(running))
+
+	complete := batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}}}
+	assert.True(t, IsComplete(complete))
+	assert.False(t, IsFailed(complete))
+
+	failed := batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}}}
+	assert.False(t, IsComplete(failed))
+	assert.True(t, IsFailed(failed))
+
+	notYetFailed := batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}}}}
+	assert.False(t, IsFailed(notYetFailed))
+}
@@ -0,0 +1,67 @@
+package poddisruptionbudget
+
+import (
+	policyv1 "k8s.io/api/policy/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Getter interface {
+	GetPodDisruptionBudget(objectKey client.ObjectKey) (policyv1.PodDisruptionBudget, error)
+}
+
+type Updater interface {
+	UpdatePodDisruptionBudget(pdb policyv1.PodDisruptionBudget) error
+}
+
+type Creator interface {
+	CreatePodDisruptionBudget(pdb policyv1.PodDisruptionBudget) error
+}
+
+type Deleter interface {
+	DeletePodDisruptionBudget(objectKey client.ObjectKey) error
+}
+
+type GetDeleter interface {
+	Getter
+	Deleter
+}
+
+type GetUpdateCreator interface {
+	Getter
+	Updater
+	Creator
+}
+
+type GetUpdateCreateDeleter interface {
+	Getter
+	Updater
+	Creator
+	Deleter
+}
+
+// CreateOrUpdate creates the PodDisruptionBudget if it doesn't exist, otherwise it replaces its spec.
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, desired policyv1.PodDisruptionBudget) error {
+	existing, err := getUpdateCreator.GetPodDisruptionBudget(types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return getUpdateCreator.CreatePodDisruptionBudget(desired)
+		}
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	return getUpdateCreator.UpdatePodDisruptionBudget(desired)
+}
+
+// DeletePodDisruptionBudgetIfItExists deletes the PodDisruptionBudget with the given name, nothing is done if it doesn't exist.
+func DeletePodDisruptionBudgetIfItExists(getterDeleter GetDeleter, pdbName types.NamespacedName) error {
+	_, err := getterDeleter.GetPodDisruptionBudget(pdbName)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return getterDeleter.DeletePodDisruptionBudget(pdbName)
+}
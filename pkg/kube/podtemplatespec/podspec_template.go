@@ -0,0 +1,165 @@
+package podtemplatespec
+
+import (
+	"github.com/phamidko/opencga-operator/pkg/kube/container"
+	"github.com/phamidko/opencga-operator/pkg/util/merge"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type Modification func(*corev1.PodTemplateSpec)
+
+const (
+	notFound = -1
+)
+
+func New(templateMods ...Modification) corev1.PodTemplateSpec {
+	podTemplateSpec := corev1.PodTemplateSpec{}
+	for _, templateMod := range templateMods {
+		templateMod(&podTemplateSpec)
+	}
+	return podTemplateSpec
+}
+
+// Apply returns a function which applies a series of Modification functions to a *corev1.PodTemplateSpec
+func Apply(templateMods ...Modification) Modification {
+	return func(template *corev1.PodTemplateSpec) {
+		for _, f := range templateMods {
+			f(template)
+		}
+	}
+}
+
+// NOOP is a valid Modification which applies no changes
+func NOOP() Modification {
+	return func(spec *corev1.PodTemplateSpec) {}
+}
+
+// WithContainer applies the modifications to the container with the provided name
+func WithContainer(name string, containerfunc func(*corev1.Container)) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		idx := findIndexByName(name, podTemplateSpec.Spec.Containers)
+		if idx == notFound {
+			// if we are attempting to modify a container that does not exist, we will add a new one
+			podTemplateSpec.Spec.Containers = append(podTemplateSpec.Spec.Containers, corev1.Container{})
+			idx = len(podTemplateSpec.Spec.Containers) - 1
+		}
+		c := &podTemplateSpec.Spec.Containers[idx]
+		containerfunc(c)
+	}
+}
+
+// WithContainerByIndex applies the modifications to the container with the provided index
+// if the index is out of range, a new container is added to accept these changes.
+func WithContainerByIndex(index int, funcs ...func(container *corev1.Container)) func(podTemplateSpec *corev1.PodTemplateSpec) {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		if index >= len(podTemplateSpec.Spec.Containers) {
+			podTemplateSpec.Spec.Containers = append(podTemplateSpec.Spec.Containers, corev1.Container{})
+		}
+		c := &podTemplateSpec.Spec.Containers[index]
+		for _, f := range funcs {
+			f(c)
+		}
+	}
+}
+
+// WithInitContainer applies the modifications to the init container with the provided name
+func WithInitContainer(name string, containerfunc func(*corev1.Container)) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		idx := findIndexByName(name, podTemplateSpec.Spec.InitContainers)
+		if idx == notFound {
+			// if we are attempting to modify a container that does not exist, we will add a new one
+			podTemplateSpec.Spec.InitContainers = append(podTemplateSpec.Spec.InitContainers, corev1.Container{})
+			idx = len(podTemplateSpec.Spec.InitContainers) - 1
+		}
+		c := &podTemplateSpec.Spec.InitContainers[idx]
+		containerfunc(c)
+	}
+}
+
+// WithTerminationGracePeriodSeconds sets the TerminationGracePeriodSeconds of the PodTemplateSpec
+func WithTerminationGracePeriodSeconds(seconds int) Modification {
+	s := int64(seconds)
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.TerminationGracePeriodSeconds = &s
+	}
+}
+
+// WithSecurityContext sets the SecurityContext of the PodTemplateSpec
+func WithSecurityContext(securityContext corev1.PodSecurityContext) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		spec := &podTemplateSpec.Spec
+		spec.SecurityContext = &securityContext
+	}
+}
+
+// WithPodLabels sets the labels of the PodTemplateSpec
+func WithPodLabels(labels map[string]string) Modification {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.ObjectMeta.Labels = labels
+	}
+}
+
+// WithAnnotations merges the given annotations into the ones of the PodTemplateSpec
+func WithAnnotations(annotations map[string]string) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Annotations = merge.StringToStringMap(podTemplateSpec.Annotations, annotations)
+	}
+}
+
+// WithServiceAccount sets the ServiceAccount of the PodTemplateSpec
+func WithServiceAccount(serviceAccountName string) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.ServiceAccountName = serviceAccountName
+	}
+}
+
+// WithAutomountServiceAccountToken sets whether the token of the ServiceAccount is mounted into the Pods
+func WithAutomountServiceAccountToken(automount bool) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.AutomountServiceAccountToken = &automount
+	}
+}
+
+// WithRestartPolicy sets the RestartPolicy of the PodTemplateSpec
+func WithRestartPolicy(restartPolicy corev1.RestartPolicy) Modification {
+	return func(podTemplateSpec *corev1.PodTemplateSpec) {
+		podTemplateSpec.Spec.RestartPolicy = restartPolicy
+	}
+}
+
+// WithVolume ensures the given volume exists
+func WithVolume(volume corev1.Volume) Modification {
+	return func(template *corev1.PodTemplateSpec) {
+		for i := range template.Spec.Volumes {
+			if template.Spec.Volumes[i].Name == volume.Name {
+				template.Spec.Volumes[i] = volume
+				return
+			}
+		}
+
+		template.Spec.Volumes = append(template.Spec.Volumes, volume)
+	}
+}
+
+// WithVolumeMounts will add the given volume mounts to the container with the given name.
+func WithVolumeMounts(containerName string, volumeMounts ...corev1.VolumeMount) Modification {
+	return func(template *corev1.PodTemplateSpec) {
+		idx := findIndexByName(containerName, template.Spec.Containers)
+		if idx == notFound {
+			return
+		}
+		container.WithVolumeMounts(volumeMounts)(&template.Spec.Containers[idx])
+	}
+}
+
+func findIndexByName(name string, containers []corev1.Container) int {
+	for idx, c := range containers {
+		if c.Name == name {
+			return idx
+		}
+	}
+	return notFound
+}
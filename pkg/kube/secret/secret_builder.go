@@ -9,6 +9,7 @@ type builder struct {
 	data            map[string][]byte
 	dataType        corev1.SecretType
 	labels          map[string]string
+	annotations     map[string]string
 	name            string
 	namespace       string
 	ownerReferences []metav1.OwnerReference
@@ -43,6 +44,15 @@ func (b *builder) SetLabels(labels map[string]string) *builder {
 	return b
 }
 
+func (b *builder) SetAnnotations(annotations map[string]string) *builder {
+	newAnnotations := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		newAnnotations[k] = v
+	}
+	b.annotations = newAnnotations
+	return b
+}
+
 func (b *builder) SetByteData(stringData map[string][]byte) *builder {
 	newStringDataBytes := make(map[string][]byte, len(stringData))
 	for k, v := range stringData {
@@ -72,6 +82,7 @@ func (b builder) Build() corev1.Secret {
 			Namespace:       b.namespace,
 			OwnerReferences: b.ownerReferences,
 			Labels:          b.labels,
+			Annotations:     b.annotations,
 		},
 		Data: b.data,
 		Type: b.dataType,
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Getter interface {
+	GetService(objectKey client.ObjectKey) (corev1.Service, error)
+}
+
+type Updater interface {
+	UpdateService(service corev1.Service) error
+}
+
+type Creator interface {
+	CreateService(service corev1.Service) error
+}
+
+type Deleter interface {
+	DeleteService(objectKey client.ObjectKey) error
+}
+
+type GetDeleter interface {
+	Getter
+	Deleter
+}
+
+type GetUpdater interface {
+	Getter
+	Updater
+}
+
+type GetUpdateCreator interface {
+	Getter
+	Updater
+	Creator
+}
+
+type GetUpdateCreateDeleter interface {
+	Getter
+	Updater
+	Creator
+	Deleter
+}
+
+// CreateOrUpdate creates the Service if it doesn't exist, otherwise it updates it.
+// The ClusterIP and the ResourceVersion of an existing Service are kept, as they are immutable
+// or required for the update to be accepted.
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, desired corev1.Service) error {
+	existing, err := getUpdateCreator.GetService(types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return getUpdateCreator.CreateService(desired)
+		}
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	desired.Spec.ClusterIP = existing.Spec.ClusterIP
+	desired.Spec.ClusterIPs = existing.Spec.ClusterIPs
+	return getUpdateCreator.UpdateService(desired)
+}
+
+// DeleteServiceIfItExists deletes the Service with the given name, nothing is done if it doesn't exist.
+func DeleteServiceIfItExists(getterDeleter GetDeleter, serviceName types.NamespacedName) error {
+	_, err := getterDeleter.GetService(serviceName)
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return getterDeleter.DeleteService(serviceName)
+}
+
+// Merge merges `source` into `dest`. Both arguments will remain unchanged
+// a new service will be created and returned.
+// The "merging" process is arbitrary and it only handle specific attributes
+func Merge(dest corev1.Service, source corev1.Service) corev1.Service {
+	for k, v := range source.ObjectMeta.Annotations {
+		if dest.ObjectMeta.Annotations == nil {
+			dest.ObjectMeta.Annotations = map[string]string{}
+		}
+		dest.ObjectMeta.Annotations[k] = v
+	}
+
+	for k, v := range source.ObjectMeta.Labels {
+		if dest.ObjectMeta.Labels == nil {
+			dest.ObjectMeta.Labels = map[string]string{}
+		}
+		dest.ObjectMeta.Labels[k] = v
+	}
+
+	if dest.Spec.Selector == nil {
+		dest.Spec.Selector = map[string]string{}
+	}
+	for k, v := range source.Spec.Selector {
+		dest.Spec.Selector[k] = v
+	}
+
+	cachedNodePorts := map[int32]int32{}
+	for _, port := range dest.Spec.Ports {
+		cachedNodePorts[port.Port] = port.NodePort
+	}
+
+	if len(source.Spec.Ports) > 0 {
+		dest.Spec.Ports = make([]corev1.ServicePort, len(source.Spec.Ports))
+		copy(dest.Spec.Ports, source.Spec.Ports)
+
+		for i := range dest.Spec.Ports {
+			// Source might not specify NodePort and we shouldn't override existing NodePort value
+			if dest.Spec.Ports[i].NodePort == 0 {
+				dest.Spec.Ports[i].NodePort = cachedNodePorts[dest.Spec.Ports[i].Port]
+			}
+		}
+	}
+
+	dest.Spec.Type = source.Spec.Type
+	dest.Spec.LoadBalancerIP = source.Spec.LoadBalancerIP
+	dest.Spec.ExternalTrafficPolicy = source.Spec.ExternalTrafficPolicy
+	return dest
+}
+
+// FQDN returns the fully qualified domain name of the Service with the given name.
+func FQDN(serviceName, namespace, clusterDomain string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", serviceName, namespace, strings.TrimPrefix(clusterDomain, "."))
+}
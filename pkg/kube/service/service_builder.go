@@ -0,0 +1,134 @@
+package service
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type builder struct {
+	name                     string
+	namespace                string
+	clusterIp                string
+	serviceType              corev1.ServiceType
+	servicePort              []corev1.ServicePort
+	labels                   map[string]string
+	annotations              map[string]string
+	loadBalancerIP           string
+	ownerReferences          []metav1.OwnerReference
+	selector                 map[string]string
+	publishNotReadyAddresses bool
+	sessionAffinity          corev1.ServiceAffinity
+	sessionAffinityTimeout   *int32
+}
+
+func (b *builder) SetNamespace(namespace string) *builder {
+	b.namespace = namespace
+	return b
+}
+
+func (b *builder) SetName(name string) *builder {
+	b.name = name
+	return b
+}
+
+func (b *builder) SetLabels(labels map[string]string) *builder {
+	b.labels = labels
+	return b
+}
+
+func (b *builder) SetAnnotations(annotations map[string]string) *builder {
+	b.annotations = annotations
+	return b
+}
+
+func (b *builder) SetSelector(selector map[string]string) *builder {
+	b.selector = selector
+	return b
+}
+
+func (b *builder) SetServiceType(serviceType corev1.ServiceType) *builder {
+	b.serviceType = serviceType
+	return b
+}
+
+func (b *builder) SetClusterIP(clusterIP string) *builder {
+	b.clusterIp = clusterIP
+	return b
+}
+
+func (b *builder) SetLoadBalancerIP(ip string) *builder {
+	b.loadBalancerIP = ip
+	return b
+}
+
+func (b *builder) SetPublishNotReadyAddresses(publishNotReadyAddresses bool) *builder {
+	b.publishNotReadyAddresses = publishNotReadyAddresses
+	return b
+}
+
+// SetSessionAffinity sets the session affinity of the Service. The timeout is only used with ClientIP affinity.
+func (b *builder) SetSessionAffinity(sessionAffinity corev1.ServiceAffinity, timeoutSeconds *int32) *builder {
+	b.sessionAffinity = sessionAffinity
+	b.sessionAffinityTimeout = timeoutSeconds
+	return b
+}
+
+func (b *builder) SetOwnerReferences(ownerReferences []metav1.OwnerReference) *builder {
+	b.ownerReferences = ownerReferences
+	return b
+}
+
+// AddPort adds a port to the Service. Ports with the same name are replaced.
+func (b *builder) AddPort(port *corev1.ServicePort) *builder {
+	if port == nil {
+		return b
+	}
+	for i := range b.servicePort {
+		if b.servicePort[i].Name == port.Name {
+			b.servicePort[i] = *port
+			return b
+		}
+	}
+	b.servicePort = append(b.servicePort, *port)
+	return b
+}
+
+func (b *builder) Build() corev1.Service {
+	servicePorts := make([]corev1.ServicePort, len(b.servicePort))
+	copy(servicePorts, b.servicePort)
+
+	var sessionAffinityConfig *corev1.SessionAffinityConfig
+	if b.sessionAffinity == corev1.ServiceAffinityClientIP && b.sessionAffinityTimeout != nil {
+		timeout := *b.sessionAffinityTimeout
+		sessionAffinityConfig = &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout}}
+	}
+
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            b.name,
+			Namespace:       b.namespace,
+			Labels:          b.labels,
+			Annotations:     b.annotations,
+			OwnerReferences: b.ownerReferences,
+		},
+		Spec: corev1.ServiceSpec{
+			PublishNotReadyAddresses: b.publishNotReadyAddresses,
+			LoadBalancerIP:           b.loadBalancerIP,
+			Type:                     b.serviceType,
+			ClusterIP:                b.clusterIp,
+			Ports:                    servicePorts,
+			Selector:                 b.selector,
+			SessionAffinity:          b.sessionAffinity,
+			SessionAffinityConfig:    sessionAffinityConfig,
+		},
+	}
+}
+
+func Builder() *builder {
+	return &builder{
+		labels:          map[string]string{},
+		annotations:     map[string]string{},
+		ownerReferences: []metav1.OwnerReference{},
+		selector:        map[string]string{},
+	}
+}
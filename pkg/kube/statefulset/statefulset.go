@@ -17,19 +17,19 @@ const (
 )
 
 type Getter interface {
-	GetDeployment(objectKey client.ObjectKey) (appsv1.Deployment, error)
+	GetStatefulSet(objectKey client.ObjectKey) (appsv1.StatefulSet, error)
 }
 
 type Updater interface {
-	UpdateDeployment(sts appsv1.Deployment) (appsv1.Deployment, error)
+	UpdateStatefulSet(sts appsv1.StatefulSet) (appsv1.StatefulSet, error)
 }
 
 type Creator interface {
-	CreateDeployment(sts appsv1.Deployment) error
+	CreateStatefulSet(sts appsv1.StatefulSet) error
 }
 
 type Deleter interface {
-	DeleteDeployment(objectKey client.ObjectKey) error
+	DeleteStatefulSet(objectKey client.ObjectKey) error
 }
 
 type GetUpdater interface {
@@ -50,28 +50,28 @@ type GetUpdateCreateDeleter interface {
 	Deleter
 }
 
-// CreateOrUpdate creates the given Deployment if it doesn't exist,
+// CreateOrUpdate creates the given StatefulSet if it doesn't exist,
 // or updates it if it does.
-func CreateOrUpdate(getUpdateCreator GetUpdateCreator, dep appsv1.Deployment) (appsv1.Deployment, error) {
-	_, err := getUpdateCreator.GetDeployment(types.NamespacedName{Name: dep.Name, Namespace: dep.Namespace})
+func CreateOrUpdate(getUpdateCreator GetUpdateCreator, sts appsv1.StatefulSet) (appsv1.StatefulSet, error) {
+	_, err := getUpdateCreator.GetStatefulSet(types.NamespacedName{Name: sts.Name, Namespace: sts.Namespace})
 	if err != nil {
 		if apiErrors.IsNotFound(err) {
-			return appsv1.Deployment{}, getUpdateCreator.CreateDeployment(dep)
+			return appsv1.StatefulSet{}, getUpdateCreator.CreateStatefulSet(sts)
 		}
-		return appsv1.Deployment{}, err
+		return appsv1.StatefulSet{}, err
 	}
-	return getUpdateCreator.UpdateDeployment(dep)
+	return getUpdateCreator.UpdateStatefulSet(sts)
 }
 
 // GetAndUpdate applies the provided function to the most recent version of the object
-func GetAndUpdate(getUpdater GetUpdater, nsName types.NamespacedName, updateFunc func(*appsv1.Deployment)) (appsv1.Deployment, error) {
-	dep, err := getUpdater.GetDeployment(nsName)
+func GetAndUpdate(getUpdater GetUpdater, nsName types.NamespacedName, updateFunc func(*appsv1.StatefulSet)) (appsv1.StatefulSet, error) {
+	sts, err := getUpdater.GetStatefulSet(nsName)
 	if err != nil {
-		return appsv1.Deployment{}, err
+		return appsv1.StatefulSet{}, err
 	}
 	// apply the function on the most recent version of the resource
-	updateFunc(&dep)
-	return getUpdater.UpdateDeployment(dep)
+	updateFunc(&sts)
+	return getUpdater.UpdateStatefulSet(sts)
 }
 
 // VolumeMountData contains values required for the MountVolume function
@@ -142,7 +142,7 @@ func CreateVolumeMount(name, path string, options ...func(*corev1.VolumeMount))
 
 // NOOP is a valid Modification which applies no changes
 func NOOP() Modification {
-	return func(dep *appsv1.Deployment) {}
+	return func(sts *appsv1.StatefulSet) {}
 }
 
 func WithSecretDefaultMode(mode *int32) func(*corev1.Volume) {
@@ -175,56 +175,56 @@ func IsReady(sts appsv1.StatefulSet, expectedReplicas int) bool {
 	return allUpdated && allReady && atExpectedGeneration
 }
 
-type Modification func(*appsv1.Deployment)
+type Modification func(*appsv1.StatefulSet)
 
-func New(mods ...Modification) appsv1.Deployment {
-	ocb := appsv1.Deployment{}
+func New(mods ...Modification) appsv1.StatefulSet {
+	sts := appsv1.StatefulSet{}
 	for _, mod := range mods {
-		mod(&ocb)
+		mod(&sts)
 	}
-	return ocb
+	return sts
 }
 
-func Apply(funcs ...Modification) func(*appsv1.Deployment) {
-	return func(dep *appsv1.Deployment) {
+func Apply(funcs ...Modification) func(*appsv1.StatefulSet) {
+	return func(sts *appsv1.StatefulSet) {
 		for _, f := range funcs {
-			f(dep)
+			f(sts)
 		}
 	}
 }
 
 func WithName(name string) Modification {
-	return func(dep *appsv1.Deployment) {
-		dep.Name = name
+	return func(sts *appsv1.StatefulSet) {
+		sts.Name = name
 	}
 }
 
 func WithNamespace(namespace string) Modification {
-	return func(dep *appsv1.Deployment) {
-		dep.Namespace = namespace
+	return func(sts *appsv1.StatefulSet) {
+		sts.Namespace = namespace
 	}
 }
 
 func WithServiceName(svcName string) Modification {
-	return func(sts *appsv1.Deployment) {
-		// sts.Spec.ServiceName = svcName
+	return func(sts *appsv1.StatefulSet) {
+		sts.Spec.ServiceName = svcName
 	}
 }
 
 func WithLabels(labels map[string]string) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		set.Labels = copyMap(labels)
 	}
 }
 
 func WithAnnotations(annotations map[string]string) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		set.Annotations = merge.StringToStringMap(set.Annotations, annotations)
 	}
 }
 
 func WithMatchLabels(matchLabels map[string]string) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		if set.Spec.Selector == nil {
 			set.Spec.Selector = &metav1.LabelSelector{}
 		}
@@ -234,54 +234,54 @@ func WithMatchLabels(matchLabels map[string]string) Modification {
 func WithOwnerReference(ownerRefs []metav1.OwnerReference) Modification {
 	ownerReference := make([]metav1.OwnerReference, len(ownerRefs))
 	copy(ownerReference, ownerRefs)
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		set.OwnerReferences = ownerReference
 	}
 }
 
 func WithReplicas(replicas int) Modification {
 	stsReplicas := int32(replicas)
-	return func(sts *appsv1.Deployment) {
+	return func(sts *appsv1.StatefulSet) {
 		sts.Spec.Replicas = &stsReplicas
 	}
 }
 
 func WithRevisionHistoryLimit(revisionHistoryLimit int) Modification {
 	rhl := int32(revisionHistoryLimit)
-	return func(sts *appsv1.Deployment) {
+	return func(sts *appsv1.StatefulSet) {
 		sts.Spec.RevisionHistoryLimit = &rhl
 	}
 }
 
 func WithPodManagementPolicyType(policyType appsv1.PodManagementPolicyType) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		set.Spec.PodManagementPolicy = policyType
 	}
 }
 
 func WithSelector(selector *metav1.LabelSelector) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		set.Spec.Selector = selector
 	}
 }
 
-func WithUpdateStrategyType(strategyType appsv1.DeploymentStrategyType) Modification {
-	return func(set *appsv1.Deployment) {
-		set.Spec.Strategy = appsv1.DeploymentStrategy{
+func WithUpdateStrategyType(strategyType appsv1.StatefulSetUpdateStrategyType) Modification {
+	return func(set *appsv1.StatefulSet) {
+		set.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
 			Type: strategyType,
 		}
 	}
 }
 
 func WithPodSpecTemplate(templateFunc func(*corev1.PodTemplateSpec)) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		template := &set.Spec.Template
 		templateFunc(template)
 	}
 }
 
 func WithVolumeClaim(name string, f func(*corev1.PersistentVolumeClaim)) Modification {
-	return func(set *appsv1.Deployment) {
+	return func(set *appsv1.StatefulSet) {
 		idx := findVolumeClaimIndexByName(name, set.Spec.VolumeClaimTemplates)
 		if idx == notFound {
 			set.Spec.VolumeClaimTemplates = append(set.Spec.VolumeClaimTemplates, corev1.PersistentVolumeClaim{})
@@ -292,8 +292,8 @@ func WithVolumeClaim(name string, f func(*corev1.PersistentVolumeClaim)) Modific
 	}
 }
 
-func WithCustomSpecs(spec appsv1.Deployment) Modification {
-	return func(set *appsv1.Deployment) {
+func WithCustomSpecs(spec appsv1.StatefulSetSpec) Modification {
+	return func(set *appsv1.StatefulSet) {
 		set.Spec = merge.StatefulSetSpecs(set.Spec, spec)
 	}
 }
@@ -324,10 +324,8 @@ func ResetUpdateStrategy(ocb annotations.Versioned, kubeClient GetUpdater) error
 	}
 
 	// if we changed the version, we need to reset the UpdatePolicy back to OnUpdate
-	_, err := GetAndUpdate(kubeClient, ocb.NamespacedName(), func(dep *appsv1.Deployment) {
-		//dep.Spec.UpdateStrategy.Type = appsv1.RollingUpdateDeploymentStrategyType
-		dep.Spec.Strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
-
+	_, err := GetAndUpdate(kubeClient, ocb.NamespacedName(), func(sts *appsv1.StatefulSet) {
+		sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
 	})
 	return err
 }
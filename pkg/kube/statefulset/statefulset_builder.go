@@ -25,7 +25,7 @@ type Builder struct {
 	readinessProbePerContainer map[string]*corev1.Probe
 	volumeClaimsTemplates      []corev1.PersistentVolumeClaim
 	volumeMountsPerContainer   map[string][]corev1.VolumeMount
-	updateStrategyType         appsv1.DeploymentStrategyType
+	updateStrategyType         appsv1.StatefulSetUpdateStrategyType
 }
 
 func (s *Builder) SetLabels(labels map[string]string) *Builder {
@@ -73,7 +73,7 @@ func (s *Builder) SetPodTemplateSpec(podTemplateSpec corev1.PodTemplateSpec) *Bu
 	return s
 }
 
-func (s *Builder) SetUpdateStrategy(updateStrategyType appsv1.DeploymentStrategyType) *Builder {
+func (s *Builder) SetUpdateStrategy(updateStrategyType appsv1.StatefulSetUpdateStrategyType) *Builder {
 	s.updateStrategyType = updateStrategyType
 	return s
 }
@@ -186,10 +186,10 @@ func copyMap(originalMap map[string]string) map[string]string {
 	return newMap
 }
 
-func (s Builder) Build() (appsv1.Deployment, error) {
+func (s Builder) Build() (appsv1.StatefulSet, error) {
 	podTemplateSpec, err := s.buildPodTemplateSpec()
 	if err != nil {
-		return appsv1.Deployment{}, err
+		return appsv1.StatefulSet{}, err
 	}
 
 	replicas := int32(s.replicas)
@@ -200,26 +200,27 @@ func (s Builder) Build() (appsv1.Deployment, error) {
 	volumeClaimsTemplates := make([]corev1.PersistentVolumeClaim, len(s.volumeClaimsTemplates))
 	copy(volumeClaimsTemplates, s.volumeClaimsTemplates)
 
-	dep := appsv1.Deployment{
+	sts := appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            s.name,
 			Namespace:       s.namespace,
 			Labels:          copyMap(s.labels),
 			OwnerReferences: ownerReference,
 		},
-		Spec: appsv1.DeploymentSpec{
-			UpdateStrategy: appsv1.DeploymentStrategy{
-				Type: s.updateStrategyType,
-			},
-			// ServiceName: s.serviceName,
-			Replicas: &replicas,
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: s.serviceName,
+			Replicas:    &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: copyMap(s.matchLabels),
 			},
-			Template: podTemplateSpec,
+			Template:             podTemplateSpec,
+			VolumeClaimTemplates: volumeClaimsTemplates,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: s.updateStrategyType,
+			},
 		},
 	}
-	return dep, err
+	return sts, err
 }
 
 func NewBuilder() *Builder {
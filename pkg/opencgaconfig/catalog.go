@@ -0,0 +1,128 @@
+package opencgaconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultCatalogAuthenticationDatabase is the database the catalog user authenticates against
+	// if none is configured.
+	DefaultCatalogAuthenticationDatabase = "admin"
+
+	// x509AuthenticationMechanism authenticates with the client certificate, against the x509AuthenticationDatabase.
+	x509AuthenticationMechanism = "MONGODB-X509"
+	x509AuthenticationDatabase  = "$external"
+
+	mongoDBScheme    = "mongodb"
+	mongoDBSRVScheme = "mongodb+srv"
+)
+
+// Catalog holds the settings OpenCGA uses to connect to the MongoDB deployment storing its catalog.
+type Catalog struct {
+	Hosts                  []string
+	User                   string
+	Password               string
+	AuthenticationDatabase string
+	ReplicaSet             string
+	SSLEnabled             bool
+
+	// X509Authentication authenticates with the client certificate of the JVM key store instead of a password.
+	X509Authentication bool
+}
+
+// render returns the catalog section of configuration.yml.
+func (c Catalog) render() map[string]interface{} {
+	return map[string]interface{}{
+		"database": c.renderDatabase(),
+	}
+}
+
+// renderDatabase returns the connection to the MongoDB deployment in the format used by both
+// the catalog and the MongoDB variant storage engine.
+func (c Catalog) renderDatabase() map[string]interface{} {
+	hosts := make([]interface{}, len(c.Hosts))
+	for i, host := range c.Hosts {
+		hosts[i] = host
+	}
+
+	authenticationDatabase := c.AuthenticationDatabase
+	if authenticationDatabase == "" {
+		authenticationDatabase = DefaultCatalogAuthenticationDatabase
+	}
+
+	options := map[string]interface{}{
+		"authenticationDatabase": authenticationDatabase,
+		"sslEnabled":             c.SSLEnabled,
+	}
+	if c.ReplicaSet != "" {
+		options["replicaSet"] = c.ReplicaSet
+	}
+	if c.X509Authentication {
+		options["authenticationMechanism"] = x509AuthenticationMechanism
+		options["authenticationDatabase"] = x509AuthenticationDatabase
+	}
+
+	return map[string]interface{}{
+		"hosts":    hosts,
+		"user":     c.User,
+		"password": c.Password,
+		"options":  options,
+	}
+}
+
+// ValidateHost returns an error if the given host is not in the form host:port.
+func ValidateHost(host string) error {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return fmt.Errorf("%q is not in the form host:port: %s", host, err)
+	}
+	if hostname == "" {
+		return fmt.Errorf("%q has an empty host name", host)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("%q has an invalid port", host)
+	}
+	return nil
+}
+
+// ParseConnectionString parses a standard MongoDB connection string into the catalog settings.
+// Credentials are only set if the connection string contains them. Connection strings using the
+// mongodb+srv scheme are rejected, as OpenCGA needs the list of hosts.
+func ParseConnectionString(connectionString string) (Catalog, error) {
+	u, err := url.Parse(strings.TrimSpace(connectionString))
+	if err != nil {
+		return Catalog{}, fmt.Errorf("could not parse the connection string: %s", err)
+	}
+	if u.Scheme == mongoDBSRVScheme {
+		return Catalog{}, fmt.Errorf("%s connection strings are not supported, the hosts must be listed", mongoDBSRVScheme)
+	}
+	if u.Scheme != mongoDBScheme {
+		return Catalog{}, fmt.Errorf("the connection string must start with %s://", mongoDBScheme)
+	}
+
+	catalog := Catalog{}
+	for _, host := range strings.Split(u.Host, ",") {
+		if err := ValidateHost(host); err != nil {
+			return Catalog{}, err
+		}
+		catalog.Hosts = append(catalog.Hosts, host)
+	}
+
+	if u.User != nil {
+		catalog.User = u.User.Username()
+		catalog.Password, _ = u.User.Password()
+	}
+
+	query := u.Query()
+	catalog.AuthenticationDatabase = strings.TrimPrefix(u.Path, "/")
+	if authSource := query.Get("authSource"); authSource != "" {
+		catalog.AuthenticationDatabase = authSource
+	}
+	catalog.ReplicaSet = query.Get("replicaSet")
+	catalog.SSLEnabled = query.Get("tls") == "true" || query.Get("ssl") == "true"
+	return catalog, nil
+}
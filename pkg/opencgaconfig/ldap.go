@@ -0,0 +1,61 @@
+package opencgaconfig
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// LDAPAuthenticationOriginID is the id of the LDAP authentication origin, users authenticated against LDAP
+	// are registered in OpenCGA with it.
+	LDAPAuthenticationOriginID = "ldap"
+
+	ldapAuthenticationOriginType = "LDAP"
+	ldapScheme                   = "ldap"
+	ldapsScheme                  = "ldaps"
+)
+
+// LDAP holds the settings OpenCGA uses to authenticate users against LDAP servers.
+type LDAP struct {
+	Servers         []string
+	BindDN          string
+	BindPassword    string
+	UserSearchBase  string
+	GroupSearchBase string
+}
+
+// render returns the authentication origin of configuration.yml. The servers are joined into a single host,
+// which the JNDI LDAP provider tries in order.
+func (l LDAP) render() map[string]interface{} {
+	options := map[string]interface{}{
+		"usersSearch":  l.UserSearchBase,
+		"groupsSearch": l.GroupSearchBase,
+	}
+	if l.BindDN != "" {
+		options["authUserId"] = l.BindDN
+		options["authPassword"] = l.BindPassword
+	}
+
+	return map[string]interface{}{
+		"id":      LDAPAuthenticationOriginID,
+		"type":    ldapAuthenticationOriginType,
+		"host":    strings.Join(l.Servers, " "),
+		"options": options,
+	}
+}
+
+// ValidateLDAPServer returns an error if the given server is not an ldap:// or ldaps:// URL with a host.
+func ValidateLDAPServer(server string) error {
+	u, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL: %s", server, err)
+	}
+	if u.Scheme != ldapScheme && u.Scheme != ldapsScheme {
+		return fmt.Errorf("%q must start with %s:// or %s://", server, ldapScheme, ldapsScheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%q has an empty host name", server)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package opencgaconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild_LDAP(t *testing.T) {
+	t.Run("No authentication origin is configured without LDAP", func(t *testing.T) {
+		config := NewBuilder().Build()
+		assert.False(t, config.Configuration.Has("authentication"))
+	})
+	t.Run("LDAP is rendered as an authentication origin", func(t *testing.T) {
+		config := NewBuilder().SetLDAP(LDAP{
+			Servers:         []string{"ldaps://ldap-0.example.org:636", "ldaps://ldap-1.example.org:636"},
+			BindDN:          "cn=opencga,ou=services,dc=example,dc=org",
+			BindPassword:    "s3cr3t",
+			UserSearchBase:  "ou=people,dc=example,dc=org",
+			GroupSearchBase: "ou=groups,dc=example,dc=org",
+		}).Build()
+
+		origins := config.Configuration.Get("authentication.authenticationOrigins").InterSlice()
+		if assert.Len(t, origins, 1) {
+			origin := config.Configuration.Get("authentication.authenticationOrigins[0]").ObjxMap()
+			assert.Equal(t, LDAPAuthenticationOriginID, origin.Get("id").Str())
+			assert.Equal(t, "LDAP", origin.Get("type").Str())
+			assert.Equal(t, "ldaps://ldap-0.example.org:636 ldaps://ldap-1.example.org:636", origin.Get("host").Str())
+			assert.Equal(t, "ou=people,dc=example,dc=org", origin.Get("options.usersSearch").Str())
+			assert.Equal(t, "ou=groups,dc=example,dc=org", origin.Get("options.groupsSearch").Str())
+			assert.Equal(t, "cn=opencga,ou=services,dc=example,dc=org", origin.Get("options.authUserId").Str())
+			assert.Equal(t, "s3cr3t", origin.Get("options.authPassword").Str())
+		}
+	})
+	t.Run("Anonymous binds have no credentials", func(t *testing.T) {
+		config := NewBuilder().SetLDAP(LDAP{Servers: []string{"ldap://ldap:389"}, UserSearchBase: "ou=people,dc=example,dc=org"}).Build()
+		options := config.Configuration.Get("authentication.authenticationOrigins[0].options").ObjxMap()
+		assert.False(t, options.Has("authUserId"))
+		assert.False(t, options.Has("authPassword"))
+	})
+}
+
+func TestValidateLDAPServer(t *testing.T) {
+	assert.NoError(t, ValidateLDAPServer("ldap://ldap:389"))
+	assert.NoError(t, ValidateLDAPServer("ldaps://ldap.example.org"))
+	assert.Error(t, ValidateLDAPServer("https://ldap.example.org"))
+	assert.Error(t, ValidateLDAPServer("ldap.example.org:389"))
+	assert.Error(t, ValidateLDAPServer("ldaps://:636"))
+}
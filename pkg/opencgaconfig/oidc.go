@@ -0,0 +1,88 @@
+package opencgaconfig
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	// OIDCAuthenticationOriginID is the id of the OpenID Connect authentication origin, users authenticated by
+	// the OpenID provider are registered in OpenCGA with it.
+	OIDCAuthenticationOriginID = "oidc"
+
+	oidcAuthenticationOriginType = "SSO"
+
+	DefaultOIDCUserIDClaim = "sub"
+	DefaultOIDCNameClaim   = "name"
+	DefaultOIDCEmailClaim  = "email"
+)
+
+// DefaultOIDCScopes are the scopes requested if none are configured.
+var DefaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// OIDC holds the settings OpenCGA uses to authenticate users with an OpenID provider.
+type OIDC struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	UserIDClaim  string
+	NameClaim    string
+	EmailClaim   string
+	GroupsClaim  string
+}
+
+// render returns the authentication origin of configuration.yml. Groups are only read from the ID token
+// if a claim is configured for them.
+func (o OIDC) render() map[string]interface{} {
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultOIDCScopes
+	}
+	renderedScopes := make([]interface{}, len(scopes))
+	for i, scope := range scopes {
+		renderedScopes[i] = scope
+	}
+
+	options := map[string]interface{}{
+		"clientId":     o.ClientID,
+		"clientSecret": o.ClientSecret,
+		"scopes":       renderedScopes,
+		"userIdClaim":  orDefault(o.UserIDClaim, DefaultOIDCUserIDClaim),
+		"nameClaim":    orDefault(o.NameClaim, DefaultOIDCNameClaim),
+		"emailClaim":   orDefault(o.EmailClaim, DefaultOIDCEmailClaim),
+	}
+	if o.GroupsClaim != "" {
+		options["groupsClaim"] = o.GroupsClaim
+	}
+
+	return map[string]interface{}{
+		"id":      OIDCAuthenticationOriginID,
+		"type":    oidcAuthenticationOriginType,
+		"host":    o.Issuer,
+		"options": options,
+	}
+}
+
+// ValidateOIDCIssuer returns an error if the given issuer is not an https:// URL without query or fragment,
+// as required by OpenID Connect Discovery.
+func ValidateOIDCIssuer(issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL: %s", issuer, err)
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%q is not an https URL", issuer)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must not have a query or a fragment", issuer)
+	}
+	return nil
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,58 @@
+package opencgaconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild_OIDC(t *testing.T) {
+	t.Run("Defaults are used for unset values", func(t *testing.T) {
+		config := NewBuilder().SetOIDC(OIDC{Issuer: "https://idp.example.org/realms/opencga", ClientID: "opencga", ClientSecret: "s3cr3t"}).Build()
+
+		origin := config.Configuration.Get("authentication.authenticationOrigins[0]").ObjxMap()
+		assert.Equal(t, OIDCAuthenticationOriginID, origin.Get("id").Str())
+		assert.Equal(t, "SSO", origin.Get("type").Str())
+		assert.Equal(t, "https://idp.example.org/realms/opencga", origin.Get("host").Str())
+		assert.Equal(t, "opencga", origin.Get("options.clientId").Str())
+		assert.Equal(t, "s3cr3t", origin.Get("options.clientSecret").Str())
+		assert.Equal(t, []interface{}{"openid", "profile", "email"}, origin.Get("options.scopes").Data())
+		assert.Equal(t, DefaultOIDCUserIDClaim, origin.Get("options.userIdClaim").Str())
+		assert.Equal(t, DefaultOIDCNameClaim, origin.Get("options.nameClaim").Str())
+		assert.Equal(t, DefaultOIDCEmailClaim, origin.Get("options.emailClaim").Str())
+		assert.False(t, origin.Has("options.groupsClaim"))
+	})
+	t.Run("Claims can be mapped", func(t *testing.T) {
+		config := NewBuilder().SetOIDC(OIDC{
+			Issuer:      "https://idp.example.org",
+			ClientID:    "opencga",
+			Scopes:      []string{"openid", "groups"},
+			UserIDClaim: "preferred_username",
+			GroupsClaim: "groups",
+		}).Build()
+
+		options := config.Configuration.Get("authentication.authenticationOrigins[0].options").ObjxMap()
+		assert.Equal(t, []interface{}{"openid", "groups"}, options.Get("scopes").Data())
+		assert.Equal(t, "preferred_username", options.Get("userIdClaim").Str())
+		assert.Equal(t, "groups", options.Get("groupsClaim").Str())
+	})
+	t.Run("LDAP and OIDC are both authentication origins", func(t *testing.T) {
+		config := NewBuilder().
+			SetLDAP(LDAP{Servers: []string{"ldap://ldap:389"}, UserSearchBase: "ou=people,dc=example,dc=org"}).
+			SetOIDC(OIDC{Issuer: "https://idp.example.org", ClientID: "opencga"}).
+			Build()
+
+		assert.Len(t, config.Configuration.Get("authentication.authenticationOrigins").InterSlice(), 2)
+		assert.Equal(t, LDAPAuthenticationOriginID, config.Configuration.Get("authentication.authenticationOrigins[0].id").Str())
+		assert.Equal(t, OIDCAuthenticationOriginID, config.Configuration.Get("authentication.authenticationOrigins[1].id").Str())
+	})
+}
+
+func TestValidateOIDCIssuer(t *testing.T) {
+	assert.NoError(t, ValidateOIDCIssuer("https://idp.example.org"))
+	assert.NoError(t, ValidateOIDCIssuer("https://idp.example.org/realms/opencga"))
+	assert.Error(t, ValidateOIDCIssuer("http://idp.example.org"))
+	assert.Error(t, ValidateOIDCIssuer("idp.example.org"))
+	assert.Error(t, ValidateOIDCIssuer("https://idp.example.org?tenant=opencga"))
+	assert.Error(t, ValidateOIDCIssuer("https://idp.example.org#opencga"))
+}
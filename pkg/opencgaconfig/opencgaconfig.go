@@ -0,0 +1,103 @@
+package opencgaconfig
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/stretchr/objx"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ConfigurationKey        = "configuration.yml"
+	StorageConfigurationKey = "storage-configuration.yml"
+	ClientConfigurationKey  = "client-configuration.yml"
+
+	// MountPath is the directory OpenCGA reads its configuration files from.
+	MountPath = "/opt/opencga/conf"
+
+	// HashAnnotationKey is set on the pod template so that Pods are rolled whenever the rendered
+	// configuration changes.
+	HashAnnotationKey = "opencga.zetta.com/configHash"
+)
+
+// Config holds the contents of the configuration files read by the OpenCGA server.
+type Config struct {
+	Configuration        objx.Map
+	StorageConfiguration objx.Map
+	ClientConfiguration  objx.Map
+}
+
+// Data returns the configuration files rendered as YAML, keyed by their file name.
+func (c Config) Data() (map[string]string, error) {
+	files := map[string]objx.Map{
+		ConfigurationKey:        c.Configuration,
+		StorageConfigurationKey: c.StorageConfiguration,
+		ClientConfigurationKey:  c.ClientConfiguration,
+	}
+
+	data := map[string]string{}
+	for name, contents := range files {
+		if contents == nil {
+			contents = objx.New(map[string]interface{}{})
+		}
+		bytes, err := yaml.Marshal(contents)
+		if err != nil {
+			return nil, fmt.Errorf("could not render %s: %s", name, err)
+		}
+		data[name] = string(bytes)
+	}
+	return data, nil
+}
+
+// Hash returns a hash of the given rendered configuration files.
+func Hash(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, k := range keys {
+		hash.Write([]byte(k))
+		hash.Write([]byte{0})
+		hash.Write([]byte(data[k]))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// deepMerge merges src into dst. Nested maps are merged recursively, every other value
+// in src replaces the one in dst. Maps from src are copied so that later changes to dst
+// are never reflected in src.
+func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	for key, srcValue := range src {
+		srcMap, srcIsMap := asMap(srcValue)
+		dstMap, dstIsMap := asMap(dst[key])
+		if srcIsMap && dstIsMap {
+			dst[key] = deepMerge(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			dst[key] = deepMerge(nil, srcMap)
+			continue
+		}
+		dst[key] = srcValue
+	}
+	return dst
+}
+
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case objx.Map:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
@@ -0,0 +1,175 @@
+package opencgaconfig
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+)
+
+// Modification changes the rendered configuration before the additional configuration is merged in.
+type Modification func(*Config)
+
+func NOOP() Modification {
+	return func(config *Config) {}
+}
+
+type Builder struct {
+	logLevel                string
+	workspace               string
+	databasePrefix          string
+	restPort                int
+	restHost                string
+	catalog                 *Catalog
+	search                  *Search
+	variantStorage          *VariantStorage
+	ldap                    *LDAP
+	oidc                    *OIDC
+	additionalConfiguration map[string]interface{}
+	additionalStorage       map[string]interface{}
+	additionalClient        map[string]interface{}
+	modifications           []Modification
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		logLevel:       automationconfig.DefaultLogLevel,
+		workspace:      automationconfig.DefaultWorkspace,
+		databasePrefix: automationconfig.DefaultCatalogDatabasePrefix,
+		restPort:       automationconfig.DefaultRestPort,
+		modifications:  []Modification{},
+	}
+}
+
+func (b *Builder) SetLogLevel(logLevel string) *Builder {
+	b.logLevel = logLevel
+	return b
+}
+
+func (b *Builder) SetWorkspace(workspace string) *Builder {
+	b.workspace = workspace
+	return b
+}
+
+func (b *Builder) SetDatabasePrefix(databasePrefix string) *Builder {
+	b.databasePrefix = databasePrefix
+	return b
+}
+
+func (b *Builder) SetRestPort(port int) *Builder {
+	b.restPort = port
+	return b
+}
+
+// SetRestHost sets the URL clients using client-configuration.yml connect to.
+func (b *Builder) SetRestHost(host string) *Builder {
+	b.restHost = host
+	return b
+}
+
+// SetCatalog sets the connection to the MongoDB deployment storing the catalog.
+func (b *Builder) SetCatalog(catalog Catalog) *Builder {
+	b.catalog = &catalog
+	return b
+}
+
+// SetSearch sets the connection to the Solr deployment used for catalog search and variant secondary indexes.
+func (b *Builder) SetSearch(search Search) *Builder {
+	b.search = &search
+	return b
+}
+
+// SetVariantStorage sets the engine variants are stored in.
+func (b *Builder) SetVariantStorage(variantStorage VariantStorage) *Builder {
+	b.variantStorage = &variantStorage
+	return b
+}
+
+// SetLDAP sets the LDAP servers users are authenticated against, in addition to their local OpenCGA accounts.
+func (b *Builder) SetLDAP(ldap LDAP) *Builder {
+	b.ldap = &ldap
+	return b
+}
+
+// SetOIDC sets the OpenID provider users are authenticated with, in addition to their local OpenCGA accounts.
+func (b *Builder) SetOIDC(oidc OIDC) *Builder {
+	b.oidc = &oidc
+	return b
+}
+
+// SetAdditionalConfiguration sets the configuration which is deep-merged into configuration.yml.
+func (b *Builder) SetAdditionalConfiguration(additional map[string]interface{}) *Builder {
+	b.additionalConfiguration = additional
+	return b
+}
+
+// SetAdditionalStorageConfiguration sets the configuration which is deep-merged into storage-configuration.yml.
+func (b *Builder) SetAdditionalStorageConfiguration(additional map[string]interface{}) *Builder {
+	b.additionalStorage = additional
+	return b
+}
+
+// SetAdditionalClientConfiguration sets the configuration which is deep-merged into client-configuration.yml.
+func (b *Builder) SetAdditionalClientConfiguration(additional map[string]interface{}) *Builder {
+	b.additionalClient = additional
+	return b
+}
+
+func (b *Builder) AddModifications(mod ...Modification) *Builder {
+	b.modifications = append(b.modifications, mod...)
+	return b
+}
+
+// Build renders the configuration from the typed settings, applies all modifications and
+// finally merges the additional configuration on top, so that it always takes precedence.
+func (b *Builder) Build() Config {
+	config := Config{
+		Configuration: objx.New(map[string]interface{}{
+			"logLevel":       b.logLevel,
+			"workspace":      b.workspace,
+			"databasePrefix": b.databasePrefix,
+			"server": map[string]interface{}{
+				"rest": map[string]interface{}{
+					"port": b.restPort,
+				},
+			},
+		}),
+		StorageConfiguration: objx.New(map[string]interface{}{}),
+		ClientConfiguration: objx.New(map[string]interface{}{
+			"logLevel": b.logLevel,
+			"rest": map[string]interface{}{
+				"host": b.restHost,
+			},
+		}),
+	}
+
+	if b.catalog != nil {
+		config.Configuration["catalog"] = b.catalog.render()
+	}
+	var authenticationOrigins []interface{}
+	if b.ldap != nil {
+		authenticationOrigins = append(authenticationOrigins, b.ldap.render())
+	}
+	if b.oidc != nil {
+		authenticationOrigins = append(authenticationOrigins, b.oidc.render())
+	}
+	if len(authenticationOrigins) > 0 {
+		config.Configuration["authentication"] = map[string]interface{}{
+			"authenticationOrigins": authenticationOrigins,
+		}
+	}
+	if b.search != nil {
+		config.StorageConfiguration["search"] = b.search.render()
+	}
+	if b.variantStorage != nil {
+		config.StorageConfiguration["variant"] = b.variantStorage.render()
+	}
+
+	for _, modification := range b.modifications {
+		modification(&config)
+	}
+
+	config.Configuration = deepMerge(config.Configuration, b.additionalConfiguration)
+	config.StorageConfiguration = deepMerge(config.StorageConfiguration, b.additionalStorage)
+	config.ClientConfiguration = deepMerge(config.ClientConfiguration, b.additionalClient)
+	return config
+}
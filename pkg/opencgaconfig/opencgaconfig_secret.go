@@ -0,0 +1,46 @@
+package opencgaconfig
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/phamidko/opencga-operator/pkg/kube/secret"
+)
+
+// EnsureSecret makes sure the Secret with the given name contains the rendered configuration files.
+// The Secret is only updated if the contents changed. The hash of the rendered configuration is returned,
+// it should be set on the pod template with HashAnnotationKey.
+func EnsureSecret(secretGetUpdateCreator secret.GetUpdateCreator, secretNsName types.NamespacedName, owner []metav1.OwnerReference, config Config) (string, error) {
+	data, err := config.Data()
+	if err != nil {
+		return "", err
+	}
+
+	configSecret := secret.Builder().
+		SetName(secretNsName.Name).
+		SetNamespace(secretNsName.Namespace).
+		SetStringData(data).
+		SetOwnerReferences(owner).
+		Build()
+
+	existingSecret, err := secretGetUpdateCreator.GetSecret(secretNsName)
+	if err != nil {
+		if secret.SecretNotExist(err) {
+			return Hash(data), secretGetUpdateCreator.CreateSecret(configSecret)
+		}
+		return "", err
+	}
+
+	if secret.HasAllKeys(existingSecret, ConfigurationKey, StorageConfigurationKey, ClientConfigurationKey) && Hash(stringData(existingSecret.Data)) == Hash(data) {
+		return Hash(data), nil
+	}
+	return Hash(data), secretGetUpdateCreator.UpdateSecret(configSecret)
+}
+
+func stringData(data map[string][]byte) map[string]string {
+	result := map[string]string{}
+	for k, v := range data {
+		result[k] = string(v)
+	}
+	return result
+}
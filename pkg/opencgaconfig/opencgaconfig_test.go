@@ -0,0 +1,167 @@
+package opencgaconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/phamidko/opencga-operator/pkg/automationconfig"
+)
+
+func TestBuild_Defaults(t *testing.T) {
+	config := NewBuilder().SetRestHost("http://opencga-svc:9090/opencga").Build()
+
+	assert.Equal(t, automationconfig.DefaultLogLevel, config.Configuration.Get("logLevel").Str())
+	assert.Equal(t, automationconfig.DefaultWorkspace, config.Configuration.Get("workspace").Str())
+	assert.Equal(t, automationconfig.DefaultCatalogDatabasePrefix, config.Configuration.Get("databasePrefix").Str())
+	assert.Equal(t, automationconfig.DefaultRestPort, config.Configuration.Get("server.rest.port").Int())
+	assert.Equal(t, "http://opencga-svc:9090/opencga", config.ClientConfiguration.Get("rest.host").Str())
+	assert.Empty(t, config.StorageConfiguration)
+}
+
+func TestBuild_AdditionalConfigurationIsDeepMerged(t *testing.T) {
+	config := NewBuilder().
+		SetRestPort(8080).
+		SetAdditionalConfiguration(map[string]interface{}{
+			"logLevel": "debug",
+			"server": map[string]interface{}{
+				"rest": map[string]interface{}{
+					"httpConfiguration": map[string]interface{}{"maxThreads": float64(100)},
+				},
+			},
+		}).
+		SetAdditionalStorageConfiguration(map[string]interface{}{
+			"search": map[string]interface{}{"timeout": float64(30000)},
+		}).
+		SetAdditionalClientConfiguration(map[string]interface{}{
+			"rest": map[string]interface{}{"tokenAutoRefresh": true},
+		}).
+		Build()
+
+	assert.Equal(t, "debug", config.Configuration.Get("logLevel").Str(), "additional configuration should take precedence")
+	assert.Equal(t, 8080, config.Configuration.Get("server.rest.port").Int(), "sibling keys should be kept")
+	assert.Equal(t, float64(100), config.Configuration.Get("server.rest.httpConfiguration.maxThreads").Float64())
+	assert.Equal(t, float64(30000), config.StorageConfiguration.Get("search.timeout").Float64())
+	assert.True(t, config.ClientConfiguration.Get("rest.tokenAutoRefresh").Bool())
+	assert.True(t, config.ClientConfiguration.Has("rest.host"))
+}
+
+func TestBuild_ModificationsAreAppliedBeforeAdditionalConfiguration(t *testing.T) {
+	config := NewBuilder().
+		AddModifications(func(config *Config) {
+			config.StorageConfiguration.Set("search.mode", "cloud")
+			config.StorageConfiguration.Set("search.timeout", 1000)
+		}).
+		SetAdditionalStorageConfiguration(map[string]interface{}{
+			"search": map[string]interface{}{"timeout": float64(30000)},
+		}).
+		Build()
+
+	assert.Equal(t, "cloud", config.StorageConfiguration.Get("search.mode").Str())
+	assert.Equal(t, float64(30000), config.StorageConfiguration.Get("search.timeout").Float64())
+}
+
+func TestBuild_AdditionalConfigurationIsNotModified(t *testing.T) {
+	additional := map[string]interface{}{
+		"server": map[string]interface{}{"grpc": map[string]interface{}{"port": float64(9091)}},
+	}
+	config := NewBuilder().SetAdditionalConfiguration(additional).Build()
+	config.Configuration.Set("server.grpc.port", 1)
+
+	assert.Equal(t, map[string]interface{}{"port": float64(9091)}, additional["server"].(map[string]interface{})["grpc"])
+}
+
+func TestData(t *testing.T) {
+	data, err := NewBuilder().SetLogLevel("warn").Build().Data()
+	assert.NoError(t, err)
+	assert.Len(t, data, 3)
+
+	var configuration map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(data[ConfigurationKey]), &configuration))
+	assert.Equal(t, "warn", objx.New(configuration).Get("logLevel").Str())
+	assert.Equal(t, "{}\n", data[StorageConfigurationKey])
+}
+
+func TestHash(t *testing.T) {
+	data, err := NewBuilder().Build().Data()
+	assert.NoError(t, err)
+	sameData, err := NewBuilder().Build().Data()
+	assert.NoError(t, err)
+	otherData, err := NewBuilder().SetLogLevel("debug").Build().Data()
+	assert.NoError(t, err)
+
+	assert.Equal(t, Hash(data), Hash(sameData))
+	assert.NotEqual(t, Hash(data), Hash(otherData))
+}
+
+func TestEnsureSecret(t *testing.T) {
+	nsName := types.NamespacedName{Name: "opencga-opencga-config", Namespace: "test-ns"}
+	secretClient := newMockSecretClient()
+
+	hash, err := EnsureSecret(secretClient, nsName, []metav1.OwnerReference{}, NewBuilder().Build())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hash)
+	assert.Equal(t, 1, secretClient.creates)
+
+	s, err := secretClient.GetSecret(nsName)
+	assert.NoError(t, err)
+	assert.Contains(t, string(s.Data[ConfigurationKey]), "logLevel: info")
+
+	t.Run("The Secret is not updated if nothing changed", func(t *testing.T) {
+		sameHash, err := EnsureSecret(secretClient, nsName, []metav1.OwnerReference{}, NewBuilder().Build())
+		assert.NoError(t, err)
+		assert.Equal(t, hash, sameHash)
+		assert.Equal(t, 0, secretClient.updates)
+	})
+
+	t.Run("The Secret is updated if the configuration changed", func(t *testing.T) {
+		newHash, err := EnsureSecret(secretClient, nsName, []metav1.OwnerReference{}, NewBuilder().SetLogLevel("debug").Build())
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, newHash)
+		assert.Equal(t, 1, secretClient.updates)
+
+		s, err := secretClient.GetSecret(nsName)
+		assert.NoError(t, err)
+		assert.Contains(t, string(s.Data[ConfigurationKey]), "logLevel: debug")
+	})
+}
+
+type mockSecretClient struct {
+	secrets map[client.ObjectKey]corev1.Secret
+	creates int
+	updates int
+}
+
+func newMockSecretClient() *mockSecretClient {
+	return &mockSecretClient{secrets: map[client.ObjectKey]corev1.Secret{}}
+}
+
+func (c *mockSecretClient) GetSecret(objectKey client.ObjectKey) (corev1.Secret, error) {
+	if s, ok := c.secrets[objectKey]; ok {
+		return s, nil
+	}
+	return corev1.Secret{}, notFoundError()
+}
+
+func (c *mockSecretClient) CreateSecret(s corev1.Secret) error {
+	c.creates++
+	c.secrets[types.NamespacedName{Name: s.Name, Namespace: s.Namespace}] = s
+	return nil
+}
+
+func (c *mockSecretClient) UpdateSecret(s corev1.Secret) error {
+	c.updates++
+	c.secrets[types.NamespacedName{Name: s.Name, Namespace: s.Namespace}] = s
+	return nil
+}
+
+func notFoundError() error {
+	return &errors.StatusError{ErrStatus: metav1.Status{Reason: metav1.StatusReasonNotFound}}
+}
@@ -0,0 +1,51 @@
+package opencgaconfig
+
+const (
+	// SearchModeCloud is used for SolrCloud deployments, reached either through ZooKeeper or the Solr nodes.
+	SearchModeCloud = "cloud"
+	// SearchModeCore is used for standalone Solr servers.
+	SearchModeCore = "core"
+
+	DefaultSearchCollectionPrefix = "opencga"
+	DefaultSearchTimeoutMs        = 30000
+)
+
+// Search holds the settings OpenCGA uses to connect to Solr.
+type Search struct {
+	Mode             string
+	Hosts            []string
+	CollectionPrefix string
+	TimeoutMs        int
+	User             string
+	Password         string
+}
+
+// render returns the search section of storage-configuration.yml.
+func (s Search) render() map[string]interface{} {
+	hosts := make([]interface{}, len(s.Hosts))
+	for i, host := range s.Hosts {
+		hosts[i] = host
+	}
+
+	mode := s.Mode
+	if mode == "" {
+		mode = SearchModeCloud
+	}
+	collectionPrefix := s.CollectionPrefix
+	if collectionPrefix == "" {
+		collectionPrefix = DefaultSearchCollectionPrefix
+	}
+	timeout := s.TimeoutMs
+	if timeout == 0 {
+		timeout = DefaultSearchTimeoutMs
+	}
+
+	return map[string]interface{}{
+		"mode":             mode,
+		"hosts":            hosts,
+		"collectionPrefix": collectionPrefix,
+		"timeout":          timeout,
+		"user":             s.User,
+		"password":         s.Password,
+	}
+}
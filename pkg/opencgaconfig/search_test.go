@@ -0,0 +1,32 @@
+package opencgaconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild_Search(t *testing.T) {
+	t.Run("Defaults are used for unset values", func(t *testing.T) {
+		config := NewBuilder().SetSearch(Search{Hosts: []string{"zk-0:2181", "zk-1:2181"}}).Build()
+
+		assert.Equal(t, SearchModeCloud, config.StorageConfiguration.Get("search.mode").Str())
+		assert.Equal(t, []interface{}{"zk-0:2181", "zk-1:2181"}, config.StorageConfiguration.Get("search.hosts").Data())
+		assert.Equal(t, DefaultSearchCollectionPrefix, config.StorageConfiguration.Get("search.collectionPrefix").Str())
+		assert.Equal(t, DefaultSearchTimeoutMs, config.StorageConfiguration.Get("search.timeout").Int())
+	})
+	t.Run("Additional storage configuration takes precedence", func(t *testing.T) {
+		config := NewBuilder().
+			SetSearch(Search{Mode: SearchModeCore, Hosts: []string{"http://solr:8983/solr"}, User: "solr", Password: "s3cr3t", TimeoutMs: 5000}).
+			SetAdditionalStorageConfiguration(map[string]interface{}{
+				"search": map[string]interface{}{"insertBatchSize": float64(5000)},
+			}).
+			Build()
+
+		assert.Equal(t, SearchModeCore, config.StorageConfiguration.Get("search.mode").Str())
+		assert.Equal(t, "solr", config.StorageConfiguration.Get("search.user").Str())
+		assert.Equal(t, "s3cr3t", config.StorageConfiguration.Get("search.password").Str())
+		assert.Equal(t, 5000, config.StorageConfiguration.Get("search.timeout").Int())
+		assert.Equal(t, float64(5000), config.StorageConfiguration.Get("search.insertBatchSize").Float64())
+	})
+}
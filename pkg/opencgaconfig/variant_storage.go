@@ -0,0 +1,47 @@
+package opencgaconfig
+
+const (
+	VariantStorageEngineMongoDB = "mongodb"
+	VariantStorageEngineHadoop  = "hadoop"
+
+	mongoDBVariantStorageEngineClass = "org.opencb.opencga.storage.mongodb.variant.MongoDBVariantStorageEngine"
+	hadoopVariantStorageEngineClass  = "org.opencb.opencga.storage.hadoop.variant.HadoopVariantStorageEngine"
+
+	hbaseNamespaceOption = "storage.hadoop.hbase.namespace"
+)
+
+// VariantStorage holds the settings of the engine OpenCGA stores variants in.
+type VariantStorage struct {
+	// Engine is either VariantStorageEngineMongoDB or VariantStorageEngineHadoop.
+	Engine string
+
+	// Database is the MongoDB deployment variants are stored in by the MongoDB engine.
+	Database Catalog
+
+	// HBaseNamespace is the HBase namespace the Hadoop engine creates its tables in.
+	HBaseNamespace string
+}
+
+// render returns the variant section of storage-configuration.yml. Only the selected engine is configured.
+func (v VariantStorage) render() map[string]interface{} {
+	engine := map[string]interface{}{
+		"id":      v.Engine,
+		"options": map[string]interface{}{},
+	}
+
+	switch v.Engine {
+	case VariantStorageEngineHadoop:
+		engine["engine"] = hadoopVariantStorageEngineClass
+		if v.HBaseNamespace != "" {
+			engine["options"] = map[string]interface{}{hbaseNamespaceOption: v.HBaseNamespace}
+		}
+	default:
+		engine["engine"] = mongoDBVariantStorageEngineClass
+		engine["database"] = v.Database.renderDatabase()
+	}
+
+	return map[string]interface{}{
+		"defaultEngine": v.Engine,
+		"engines":       []interface{}{engine},
+	}
+}
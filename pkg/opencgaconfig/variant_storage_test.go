@@ -0,0 +1,44 @@
+package opencgaconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild_VariantStorage(t *testing.T) {
+	t.Run("MongoDB engine uses the given database", func(t *testing.T) {
+		config := NewBuilder().
+			SetVariantStorage(VariantStorage{
+				Engine:   VariantStorageEngineMongoDB,
+				Database: Catalog{Hosts: []string{"mongo-0:27017"}, User: "opencga", Password: "s3cr3t"},
+			}).
+			Build()
+
+		assert.Equal(t, VariantStorageEngineMongoDB, config.StorageConfiguration.Get("variant.defaultEngine").Str())
+		engines := config.StorageConfiguration.Get("variant.engines").InterSlice()
+		if assert.Len(t, engines, 1) {
+			engine := objx.New(engines[0])
+			assert.Equal(t, VariantStorageEngineMongoDB, engine.Get("id").Str())
+			assert.Equal(t, mongoDBVariantStorageEngineClass, engine.Get("engine").Str())
+			assert.Equal(t, []interface{}{"mongo-0:27017"}, engine.Get("database.hosts").Data())
+			assert.Equal(t, "opencga", engine.Get("database.user").Str())
+			assert.Equal(t, "s3cr3t", engine.Get("database.password").Str())
+		}
+	})
+	t.Run("Hadoop engine sets the HBase namespace", func(t *testing.T) {
+		config := NewBuilder().
+			SetVariantStorage(VariantStorage{Engine: VariantStorageEngineHadoop, HBaseNamespace: "opencga"}).
+			Build()
+
+		assert.Equal(t, VariantStorageEngineHadoop, config.StorageConfiguration.Get("variant.defaultEngine").Str())
+		engines := config.StorageConfiguration.Get("variant.engines").InterSlice()
+		if assert.Len(t, engines, 1) {
+			engine := objx.New(engines[0])
+			assert.Equal(t, hadoopVariantStorageEngineClass, engine.Get("engine").Str())
+			assert.Equal(t, map[string]interface{}{hbaseNamespaceOption: "opencga"}, engine.Get("options").Data())
+			assert.False(t, engine.Has("database"))
+		}
+	})
+}
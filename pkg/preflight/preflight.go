@@ -0,0 +1,127 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	solrSystemInfoPath = "/admin/info/system?wt=json"
+
+	// OIDCDiscoveryPath is the path of the discovery document of an OpenID provider, relative to its issuer.
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// Credentials are used to authenticate against a service, they are ignored if the user is empty.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CheckSolr returns an error if none of the given Solr hosts can be reached, as the Solr clients
+// fail over between them. Hosts starting with http:// or https:// are Solr nodes, they are checked by
+// querying their system info with the given credentials. Any other host is a ZooKeeper member in the form
+// host:port, which is checked by opening a TCP connection.
+func CheckSolr(ctx context.Context, httpClient *http.Client, hosts []string, credentials Credentials) error {
+	var errs error
+	for _, host := range hosts {
+		var err error
+		if isURL(host) {
+			err = checkSolrNode(ctx, httpClient, host, credentials)
+		} else {
+			err = CheckTCP(ctx, host)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = multierror.Append(errs, fmt.Errorf("%s: %s", host, err))
+	}
+	if errs == nil {
+		return fmt.Errorf("no hosts to check")
+	}
+	return errs
+}
+
+// CheckTCP returns an error if no TCP connection can be opened to the given address.
+func CheckTCP(ctx context.Context, address string) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// oidcDiscoveryDocument holds the fields of the discovery document of an OpenID provider OpenCGA relies on.
+type oidcDiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// CheckOIDCIssuer returns an error if the discovery document of the given OpenID provider can't be fetched,
+// or if it is not issued for the provider or lacks the endpoints users are authenticated with.
+func CheckOIDCIssuer(ctx context.Context, httpClient *http.Client, issuer string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+OIDCDiscoveryPath, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("unexpected status %d fetching the discovery document", resp.StatusCode)
+	}
+
+	document := oidcDiscoveryDocument{}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return fmt.Errorf("could not parse the discovery document: %s", err)
+	}
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return fmt.Errorf("the discovery document is issued for %q", document.Issuer)
+	}
+	if document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" || document.JWKSURI == "" {
+		return fmt.Errorf("the discovery document lacks the authorization, token or JWKS endpoint")
+	}
+	return nil
+}
+
+func checkSolrNode(ctx context.Context, httpClient *http.Client, baseURL string, credentials Credentials) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+solrSystemInfoPath, nil)
+	if err != nil {
+		return err
+	}
+	if credentials.User != "" {
+		req.SetBasicAuth(credentials.User, credentials.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func isURL(host string) bool {
+	return strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")
+}
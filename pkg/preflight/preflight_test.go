@@ -0,0 +1,102 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSolr_SolrNodes(t *testing.T) {
+	solr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "solr" || password != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/solr/admin/info/system", r.URL.Path)
+		_, _ = w.Write([]byte(`{"mode":"solrcloud"}`))
+	}))
+	defer solr.Close()
+
+	t.Run("Node is reachable with valid credentials", func(t *testing.T) {
+		err := CheckSolr(context.TODO(), solr.Client(), []string{solr.URL + "/solr"}, Credentials{User: "solr", Password: "s3cr3t"})
+		assert.NoError(t, err)
+	})
+	t.Run("Wrong credentials are reported", func(t *testing.T) {
+		err := CheckSolr(context.TODO(), solr.Client(), []string{solr.URL + "/solr"}, Credentials{User: "solr", Password: "wrong"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "401")
+	})
+	t.Run("One reachable node is enough", func(t *testing.T) {
+		err := CheckSolr(context.TODO(), solr.Client(), []string{closedAddress(t), solr.URL + "/solr/"}, Credentials{User: "solr", Password: "s3cr3t"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestCheckSolr_ZooKeeper(t *testing.T) {
+	zk, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer zk.Close()
+
+	assert.NoError(t, CheckSolr(context.TODO(), http.DefaultClient, []string{zk.Addr().String()}, Credentials{}))
+
+	err = CheckSolr(context.TODO(), http.DefaultClient, []string{closedAddress(t)}, Credentials{})
+	assert.Error(t, err)
+}
+
+func TestCheckSolr_NoHosts(t *testing.T) {
+	assert.Error(t, CheckSolr(context.TODO(), http.DefaultClient, nil, Credentials{}))
+}
+
+func TestCheckOIDCIssuer(t *testing.T) {
+	var document string
+	provider := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/realms/opencga"+OIDCDiscoveryPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(document))
+	}))
+	defer provider.Close()
+	issuer := provider.URL + "/realms/opencga"
+	endpoints := `"authorization_endpoint":"` + issuer + `/auth","token_endpoint":"` + issuer + `/token","jwks_uri":"` + issuer + `/certs"`
+
+	t.Run("Discovery document is fetched", func(t *testing.T) {
+		document = `{"issuer":"` + issuer + `",` + endpoints + `}`
+		assert.NoError(t, CheckOIDCIssuer(context.TODO(), provider.Client(), issuer))
+		assert.NoError(t, CheckOIDCIssuer(context.TODO(), provider.Client(), issuer+"/"))
+	})
+	t.Run("Unknown issuer is reported", func(t *testing.T) {
+		err := CheckOIDCIssuer(context.TODO(), provider.Client(), provider.URL+"/realms/other")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "404")
+	})
+	t.Run("Document of another issuer is reported", func(t *testing.T) {
+		document = `{"issuer":"https://idp.example.org",` + endpoints + `}`
+		assert.Error(t, CheckOIDCIssuer(context.TODO(), provider.Client(), issuer))
+	})
+	t.Run("Missing endpoints are reported", func(t *testing.T) {
+		document = `{"issuer":"` + issuer + `"}`
+		assert.Error(t, CheckOIDCIssuer(context.TODO(), provider.Client(), issuer))
+	})
+	t.Run("Invalid document is reported", func(t *testing.T) {
+		document = `<html></html>`
+		assert.Error(t, CheckOIDCIssuer(context.TODO(), provider.Client(), issuer))
+	})
+	t.Run("Unreachable issuer is reported", func(t *testing.T) {
+		assert.Error(t, CheckOIDCIssuer(context.TODO(), provider.Client(), "https://"+closedAddress(t)))
+	})
+}
+
+// closedAddress returns the address of a port nothing listens on.
+func closedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := l.Addr().String()
+	assert.NoError(t, l.Close())
+	return address
+}
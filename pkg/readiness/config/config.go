@@ -12,34 +12,71 @@ import (
 )
 
 const (
-	defaultAgentHealthStatusFilePath = "/var/log/mongodb-mms-automation/agent-health-status.json"
-	defaultLogPath                   = "/var/log/mongodb-mms-automation/readiness.log"
-	podNamespaceEnv                  = "POD_NAMESPACE"
-	automationConfigSecretEnv        = "AUTOMATION_CONFIG_MAP" //nolint
-	agentHealthStatusFilePathEnv     = "AGENT_STATUS_FILEPATH"
-	logPathEnv                       = "LOG_FILE_PATH"
-	hostNameEnv                      = "HOSTNAME"
-	readinessProbeLoggerBackups      = "READINESS_PROBE_LOGGER_BACKUPS"
-	readinessProbeLoggerMaxSize      = "READINESS_PROBE_LOGGER_MAX_SIZE"
-	readinessProbeLoggerMaxAge       = "READINESS_PROBE_LOGGER_MAX_AGE"
+	defaultAgentHealthStatusFilePath       = "/var/log/mongodb-mms-automation/agent-health-status.json"
+	defaultLogPath                         = "/var/log/mongodb-mms-automation/readiness.log"
+	defaultAutomationConfigVersionFilePath = "/var/lib/automation/config/acVersion/version"
+	defaultAgentVersionFilePath            = "/var/log/mongodb-mms-automation/agent-version"
+	podNamespaceEnv                        = "POD_NAMESPACE"
+	automationConfigSecretEnv              = "AUTOMATION_CONFIG_MAP" //nolint
+	agentHealthStatusFilePathEnv           = "AGENT_STATUS_FILEPATH"
+	automationConfigVersionFilePathEnv     = "AUTOMATION_CONFIG_VERSION_FILEPATH"
+	automationConfigFilePathEnv            = "AUTOMATION_CONFIG_FILEPATH"
+	agentVersionFilePathEnv                = "AGENT_VERSION_FILEPATH"
+	logPathEnv                             = "LOG_FILE_PATH"
+	hostNameEnv                            = "HOSTNAME"
+	readinessProbeLoggerBackups            = "READINESS_PROBE_LOGGER_BACKUPS"
+	readinessProbeLoggerMaxSize            = "READINESS_PROBE_LOGGER_MAX_SIZE"
+	readinessProbeLoggerMaxAge             = "READINESS_PROBE_LOGGER_MAX_AGE"
 )
 
 type Config struct {
+	// ClientSet is nil when the probe runs without access to the Kubernetes API.
 	ClientSet                  kubernetes.Interface
 	Namespace                  string
 	Hostname                   string
 	AutomationConfigSecretName string
-	HealthStatusReader         io.Reader
-	LogFilePath                string
-	Logger                     *lumberjack.Logger
+	// AutomationConfigVersionFilePath is the mounted file containing the target automation config version.
+	AutomationConfigVersionFilePath string
+	// AutomationConfigFilePath is the mounted automation config. If set, the target automation config version is
+	// read from it instead of AutomationConfigVersionFilePath when the probe runs without access to the Kubernetes API.
+	AutomationConfigFilePath string
+	// AgentVersionFilePath is the file the achieved automation config version is written to
+	// when the probe runs without access to the Kubernetes API.
+	AgentVersionFilePath string
+	HealthStatusFilePath string
+	HealthStatusReader   io.Reader
+	LogFilePath          string
+	Logger               *lumberjack.Logger
 }
 
+// HasAPIAccess returns true if the probe is able to talk to the Kubernetes API.
+func (c Config) HasAPIAccess() bool {
+	return c.ClientSet != nil
+}
+
+// BuildFromEnvVariables builds the Config from the environment. A nil clientSet means the probe runs
+// without access to the Kubernetes API, in which case the headless check only relies on mounted files.
 func BuildFromEnvVariables(clientSet kubernetes.Interface, isHeadless bool) (Config, error) {
-	healthStatusFilePath := getEnvOrDefault(agentHealthStatusFilePathEnv, defaultAgentHealthStatusFilePath)
-	logFilePath := getEnvOrDefault(logPathEnv, defaultLogPath)
+	cfg, err := BuildForMonitoring(clientSet, isHeadless)
+	if err != nil {
+		return Config{}, err
+	}
 
+	// Note, that we shouldn't close the file here - it will be closed very soon by the 'ioutil.ReadAll'
+	// in main.go
+	file, err := os.Open(cfg.HealthStatusFilePath)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.HealthStatusReader = file
+	return cfg, nil
+}
+
+// BuildForMonitoring builds the Config from the environment without opening the health status file.
+// It is used by long-running processes which read the file again on every check.
+func BuildForMonitoring(clientSet kubernetes.Interface, isHeadless bool) (Config, error) {
 	var namespace, automationConfigName, hostname string
-	if isHeadless {
+	if isHeadless && clientSet != nil {
 		var ok bool
 		namespace, ok = os.LookupEnv(podNamespaceEnv)
 		if !ok {
@@ -62,23 +99,25 @@ func BuildFromEnvVariables(clientSet kubernetes.Interface, isHeadless bool) (Con
 		MaxAge:     readInt(readinessProbeLoggerMaxAge),
 	}
 
-	// Note, that we shouldn't close the file here - it will be closed very soon by the 'ioutil.ReadAll'
-	// in main.go
-	file, err := os.Open(healthStatusFilePath)
-	if err != nil {
-		return Config{}, err
-	}
 	return Config{
-		ClientSet:                  clientSet,
-		Namespace:                  namespace,
-		AutomationConfigSecretName: automationConfigName,
-		Hostname:                   hostname,
-		HealthStatusReader:         file,
-		LogFilePath:                logFilePath,
-		Logger:                     logger,
+		ClientSet:                       clientSet,
+		Namespace:                       namespace,
+		AutomationConfigSecretName:      automationConfigName,
+		Hostname:                        hostname,
+		AutomationConfigVersionFilePath: getEnvOrDefault(automationConfigVersionFilePathEnv, defaultAutomationConfigVersionFilePath),
+		AutomationConfigFilePath:        os.Getenv(automationConfigFilePathEnv),
+		AgentVersionFilePath:            AgentVersionFilePath(),
+		HealthStatusFilePath:            getEnvOrDefault(agentHealthStatusFilePathEnv, defaultAgentHealthStatusFilePath),
+		LogFilePath:                     getEnvOrDefault(logPathEnv, defaultLogPath),
+		Logger:                          logger,
 	}, nil
 }
 
+// AgentVersionFilePath returns the path of the file holding the automation config version achieved by the agent.
+func AgentVersionFilePath() string {
+	return getEnvOrDefault(agentVersionFilePathEnv, defaultAgentVersionFilePath)
+}
+
 func readinessProbeLogFilePath() string {
 	return getEnvOrDefault(logPathEnv, defaultLogPath)
 }
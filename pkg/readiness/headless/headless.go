@@ -3,7 +3,9 @@ package headless
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/phamidko/opencga-operator/pkg/readiness/config"
 	"github.com/phamidko/opencga-operator/pkg/readiness/health"
@@ -12,46 +14,93 @@ import (
 	"go.uber.org/zap"
 )
 
+// agentVersionFileMode is the mode of the file the achieved automation config version is written to.
+const agentVersionFileMode = 0644
+
 // performCheckHeadlessMode validates if the Agent has reached the correct goal state
 // The state is fetched from K8s automation config Secret directly to avoid flakiness of mounting process
 // Dev note: there is an alternative way to get current namespace: to read from
 // /var/run/secrets/kubernetes.io/serviceaccount/namespace file (see
 // https://kubernetes.io/docs/tasks/access-application-cluster/access-cluster/#accessing-the-api-from-a-pod)
 // though passing the namespace as an environment variable makes the code simpler for testing and saves an IO operation
+//
+// If the probe has no access to the Kubernetes API, the target version is read from the mounted file only and the
+// achieved version is written to a local file instead of being patched into the Pod annotations.
 func PerformCheckHeadlessMode(health health.Status, conf config.Config) (bool, error) {
-	var targetVersion int64
-	var err error
-	targetVersion, err = secret.ReadAutomationConfigVersionFromSecret(conf.Namespace, conf.ClientSet, conf.AutomationConfigSecretName)
+	targetVersion, err := ReadTargetVersion(conf)
 	if err != nil {
-		file, err := os.Open("/var/lib/automation/config/acVersion/version")
-		if err != nil {
-			return false, err
-		}
-		defer file.Close()
-		data, err := ioutil.ReadAll(file)
+		return false, err
+	}
 
-		if err != nil {
-			return false, err
-		}
+	currentAgentVersion := ReadCurrentAgentVersion(health, targetVersion)
 
-		targetVersion, err = strconv.ParseInt(string(data), 10, 64)
+	if err = reportAgentVersion(conf, currentAgentVersion); err != nil {
+		return false, err
+	}
 
-		if err != nil {
-			return false, err
+	return targetVersion == currentAgentVersion, nil
+}
+
+// ReadTargetVersion returns the automation config version the Agent should reach. The Secret is preferred when
+// the API is reachable, the mounted automation config, or version file, is used otherwise.
+func ReadTargetVersion(conf config.Config) (int64, error) {
+	if conf.HasAPIAccess() {
+		targetVersion, err := secret.ReadAutomationConfigVersionFromSecret(conf.Namespace, conf.ClientSet, conf.AutomationConfigSecretName)
+		if err == nil {
+			return targetVersion, nil
 		}
+		zap.S().Debugf("Could not read the automation config version from the Secret, falling back to the mounted file: %s", err)
 	}
+	if conf.AutomationConfigFilePath != "" {
+		return secret.ReadAutomationConfigVersionFromFile(conf.AutomationConfigFilePath)
+	}
+	return ReadVersionFromFile(conf.AutomationConfigVersionFilePath)
+}
 
-	currentAgentVersion := readCurrentAgentInfo(health, targetVersion)
+// reportAgentVersion makes the version achieved by the Agent visible to the Operator.
+func reportAgentVersion(conf config.Config, version int64) error {
+	if conf.HasAPIAccess() {
+		return pod.PatchPodAnnotation(conf.Namespace, version, conf.Hostname, conf.ClientSet)
+	}
+	return writeVersionToFile(conf.AgentVersionFilePath, version)
+}
 
-	if err = pod.PatchPodAnnotation(conf.Namespace, currentAgentVersion, conf.Hostname, conf.ClientSet); err != nil {
-		return false, err
+// ReadVersionFromFile reads an automation config version stored as plain text.
+func ReadVersionFromFile(path string) (int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return -1, err
 	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
 
-	return targetVersion == currentAgentVersion, nil
+// writeVersionToFile replaces the contents of the file with the given version. The file is written
+// to a temporary location first so readers never observe a partially written version. The file is
+// readable by everyone, so that the sidecar serving it does not need to run as the same user.
+func writeVersionToFile(path string, version int64) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := tmpFile.Chmod(agentVersionFileMode); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	if _, err := tmpFile.WriteString(strconv.FormatInt(version, 10)); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
 }
 
-// readCurrentAgentInfo returns the version the Agent has reached and the rs member name
-func readCurrentAgentInfo(health health.Status, targetVersion int64) int64 {
+// ReadCurrentAgentVersion returns the version the Agent has reached
+func ReadCurrentAgentVersion(health health.Status, targetVersion int64) int64 {
 	for _, v := range health.ProcessPlans {
 		zap.S().Debugf("Automation Config version: %d, Agent last version: %d", targetVersion, v.LastGoalStateClusterConfigVersion)
 		return v.LastGoalStateClusterConfigVersion
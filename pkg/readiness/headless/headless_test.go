@@ -2,6 +2,9 @@ package headless
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/phamidko/opencga-operator/cmd/readiness/testdata"
@@ -33,6 +36,76 @@ func TestPerformCheckHeadlessMode(t *testing.T) {
 	assert.Equal(t, map[string]string{"agent.mongodb.com/version": "10"}, thePod.Annotations)
 }
 
+func TestPerformCheckHeadlessModeWithoutAPIAccess(t *testing.T) {
+	dir := t.TempDir()
+	c := testConfig()
+	c.AutomationConfigVersionFilePath = filepath.Join(dir, "version")
+	c.AgentVersionFilePath = filepath.Join(dir, "agent-version")
+	require.NoError(t, ioutil.WriteFile(c.AutomationConfigVersionFilePath, []byte("11\n"), 0644))
+
+	t.Run("Agent has not reached the target version", func(t *testing.T) {
+		status := health.Status{
+			ProcessPlans: map[string]health.MmsDirectorStatus{c.Hostname: {
+				LastGoalStateClusterConfigVersion: 10,
+			}},
+		}
+
+		achieved, err := PerformCheckHeadlessMode(status, c)
+
+		require.NoError(t, err)
+		assert.False(t, achieved)
+
+		version, err := ReadVersionFromFile(c.AgentVersionFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), version)
+	})
+
+	t.Run("Agent has reached the target version", func(t *testing.T) {
+		status := health.Status{
+			ProcessPlans: map[string]health.MmsDirectorStatus{c.Hostname: {
+				LastGoalStateClusterConfigVersion: 11,
+			}},
+		}
+
+		achieved, err := PerformCheckHeadlessMode(status, c)
+
+		require.NoError(t, err)
+		assert.True(t, achieved)
+
+		version, err := ReadVersionFromFile(c.AgentVersionFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), version)
+
+		info, err := os.Stat(c.AgentVersionFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm(), "the sidecar serving the version should be able to read it")
+	})
+
+	t.Run("Target version is read from the mounted automation config", func(t *testing.T) {
+		c := c
+		c.AutomationConfigFilePath = filepath.Join(dir, "cluster-config.json")
+		require.NoError(t, ioutil.WriteFile(c.AutomationConfigFilePath, []byte(`{"version": 12}`), 0644))
+		status := health.Status{
+			ProcessPlans: map[string]health.MmsDirectorStatus{c.Hostname: {
+				LastGoalStateClusterConfigVersion: 12,
+			}},
+		}
+
+		achieved, err := PerformCheckHeadlessMode(status, c)
+
+		require.NoError(t, err)
+		assert.True(t, achieved)
+	})
+
+	t.Run("Missing version file is an error", func(t *testing.T) {
+		c := c
+		c.AutomationConfigVersionFilePath = filepath.Join(dir, "does-not-exist")
+
+		_, err := PerformCheckHeadlessMode(health.Status{}, c)
+		assert.Error(t, err)
+	})
+}
+
 func testConfig() config.Config {
 	return config.Config{
 		Namespace:                  "test-ns",
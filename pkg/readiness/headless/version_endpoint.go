@@ -0,0 +1,56 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AgentVersionPath is the path the achieved automation config version is served on
+// when the readiness binary runs as a sidecar.
+const AgentVersionPath = "/version"
+
+// AgentVersionHandler serves the automation config version recorded in the given file.
+// A 503 is returned until the probe has written the file for the first time.
+func AgentVersionHandler(agentVersionFilePath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, err := ReadVersionFromFile(agentVersionFilePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "the agent version has not been recorded yet", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = fmt.Fprint(w, version)
+	})
+}
+
+// FetchAgentVersion returns the automation config version served by the readiness sidecar
+// listening on baseURL, e.g. "http://10.0.0.1:8080".
+func FetchAgentVersion(ctx context.Context, httpClient *http.Client, baseURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+AgentVersionPath, nil)
+	if err != nil {
+		return -1, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return -1, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("unexpected status %d fetching the agent version: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
@@ -0,0 +1,41 @@
+package headless
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchAgentVersion(t *testing.T) {
+	versionFilePath := filepath.Join(t.TempDir(), "agent-version")
+	server := httptest.NewServer(AgentVersionHandler(versionFilePath))
+	defer server.Close()
+
+	t.Run("Version is not available before the probe has run", func(t *testing.T) {
+		_, err := FetchAgentVersion(context.TODO(), server.Client(), server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("Version recorded by the probe is returned", func(t *testing.T) {
+		require.NoError(t, writeVersionToFile(versionFilePath, 7))
+
+		version, err := FetchAgentVersion(context.TODO(), server.Client(), server.URL+"/")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), version)
+	})
+
+	t.Run("Invalid contents are reported as a server error", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(versionFilePath, []byte("not-a-number"), 0644))
+
+		resp, err := server.Client().Get(server.URL + AgentVersionPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "opencga"
+	subsystem = "readiness"
+
+	// UnknownVersion is used when an automation config version could not be determined.
+	UnknownVersion int64 = -1
+)
+
+// Observation is a snapshot of the readiness decision and of the values it is based on.
+type Observation struct {
+	// Ready is true if the Pod is reported ready.
+	Ready bool
+
+	// InGoalState is true if the Agent has reached the goal state.
+	InGoalState bool
+
+	// AchievedVersion is the last automation config version the Agent has reached.
+	AchievedVersion int64
+
+	// TargetVersion is the automation config version the Agent should reach.
+	TargetVersion int64
+
+	// CurrentStep is the plan step the Agent is currently working on, empty if there is none.
+	CurrentStep string
+
+	// CurrentStepStarted is the time the current step was started.
+	CurrentStepStarted time.Time
+
+	// DeadlockEscaped is true if the Pod is reported ready only because the Agent seems to be deadlocked.
+	DeadlockEscaped bool
+}
+
+// Recorder exposes readiness observations as Prometheus metrics.
+type Recorder struct {
+	ready              prometheus.Gauge
+	goalStateReached   prometheus.Gauge
+	achievedVersion    prometheus.Gauge
+	targetVersion      prometheus.Gauge
+	currentStepStarted *prometheus.GaugeVec
+	deadlockEscaped    prometheus.Gauge
+	deadlockEscapes    prometheus.Counter
+
+	escapingDeadlock bool
+}
+
+// NewRecorder creates a Recorder and registers its metrics with the given Registerer.
+func NewRecorder(registerer prometheus.Registerer) (*Recorder, error) {
+	r := &Recorder{
+		ready: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "ready",
+			Help:      "Whether the Pod is reported ready (1) or not (0).",
+		}),
+		goalStateReached: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "goal_state_reached",
+			Help:      "Whether the Agent has reached the goal state (1) or not (0).",
+		}),
+		achievedVersion: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "automation_config_version_achieved",
+			Help:      "The last automation config version reached by the Agent.",
+		}),
+		targetVersion: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "automation_config_version_target",
+			Help:      "The automation config version the Agent should reach.",
+		}),
+		currentStepStarted: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "current_step_started_timestamp_seconds",
+			Help:      "Start time of the plan step the Agent is currently working on.",
+		}, []string{"step"}),
+		deadlockEscaped: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "deadlock_escaped",
+			Help:      "Whether the Pod is reported ready only because the Agent seems to be deadlocked (1) or not (0).",
+		}),
+		deadlockEscapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "deadlock_escapes_total",
+			Help:      "Number of times the Pod started being reported ready because the Agent seemed to be deadlocked.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{r.ready, r.goalStateReached, r.achievedVersion, r.targetVersion, r.currentStepStarted, r.deadlockEscaped, r.deadlockEscapes} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Record updates all metrics with the values from the given Observation.
+func (r *Recorder) Record(o Observation) {
+	r.ready.Set(boolToFloat(o.Ready))
+	r.goalStateReached.Set(boolToFloat(o.InGoalState))
+	if o.AchievedVersion != UnknownVersion {
+		r.achievedVersion.Set(float64(o.AchievedVersion))
+	}
+	if o.TargetVersion != UnknownVersion {
+		r.targetVersion.Set(float64(o.TargetVersion))
+	}
+
+	r.currentStepStarted.Reset()
+	if o.CurrentStep != "" {
+		r.currentStepStarted.WithLabelValues(o.CurrentStep).Set(float64(o.CurrentStepStarted.Unix()))
+	}
+
+	r.deadlockEscaped.Set(boolToFloat(o.DeadlockEscaped))
+	if o.DeadlockEscaped && !r.escapingDeadlock {
+		r.deadlockEscapes.Inc()
+	}
+	r.escapingDeadlock = o.DeadlockEscaped
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
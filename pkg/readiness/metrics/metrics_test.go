@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	recorder, err := NewRecorder(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	started := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	recorder.Record(Observation{
+		Ready:              true,
+		InGoalState:        false,
+		AchievedVersion:    4,
+		TargetVersion:      5,
+		CurrentStep:        "WaitRsInit",
+		CurrentStepStarted: started,
+		DeadlockEscaped:    true,
+	})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.ready))
+	assert.Equal(t, float64(0), testutil.ToFloat64(recorder.goalStateReached))
+	assert.Equal(t, float64(4), testutil.ToFloat64(recorder.achievedVersion))
+	assert.Equal(t, float64(5), testutil.ToFloat64(recorder.targetVersion))
+	assert.Equal(t, float64(started.Unix()), testutil.ToFloat64(recorder.currentStepStarted.WithLabelValues("WaitRsInit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.deadlockEscaped))
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.deadlockEscapes))
+
+	t.Run("An ongoing deadlock escape is only counted once", func(t *testing.T) {
+		recorder.Record(Observation{
+			AchievedVersion:    4,
+			TargetVersion:      5,
+			CurrentStep:        "WaitRsInit",
+			CurrentStepStarted: started,
+			DeadlockEscaped:    true,
+		})
+		assert.Equal(t, float64(1), testutil.ToFloat64(recorder.deadlockEscapes))
+	})
+
+	t.Run("Goal state resets the step and deadlock metrics", func(t *testing.T) {
+		recorder.Record(Observation{
+			InGoalState:     true,
+			AchievedVersion: 5,
+			TargetVersion:   UnknownVersion,
+		})
+		assert.Equal(t, float64(1), testutil.ToFloat64(recorder.goalStateReached))
+		assert.Equal(t, float64(5), testutil.ToFloat64(recorder.achievedVersion))
+		assert.Equal(t, float64(5), testutil.ToFloat64(recorder.targetVersion), "an unknown target version should keep the last known value")
+		assert.Equal(t, 0, testutil.CollectAndCount(recorder.currentStepStarted))
+		assert.Equal(t, float64(0), testutil.ToFloat64(recorder.deadlockEscaped))
+		assert.Equal(t, float64(1), testutil.ToFloat64(recorder.deadlockEscapes))
+	})
+}
+
+func TestNewRecorderFailsOnDuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := NewRecorder(registry)
+	require.NoError(t, err)
+
+	_, err = NewRecorder(registry)
+	assert.Error(t, err)
+}
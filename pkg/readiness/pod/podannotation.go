@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/client-go/kubernetes"
@@ -42,3 +43,13 @@ func PatchPodAnnotation(podNamespace string, lastVersionAchieved int64, memberNa
 	}
 	return err
 }
+
+// ReachedAutomationConfigVersion returns true if the agent of the Pod has reached the goal state of the given
+// automation config version, as recorded on the Pod by the readiness probe.
+func ReachedAutomationConfigVersion(pod corev1.Pod, automationConfigVersion int) bool {
+	version, err := strconv.Atoi(pod.Annotations[mongodbAgentVersionAnnotation])
+	if err != nil {
+		return false
+	}
+	return version >= automationConfigVersion
+}
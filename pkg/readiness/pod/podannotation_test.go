@@ -44,3 +44,15 @@ func TestPatchPodAnnotation(t *testing.T) {
 func TestUpdatePodAnnotationPodNotFound(t *testing.T) {
 	assert.True(t, apiErrors.IsNotFound(PatchPodAnnotation("wrong-ns", 1, "my-replica-set-0", fake.NewSimpleClientset())))
 }
+
+func TestReachedAutomationConfigVersion(t *testing.T) {
+	podWithVersion := func(version string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{mongodbAgentVersionAnnotation: version}}}
+	}
+
+	assert.True(t, ReachedAutomationConfigVersion(podWithVersion("3"), 3))
+	assert.True(t, ReachedAutomationConfigVersion(podWithVersion("4"), 3))
+	assert.False(t, ReachedAutomationConfigVersion(podWithVersion("2"), 3))
+	assert.False(t, ReachedAutomationConfigVersion(podWithVersion(""), 3), "the agent has not reported any version yet")
+	assert.False(t, ReachedAutomationConfigVersion(v1.Pod{}, 3))
+}
@@ -1,14 +1,22 @@
 package secret
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cast"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	automationConfigKey = "cluster-config.json"
+	automationConfigKey         = "cluster-config.json"
+	automationConfigEncodingKey = "cluster-config.encoding"
+	gzipEncoding                = "gzip"
 )
 
 func ReadAutomationConfigVersionFromSecret(namespace string, clientSet kubernetes.Interface, automationConfigMap string) (int64, error) {
@@ -17,14 +25,60 @@ func ReadAutomationConfigVersionFromSecret(namespace string, clientSet kubernete
 	if err != nil {
 		return -1, err
 	}
+	return automationConfigVersion(theSecret.Data)
+}
+
+// ReadAutomationConfigVersionFromFile returns the version of the automation config mounted from its Secret at the
+// given path. The encoding of the automation config is read from the key mounted next to it.
+func ReadAutomationConfigVersionFromFile(path string) (int64, error) {
+	acBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return -1, err
+	}
+	data := map[string][]byte{automationConfigKey: acBytes}
+	encoding, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), automationConfigEncodingKey))
+	if err != nil && !os.IsNotExist(err) {
+		return -1, err
+	}
+	if err == nil {
+		data[automationConfigEncodingKey] = encoding
+	}
+	return automationConfigVersion(data)
+}
+
+// automationConfigVersion returns the version of the automation config held by the Secret data.
+func automationConfigVersion(data map[string][]byte) (int64, error) {
+	acBytes, err := automationConfigBytes(data)
+	if err != nil {
+		return -1, err
+	}
 	var existingDeployment map[string]interface{}
-	if err := json.Unmarshal(theSecret.Data[automationConfigKey], &existingDeployment); err != nil {
+	if err := json.Unmarshal(acBytes, &existingDeployment); err != nil {
 		return -1, err
 	}
 
 	version, ok := existingDeployment["version"]
 	if !ok {
-		return -1, err
+		return -1, fmt.Errorf("the automation config has no version")
 	}
 	return cast.ToInt64(version), nil
 }
+
+// automationConfigBytes returns the JSON automation config held by the Secret data, which the Operator compresses
+// with gzip when it is large.
+func automationConfigBytes(data map[string][]byte) ([]byte, error) {
+	acBytes := data[automationConfigKey]
+	encoding, ok := data[automationConfigEncodingKey]
+	if !ok {
+		return acBytes, nil
+	}
+	if string(encoding) != gzipEncoding {
+		return nil, fmt.Errorf("unsupported automation config encoding %q", encoding)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(acBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,79 @@
+package secret
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadAutomationConfigVersionFromSecret(t *testing.T) {
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "ac"}, Data: data}
+	}
+
+	t.Run("Plain automation config", func(t *testing.T) {
+		clientSet := fake.NewSimpleClientset(newSecret(map[string][]byte{automationConfigKey: []byte(`{"version": 5}`)}))
+		version, err := ReadAutomationConfigVersionFromSecret("test-ns", clientSet, "ac")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), version)
+	})
+	t.Run("Compressed automation config", func(t *testing.T) {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		_, err := writer.Write([]byte(`{"version": 7}`))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		clientSet := fake.NewSimpleClientset(newSecret(map[string][]byte{
+			automationConfigKey:         compressed.Bytes(),
+			automationConfigEncodingKey: []byte(gzipEncoding),
+		}))
+		version, err := ReadAutomationConfigVersionFromSecret("test-ns", clientSet, "ac")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), version)
+	})
+	t.Run("Unknown encoding is reported", func(t *testing.T) {
+		clientSet := fake.NewSimpleClientset(newSecret(map[string][]byte{
+			automationConfigKey:         []byte(`{"version": 7}`),
+			automationConfigEncodingKey: []byte("zstd"),
+		}))
+		_, err := ReadAutomationConfigVersionFromSecret("test-ns", clientSet, "ac")
+		assert.Error(t, err)
+	})
+}
+
+func TestReadAutomationConfigVersionFromFile(t *testing.T) {
+	t.Run("Plain automation config", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, automationConfigKey), []byte(`{"version": 5}`), 0644))
+
+		version, err := ReadAutomationConfigVersionFromFile(filepath.Join(dir, automationConfigKey))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), version)
+	})
+	t.Run("Compressed automation config", func(t *testing.T) {
+		dir := t.TempDir()
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		_, err := writer.Write([]byte(`{"version": 7}`))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, automationConfigKey), compressed.Bytes(), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, automationConfigEncodingKey), []byte(gzipEncoding), 0644))
+
+		version, err := ReadAutomationConfigVersionFromFile(filepath.Join(dir, automationConfigKey))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), version)
+	})
+	t.Run("Missing automation config is reported", func(t *testing.T) {
+		_, err := ReadAutomationConfigVersionFromFile(filepath.Join(t.TempDir(), automationConfigKey))
+		assert.Error(t, err)
+	})
+}